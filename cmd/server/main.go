@@ -33,6 +33,11 @@ import (
 // @name Authorization
 // @description Type "Bearer" followed by a space and JWT token.
 
+// @securityDefinitions.apikey SCIMAuth
+// @in header
+// @name Authorization
+// @description Type "Bearer" followed by a space and the SCIM_BEARER_TOKEN.
+
 func main() {
 	// Load environment variables
 	if err := godotenv.Load(); err != nil {
@@ -54,7 +59,10 @@ func main() {
 	}
 
 	// Initialize router
-	router := routes.Setup(cfg, db)
+	router, err := routes.Setup(cfg, db)
+	if err != nil {
+		log.Fatalf("Failed to initialize routes: %v", err)
+	}
 
 	// Start server
 	port := os.Getenv("SERVER_PORT")
@@ -0,0 +1,363 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"ishare-task-api/internal/models"
+
+	"github.com/google/uuid"
+	"golang.org/x/text/language"
+	"gorm.io/gorm"
+)
+
+// Account audit event names
+const (
+	AuditPasswordChanged          = "password.changed"
+	AuditEmailChangeRequested     = "email.change_requested"
+	AuditEmailChanged             = "email.changed"
+	AuditProfileUpdated           = "profile.updated"
+	AuditAccountDeletionScheduled = "account.deletion_scheduled"
+	AuditAccountDeletionCancelled = "account.deletion_cancelled"
+	AuditAccountDeleted           = "account.deleted"
+)
+
+// Account errors
+var (
+	ErrIncorrectPassword = errors.New("current password is incorrect")
+	ErrEmailTaken        = errors.New("email address is already in use")
+	ErrSameEmail         = errors.New("new email address is the current one")
+	ErrInvalidLocale     = errors.New("invalid locale")
+	ErrInvalidTimezone   = errors.New("invalid timezone")
+)
+
+// UpdateProfile applies the given profile changes to the user
+func (o *OAuthManager) UpdateProfile(user *models.User, req models.UpdateProfileRequest) error {
+	updates := map[string]interface{}{}
+
+	if req.DisplayName != nil {
+		updates["display_name"] = strings.TrimSpace(*req.DisplayName)
+	}
+
+	if req.Locale != nil {
+		locale, err := normalizeLocale(*req.Locale)
+		if err != nil {
+			return err
+		}
+		updates["locale"] = locale
+	}
+
+	if req.Timezone != nil {
+		timezone, err := normalizeTimezone(*req.Timezone)
+		if err != nil {
+			return err
+		}
+		updates["timezone"] = timezone
+	}
+
+	if len(updates) == 0 {
+		return nil
+	}
+
+	if err := o.db.Model(user).Updates(updates).Error; err != nil {
+		return err
+	}
+
+	o.RecordAuditEvent(models.AuditEvent{
+		Event:   AuditProfileUpdated,
+		UserID:  &user.ID,
+		ActorID: &user.ID,
+		Email:   user.Email,
+	})
+
+	return o.db.Where("id = ?", user.ID).First(user).Error
+}
+
+// normalizeLocale validates a BCP 47 language tag; empty clears the locale
+func normalizeLocale(locale string) (string, error) {
+	locale = strings.TrimSpace(locale)
+	if locale == "" {
+		return "", nil
+	}
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return "", ErrInvalidLocale
+	}
+	return tag.String(), nil
+}
+
+// normalizeTimezone validates an IANA time zone name; empty clears the
+// timezone
+func normalizeTimezone(timezone string) (string, error) {
+	timezone = strings.TrimSpace(timezone)
+	if timezone == "" {
+		return "", nil
+	}
+	if _, err := time.LoadLocation(timezone); err != nil || timezone == "Local" {
+		return "", ErrInvalidTimezone
+	}
+	return timezone, nil
+}
+
+// ChangePassword sets a new password after checking the current one and
+// signs the user out of all other sessions. It returns the number of
+// revoked sessions.
+func (o *OAuthManager) ChangePassword(user *models.User, currentPassword, newPassword, keepJTI, ipAddress string) (int64, error) {
+	if err := o.verifyCurrentPassword(user, currentPassword, ipAddress); err != nil {
+		return 0, err
+	}
+
+	if err := o.policy.Validate(newPassword, user.Email); err != nil {
+		return 0, err
+	}
+
+	hashedPassword, err := o.hasher.Hash(newPassword)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := o.db.Model(user).Update("password_hash", hashedPassword).Error; err != nil {
+		return 0, err
+	}
+
+	revoked, err := o.RevokeAllSessions(user.ID, keepJTI)
+	if err != nil {
+		return revoked, err
+	}
+
+	o.RecordAuditEvent(models.AuditEvent{
+		Event:     AuditPasswordChanged,
+		UserID:    &user.ID,
+		ActorID:   &user.ID,
+		Email:     user.Email,
+		IPAddress: ipAddress,
+	})
+
+	return revoked, nil
+}
+
+// RequestEmailChange checks the current password and returns a verification
+// token for the new address. The email only changes once the token is
+// verified.
+func (o *OAuthManager) RequestEmailChange(user *models.User, newEmail, currentPassword, ipAddress string) (string, error) {
+	newEmail = strings.TrimSpace(newEmail)
+	if strings.EqualFold(newEmail, user.Email) {
+		return "", ErrSameEmail
+	}
+
+	if err := o.verifyCurrentPassword(user, currentPassword, ipAddress); err != nil {
+		return "", err
+	}
+
+	taken, err := o.emailTaken(o.db, newEmail, user.ID)
+	if err != nil {
+		return "", err
+	}
+	if taken {
+		return "", ErrEmailTaken
+	}
+
+	token, err := o.jwt.GenerateEmailVerificationToken(user.ID, newEmail, user.Email, o.config.EmailVerificationTTL)
+	if err != nil {
+		return "", err
+	}
+
+	o.RecordAuditEvent(models.AuditEvent{
+		Event:     AuditEmailChangeRequested,
+		UserID:    &user.ID,
+		ActorID:   &user.ID,
+		Email:     user.Email,
+		IPAddress: ipAddress,
+		Details:   "new email: " + newEmail,
+	})
+
+	return token, nil
+}
+
+// applyEmailChange replaces the user's email with the verified new address.
+// The token must start from the user's current address so that links for
+// superseded changes stop working.
+func (o *OAuthManager) applyEmailChange(user *models.User, claims *EmailVerificationClaims) (*models.User, error) {
+	if user.Email != claims.PreviousEmail {
+		if user.Email == claims.Email {
+			// Link opened again after the change
+			return user, nil
+		}
+		return nil, ErrVerificationEmailMismatch
+	}
+
+	previousEmail := user.Email
+	err := o.db.Transaction(func(tx *gorm.DB) error {
+		taken, err := o.emailTaken(tx, claims.Email, user.ID)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return ErrEmailTaken
+		}
+
+		now := time.Now()
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"email":             claims.Email,
+			"email_verified_at": now,
+		}).Error; err != nil {
+			return err
+		}
+		user.Email = claims.Email
+		user.EmailVerifiedAt = &now
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	o.RecordAuditEvent(models.AuditEvent{
+		Event:   AuditEmailChanged,
+		UserID:  &user.ID,
+		ActorID: &user.ID,
+		Email:   user.Email,
+		Details: "previous email: " + previousEmail,
+	})
+
+	return user, nil
+}
+
+// ScheduleAccountDeletion marks the account for deletion after the grace
+// period and signs the user out everywhere. Signing in again before the
+// deletion date cancels it.
+func (o *OAuthManager) ScheduleAccountDeletion(user *models.User, currentPassword, ipAddress string) (time.Time, error) {
+	if err := o.verifyCurrentPassword(user, currentPassword, ipAddress); err != nil {
+		return time.Time{}, err
+	}
+
+	deleteAt := time.Now().Add(o.config.AccountDeletionGrace)
+	if err := o.db.Model(user).Update("deletion_scheduled_at", deleteAt).Error; err != nil {
+		return time.Time{}, err
+	}
+	user.DeletionScheduledAt = &deleteAt
+
+	if _, err := o.RevokeAllSessions(user.ID, ""); err != nil {
+		return time.Time{}, err
+	}
+
+	o.RecordAuditEvent(models.AuditEvent{
+		Event:     AuditAccountDeletionScheduled,
+		UserID:    &user.ID,
+		ActorID:   &user.ID,
+		Email:     user.Email,
+		IPAddress: ipAddress,
+		Details:   "scheduled for " + deleteAt.UTC().Format(time.RFC3339),
+	})
+
+	return deleteAt, nil
+}
+
+// cancelAccountDeletion restores an account scheduled for deletion
+func (o *OAuthManager) cancelAccountDeletion(user *models.User, ipAddress string) error {
+	if err := o.db.Model(user).Update("deletion_scheduled_at", nil).Error; err != nil {
+		return err
+	}
+	user.DeletionScheduledAt = nil
+
+	o.RecordAuditEvent(models.AuditEvent{
+		Event:     AuditAccountDeletionCancelled,
+		UserID:    &user.ID,
+		ActorID:   &user.ID,
+		Email:     user.Email,
+		IPAddress: ipAddress,
+	})
+
+	return nil
+}
+
+// PurgeDeletedAccounts permanently deletes accounts whose deletion grace
+// period has passed, together with their credentials and sessions
+func (o *OAuthManager) PurgeDeletedAccounts() (int64, error) {
+	var users []models.User
+	if err := o.db.Where("deletion_scheduled_at < ?", time.Now()).Find(&users).Error; err != nil {
+		return 0, err
+	}
+
+	var purged int64
+	for _, user := range users {
+		if err := o.deleteUser(&user); err != nil {
+			return purged, err
+		}
+		purged++
+
+		userID := user.ID
+		o.RecordAuditEvent(models.AuditEvent{
+			Event:  AuditAccountDeleted,
+			UserID: &userID,
+			Email:  user.Email,
+		})
+	}
+
+	return purged, nil
+}
+
+// deleteUser permanently deletes a user together with its credentials,
+// sessions, role assignments and memberships
+func (o *OAuthManager) deleteUser(user *models.User) error {
+	return o.db.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{
+			&models.AccessToken{},
+			&models.AuthorizationCode{},
+			&models.SSOSession{},
+			&models.PasswordResetToken{},
+			&models.MFACredential{},
+			&models.RecoveryCode{},
+			&models.MFAChallenge{},
+			&models.PersonalAccessToken{},
+			&models.FederatedIdentity{},
+		} {
+			if err := tx.Where("user_id = ?", user.ID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Exec("DELETE FROM user_roles WHERE user_id = ?", user.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM group_members WHERE user_id = ?", user.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM organization_members WHERE user_id = ?", user.ID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(user).Error
+	})
+}
+
+// verifyCurrentPassword re-authenticates the user for a sensitive change.
+// Failures count towards the login throttle of the account.
+func (o *OAuthManager) verifyCurrentPassword(user *models.User, currentPassword, ipAddress string) error {
+	if err := o.checkLoginThrottle(user.Email, ipAddress); err != nil {
+		return err
+	}
+
+	match, err := o.hasher.Verify(currentPassword, user.PasswordHash)
+	if err != nil {
+		return err
+	}
+	if !match {
+		if err := o.recordLoginFailure(&user.ID, user.Email, ipAddress); err != nil {
+			return err
+		}
+		return ErrIncorrectPassword
+	}
+
+	return nil
+}
+
+// emailTaken reports whether another user already uses the email address
+func (o *OAuthManager) emailTaken(db *gorm.DB, email string, userID uuid.UUID) (bool, error) {
+	var count int64
+	if err := db.Model(&models.User{}).
+		Where("LOWER(email) = LOWER(?) AND id <> ?", email, userID).
+		Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check email: %w", err)
+	}
+	return count > 0, nil
+}
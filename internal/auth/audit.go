@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"log"
+
+	"ishare-task-api/internal/models"
+)
+
+// Audit event names
+const (
+	AuditLoginSucceeded = "login.succeeded"
+	AuditLoginFailed    = "login.failed"
+	AuditLoginThrottled = "login.throttled"
+	AuditAccountLocked  = "account.locked"
+	AuditIPLocked       = "ip.locked"
+	AuditLockoutLifted  = "lockout.lifted"
+)
+
+// RecordAuditEvent stores an audit event. Failures are logged rather than
+// returned so auditing never blocks the operation being audited.
+func (o *OAuthManager) RecordAuditEvent(event models.AuditEvent) {
+	event.IPAddress = truncate(event.IPAddress, 45)
+	event.Email = truncate(event.Email, 255)
+	if err := o.db.Create(&event).Error; err != nil {
+		log.Printf("Failed to record audit event %s: %v", event.Event, err)
+	}
+}
+
+// ListAuditEvents returns the most recent audit events, optionally filtered
+// by event name
+func (o *OAuthManager) ListAuditEvents(event string, limit int) ([]models.AuditEvent, error) {
+	query := o.db.Order("created_at DESC").Limit(limit)
+	if event != "" {
+		query = query.Where("event = ?", event)
+	}
+
+	var events []models.AuditEvent
+	if err := query.Find(&events).Error; err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"fmt"
+	"log"
+
+	"ishare-task-api/internal/config"
+	"ishare-task-api/internal/directory"
+	"ishare-task-api/internal/models"
+)
+
+// Authenticator names accepted in AUTHENTICATORS
+const (
+	AuthenticatorLocal = "local"
+	AuthenticatorLDAP  = "ldap"
+)
+
+// Authenticator verifies the email and password entered at sign-in. It
+// returns ErrInvalidCredentials when it does not know the user or the
+// password is wrong, so the next authenticator in the chain is tried.
+type Authenticator interface {
+	Name() string
+	Authenticate(email, password string) (*models.User, error)
+}
+
+// NewAuthenticators creates the named authenticators in order
+func NewAuthenticators(names []string, o *OAuthManager, rbac *RBAC, ldapConfig config.LDAPConfig) ([]Authenticator, error) {
+	if len(names) == 0 {
+		return nil, fmt.Errorf("at least one authenticator is required")
+	}
+
+	authenticators := make([]Authenticator, 0, len(names))
+	for _, name := range uniqueStrings(names) {
+		switch name {
+		case AuthenticatorLocal:
+			authenticators = append(authenticators, NewLocalAuthenticator(o))
+		case AuthenticatorLDAP:
+			client, err := directory.NewClient(ldapConfig)
+			if err != nil {
+				return nil, err
+			}
+			authenticators = append(authenticators, NewLDAPAuthenticator(o, rbac, client, ldapConfig))
+		default:
+			return nil, fmt.Errorf("unknown authenticator %q", name)
+		}
+	}
+
+	return authenticators, nil
+}
+
+// SetAuthenticators replaces the authenticators used by AuthenticateUser
+func (o *OAuthManager) SetAuthenticators(authenticators ...Authenticator) {
+	o.authenticators = authenticators
+}
+
+// authenticate tries the authenticators in order until one accepts the
+// credentials. An authenticator that fails for another reason, such as an
+// unreachable directory, does not stop the chain; its error is returned
+// when no other authenticator accepts the credentials.
+func (o *OAuthManager) authenticate(email, password string) (*models.User, string, error) {
+	var firstErr error
+	for _, authenticator := range o.authenticators {
+		user, err := authenticator.Authenticate(email, password)
+		if err == nil {
+			return user, authenticator.Name(), nil
+		}
+		if err != ErrInvalidCredentials {
+			log.Printf("Authenticator %s failed: %v", authenticator.Name(), err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	if firstErr != nil {
+		return nil, "", firstErr
+	}
+	return nil, "", ErrInvalidCredentials
+}
+
+// LocalAuthenticator checks passwords against the users table
+type LocalAuthenticator struct {
+	o *OAuthManager
+}
+
+// NewLocalAuthenticator creates an authenticator for local accounts
+func NewLocalAuthenticator(o *OAuthManager) *LocalAuthenticator {
+	return &LocalAuthenticator{
+		o: o,
+	}
+}
+
+// Name returns "local"
+func (a *LocalAuthenticator) Name() string {
+	return AuthenticatorLocal
+}
+
+// Authenticate verifies the password hash of the account with the email
+func (a *LocalAuthenticator) Authenticate(email, password string) (*models.User, error) {
+	var user models.User
+	if err := a.o.db.Where("email = ?", email).First(&user).Error; err != nil {
+		// Spend the same time as a real password check
+		a.o.hasher.Verify(password, a.o.dummyHash)
+		return nil, ErrInvalidCredentials
+	}
+
+	match, err := a.o.hasher.Verify(password, user.PasswordHash)
+	if err != nil {
+		return nil, err
+	}
+	if !match {
+		return nil, ErrInvalidCredentials
+	}
+
+	// Upgrade hashes made with another algorithm or outdated parameters
+	if a.o.hasher.NeedsRehash(user.PasswordHash) {
+		a.o.rehashPassword(&user, password)
+	}
+
+	return &user, nil
+}
@@ -0,0 +1,249 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"ishare-task-api/internal/federation"
+	"ishare-task-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// AMRFederated is recorded in the amr claim for sign-ins at an upstream
+// identity provider
+const AMRFederated = "fed"
+
+// Federation audit event names
+const (
+	AuditFederatedIdentityLinked  = "federation.linked"
+	AuditFederatedUserProvisioned = "federation.provisioned"
+)
+
+// Federation errors
+var (
+	ErrInvalidFederatedState     = errors.New("invalid or expired sign-in state")
+	ErrFederatedEmailUnverified  = errors.New("identity provider did not supply a verified email address")
+	ErrFederatedDomainNotAllowed = errors.New("email domain is not allowed for this identity provider")
+	ErrFederatedAccountNotFound  = errors.New("no account is linked to this identity")
+	ErrFederatedLocalUnverified  = errors.New("an account with this email exists but its email is not verified")
+)
+
+// BeginFederatedLogin stores the state of an upstream sign-in and returns
+// the random state parameter that identifies it
+func (o *OAuthManager) BeginFederatedLogin(provider, clientID, redirectURI, scope, state string, ttl time.Duration) (string, *models.FederatedLoginState, error) {
+	stateToken, err := randomToken()
+	if err != nil {
+		return "", nil, err
+	}
+	codeVerifier, err := randomToken()
+	if err != nil {
+		return "", nil, err
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	loginState := &models.FederatedLoginState{
+		StateHash:    HashToken(stateToken),
+		Provider:     provider,
+		CodeVerifier: codeVerifier,
+		Nonce:        nonce,
+		ClientID:     clientID,
+		RedirectURI:  redirectURI,
+		Scope:        scope,
+		State:        state,
+		ExpiresAt:    time.Now().Add(ttl),
+	}
+	if err := o.db.Create(loginState).Error; err != nil {
+		return "", nil, err
+	}
+
+	return stateToken, loginState, nil
+}
+
+// ConsumeFederatedLoginState returns and deletes the state of an upstream
+// sign-in; each state can be used once
+func (o *OAuthManager) ConsumeFederatedLoginState(provider, stateToken string) (*models.FederatedLoginState, error) {
+	var loginState models.FederatedLoginState
+	if err := o.db.Where("state_hash = ? AND provider = ? AND expires_at > ?",
+		HashToken(stateToken), provider, time.Now()).First(&loginState).Error; err != nil {
+		return nil, ErrInvalidFederatedState
+	}
+
+	// The delete guards against concurrent use of the same state
+	result := o.db.Delete(&loginState)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrInvalidFederatedState
+	}
+
+	return &loginState, nil
+}
+
+// FederatedLogin signs in the user behind a validated upstream identity.
+// Unknown identities are linked to the account with the same verified email
+// or, if the provider allows it, provisioned as new accounts.
+func (o *OAuthManager) FederatedLogin(provider *federation.Provider, claims *federation.IDTokenClaims, ipAddress string) (*models.User, error) {
+	var user *models.User
+
+	var identity models.FederatedIdentity
+	err := o.db.Where("provider = ? AND subject = ?", provider.Config.Name, claims.Subject).First(&identity).Error
+	switch {
+	case err == nil:
+		user, err = o.GetUserByID(identity.UserID)
+		if err != nil {
+			return nil, err
+		}
+	case err == gorm.ErrRecordNotFound:
+		user, err = o.linkOrProvision(provider, claims, ipAddress)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	if user.Disabled() {
+		o.RecordAuditEvent(models.AuditEvent{
+			Event:     AuditLoginFailed,
+			UserID:    &user.ID,
+			Email:     user.Email,
+			IPAddress: ipAddress,
+			Details:   "provider: " + provider.Config.Name + ", " + ErrUserDisabled.Error(),
+		})
+		return nil, ErrUserDisabled
+	}
+
+	if err := o.db.Model(&models.FederatedIdentity{}).
+		Where("provider = ? AND subject = ?", provider.Config.Name, claims.Subject).
+		Updates(map[string]interface{}{"last_login_at": time.Now(), "email": claims.Email}).Error; err != nil {
+		return nil, err
+	}
+
+	// Signing in during the grace period restores a deleted account
+	if user.DeletionScheduledAt != nil {
+		if err := o.cancelAccountDeletion(user, ipAddress); err != nil {
+			return nil, err
+		}
+	}
+
+	o.RecordAuditEvent(models.AuditEvent{
+		Event:     AuditLoginSucceeded,
+		UserID:    &user.ID,
+		Email:     user.Email,
+		IPAddress: ipAddress,
+		Details:   "provider: " + provider.Config.Name,
+	})
+
+	return user, nil
+}
+
+// linkOrProvision connects a new upstream identity to a local account
+func (o *OAuthManager) linkOrProvision(provider *federation.Provider, claims *federation.IDTokenClaims, ipAddress string) (*models.User, error) {
+	if claims.Email == "" || !(claims.EmailVerified || provider.Config.TrustEmail) {
+		return nil, ErrFederatedEmailUnverified
+	}
+	if !provider.AllowsEmail(claims.Email) {
+		return nil, ErrFederatedDomainNotAllowed
+	}
+
+	identity := &models.FederatedIdentity{
+		Provider: provider.Config.Name,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	}
+
+	var user models.User
+	err := o.db.Preload("Roles").Where("LOWER(email) = LOWER(?)", claims.Email).First(&user).Error
+	if err == nil {
+		if !provider.Config.LinkByEmail {
+			return nil, ErrFederatedAccountNotFound
+		}
+		// Linking to an unverified account would hand it to whoever
+		// registered the address first
+		if !user.EmailVerified() {
+			return nil, ErrFederatedLocalUnverified
+		}
+
+		identity.UserID = user.ID
+		if err := o.db.Create(identity).Error; err != nil {
+			return nil, err
+		}
+
+		o.RecordAuditEvent(models.AuditEvent{
+			Event:     AuditFederatedIdentityLinked,
+			UserID:    &user.ID,
+			Email:     user.Email,
+			IPAddress: ipAddress,
+			Details:   "provider: " + provider.Config.Name + ", subject: " + claims.Subject,
+		})
+		return &user, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	if !provider.Config.AutoProvision {
+		return nil, ErrFederatedAccountNotFound
+	}
+
+	// Provisioned accounts get an unusable password; they can set one
+	// through the password reset flow
+	unusable, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	hashedPassword, err := o.hasher.Hash(unusable)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	user = models.User{
+		Email:           claims.Email,
+		PasswordHash:    hashedPassword,
+		EmailVerifiedAt: &now,
+		DisplayName:     truncate(claims.Name, 100),
+	}
+	err = o.db.Transaction(func(tx *gorm.DB) error {
+		if err := o.createUserWithDefaultRole(tx, &user); err != nil {
+			return err
+		}
+		identity.UserID = user.ID
+		return tx.Create(identity).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	o.RecordAuditEvent(models.AuditEvent{
+		Event:     AuditFederatedUserProvisioned,
+		UserID:    &user.ID,
+		Email:     user.Email,
+		IPAddress: ipAddress,
+		Details:   "provider: " + provider.Config.Name + ", subject: " + claims.Subject,
+	})
+
+	return &user, nil
+}
+
+// UpstreamMFA reports whether the provider asserted a multi-factor sign-in
+// in the amr claim of the validated id_token. Only then does a federated
+// sign-in skip the local second factor.
+func UpstreamMFA(claims *federation.IDTokenClaims) bool {
+	return containsString(claims.AMR, AMRMFA)
+}
+
+// FederatedAMR returns the amr values for an upstream sign-in, keeping the
+// provider's multi-factor assertion
+func FederatedAMR(claims *federation.IDTokenClaims) []string {
+	amr := []string{AMRFederated}
+	if UpstreamMFA(claims) {
+		amr = append(amr, AMRMFA)
+	}
+	return amr
+}
@@ -0,0 +1,218 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"ishare-task-api/internal/config"
+	"ishare-task-api/internal/federation"
+	"ishare-task-api/internal/federation/federationtest"
+	"ishare-task-api/internal/models"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testFederatedCallback = "https://api.example.com/oauth/federated/test/callback"
+
+func newTestProvider(t *testing.T, cfg config.OIDCProviderConfig) *federation.Provider {
+	t.Helper()
+
+	registry, err := federation.NewRegistry(config.FederationConfig{
+		Providers: []config.OIDCProviderConfig{cfg},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewRegistry: %v", err)
+	}
+	provider, err := registry.Get(cfg.Name)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	return provider
+}
+
+// federatedSignIn runs an upstream sign-in the way the callback handler
+// does and returns the validated claims
+func federatedSignIn(t *testing.T, o *OAuthManager, provider *federation.Provider) *federation.IDTokenClaims {
+	t.Helper()
+	ctx := context.Background()
+
+	stateToken, loginState, err := o.BeginFederatedLogin(provider.Config.Name, "test-client",
+		"https://client.example.com/callback", "read", "client-state", time.Minute)
+	if err != nil {
+		t.Fatalf("BeginFederatedLogin: %v", err)
+	}
+	authURL, err := provider.AuthCodeURL(ctx, testFederatedCallback, stateToken, loginState.Nonce,
+		federation.CodeChallenge(loginState.CodeVerifier))
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+
+	callback := federationtest.Authorize(t, authURL)
+	consumed, err := o.ConsumeFederatedLoginState(provider.Config.Name, callback.Query().Get("state"))
+	if err != nil {
+		t.Fatalf("ConsumeFederatedLoginState: %v", err)
+	}
+	if consumed.ClientID != "test-client" || consumed.State != "client-state" {
+		t.Fatalf("consumed state = %+v", consumed)
+	}
+
+	token, err := provider.Exchange(ctx, callback.Query().Get("code"), consumed.CodeVerifier, testFederatedCallback)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	claims, err := provider.VerifyIDToken(ctx, token.IDToken, consumed.Nonce)
+	if err != nil {
+		t.Fatalf("VerifyIDToken: %v", err)
+	}
+	return claims
+}
+
+func TestFederatedLoginState(t *testing.T) {
+	o := newTestOAuthManager(t, config.OAuthConfig{})
+
+	stateToken, _, err := o.BeginFederatedLogin("test", "test-client", "https://client.example.com/callback", "", "", time.Minute)
+	if err != nil {
+		t.Fatalf("BeginFederatedLogin: %v", err)
+	}
+	if _, err := o.ConsumeFederatedLoginState("other", stateToken); err != ErrInvalidFederatedState {
+		t.Fatalf("consume for another provider error = %v; want ErrInvalidFederatedState", err)
+	}
+	if _, err := o.ConsumeFederatedLoginState("test", stateToken); err != nil {
+		t.Fatalf("ConsumeFederatedLoginState: %v", err)
+	}
+	if _, err := o.ConsumeFederatedLoginState("test", stateToken); err != ErrInvalidFederatedState {
+		t.Fatalf("second consume error = %v; want ErrInvalidFederatedState", err)
+	}
+
+	expired, _, err := o.BeginFederatedLogin("test", "test-client", "https://client.example.com/callback", "", "", -time.Minute)
+	if err != nil {
+		t.Fatalf("BeginFederatedLogin: %v", err)
+	}
+	if _, err := o.ConsumeFederatedLoginState("test", expired); err != ErrInvalidFederatedState {
+		t.Fatalf("consume of an expired state error = %v; want ErrInvalidFederatedState", err)
+	}
+}
+
+func TestFederatedLoginProvisionsUser(t *testing.T) {
+	o := newTestOAuthManager(t, config.OAuthConfig{})
+	rbac := NewRBAC(o.db, time.Minute)
+	idp := federationtest.NewIdP(t, "api-client", "api-secret")
+	cfg := idp.Config("test")
+	cfg.AutoProvision = true
+	provider := newTestProvider(t, cfg)
+
+	email := testEmail("federated-provision")
+	subject := "subject-" + email
+	idp.SetUser(jwt.MapClaims{"sub": subject, "email": email, "email_verified": true, "name": "Federated User"})
+
+	user, err := o.FederatedLogin(provider, federatedSignIn(t, o, provider), "127.0.0.1")
+	if err != nil {
+		t.Fatalf("FederatedLogin: %v", err)
+	}
+	if user.Email != email || user.DisplayName != "Federated User" || !user.EmailVerified() {
+		t.Errorf("provisioned user = %+v", user)
+	}
+	if roles := roleNames(t, rbac, user.ID); !roles[models.RoleMember] {
+		t.Errorf("roles = %v; want member", roles)
+	}
+
+	var identity models.FederatedIdentity
+	if err := o.db.Where("provider = ? AND subject = ?", "test", subject).First(&identity).Error; err != nil {
+		t.Fatalf("federated identity not stored: %v", err)
+	}
+	if identity.UserID != user.ID || identity.LastLoginAt == nil {
+		t.Errorf("identity = %+v", identity)
+	}
+
+	// The identity signs in to the same account even after its email changed
+	idp.SetUser(jwt.MapClaims{"sub": subject, "email": "renamed-" + email, "email_verified": true})
+	again, err := o.FederatedLogin(provider, federatedSignIn(t, o, provider), "127.0.0.1")
+	if err != nil {
+		t.Fatalf("second FederatedLogin: %v", err)
+	}
+	if again.ID != user.ID {
+		t.Errorf("second sign-in returned user %s; want %s", again.ID, user.ID)
+	}
+}
+
+func TestFederatedLoginLinksVerifiedAccount(t *testing.T) {
+	o := newTestOAuthManager(t, config.OAuthConfig{})
+	idp := federationtest.NewIdP(t, "api-client", "api-secret")
+	cfg := idp.Config("test")
+	cfg.LinkByEmail = true
+	provider := newTestProvider(t, cfg)
+
+	email := testEmail("federated-link")
+	local := createTestUser(t, o, email, "local-secret", true)
+
+	// Emails compare case insensitively
+	idp.SetUser(jwt.MapClaims{"sub": "subject-" + email, "email": strings.ToUpper(email), "email_verified": true})
+	user, err := o.FederatedLogin(provider, federatedSignIn(t, o, provider), "127.0.0.1")
+	if err != nil {
+		t.Fatalf("FederatedLogin: %v", err)
+	}
+	if user.ID != local.ID {
+		t.Fatalf("linked user %s; want the existing account %s", user.ID, local.ID)
+	}
+
+	var events int64
+	o.db.Model(&models.AuditEvent{}).
+		Where("event = ? AND user_id = ?", AuditFederatedIdentityLinked, local.ID).Count(&events)
+	if events != 1 {
+		t.Errorf("%d link audit events; want 1", events)
+	}
+}
+
+func TestFederatedLoginRejects(t *testing.T) {
+	o := newTestOAuthManager(t, config.OAuthConfig{})
+	idp := federationtest.NewIdP(t, "api-client", "api-secret")
+
+	linking := idp.Config("test")
+	linking.LinkByEmail = true
+	linking.AutoProvision = true
+	linking.AllowedDomains = []string{"example.com"}
+
+	notLinking := idp.Config("test")
+	notLinking.AutoProvision = true
+
+	notProvisioning := idp.Config("test")
+	notProvisioning.LinkByEmail = true
+
+	unverified := testEmail("federated-unverified")
+	createTestUser(t, o, unverified, "local-secret", false)
+	existing := testEmail("federated-existing")
+	createTestUser(t, o, existing, "local-secret", true)
+
+	tests := []struct {
+		name   string
+		cfg    config.OIDCProviderConfig
+		claims jwt.MapClaims
+		want   error
+	}{
+		{"unverified upstream email", linking,
+			jwt.MapClaims{"email": testEmail("federated-new"), "email_verified": false}, ErrFederatedEmailUnverified},
+		{"missing email", linking,
+			jwt.MapClaims{"email_verified": true}, ErrFederatedEmailUnverified},
+		{"domain not allowed", linking,
+			jwt.MapClaims{"email": strings.Replace(testEmail("federated-domain"), "@example.com", "@other.com", 1), "email_verified": true}, ErrFederatedDomainNotAllowed},
+		{"unverified local account", linking,
+			jwt.MapClaims{"email": unverified, "email_verified": true}, ErrFederatedLocalUnverified},
+		{"linking disabled", notLinking,
+			jwt.MapClaims{"email": existing, "email_verified": true}, ErrFederatedAccountNotFound},
+		{"provisioning disabled", notProvisioning,
+			jwt.MapClaims{"email": testEmail("federated-new"), "email_verified": true}, ErrFederatedAccountNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newTestProvider(t, tt.cfg)
+			tt.claims["sub"] = "subject-" + testEmail(tt.name)
+			idp.SetUser(tt.claims)
+
+			if _, err := o.FederatedLogin(provider, federatedSignIn(t, o, provider), "127.0.0.1"); err != tt.want {
+				t.Fatalf("FederatedLogin error = %v; want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"ishare-task-api/internal/config"
+	"ishare-task-api/internal/database"
+	"ishare-task-api/internal/models"
+	"ishare-task-api/internal/password"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var (
+	testDBOnce sync.Once
+	testDBConn *gorm.DB
+	testDBErr  error
+)
+
+// testDB returns the migrated database at TEST_DATABASE_URL. Tests that
+// need it are skipped when the variable is not set. Accounts are created
+// with unique emails and left behind, so use a disposable database.
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	testDBOnce.Do(func() {
+		testDBConn, testDBErr = gorm.Open(postgres.Open(dsn), &gorm.Config{
+			Logger: logger.Default.LogMode(logger.Silent),
+		})
+		if testDBErr == nil {
+			testDBErr = database.Migrate(testDBConn)
+		}
+	})
+	if testDBErr != nil {
+		t.Fatalf("failed to prepare the test database: %v", testDBErr)
+	}
+
+	return testDBConn
+}
+
+// newTestOAuthManager creates an OAuthManager on the test database with a
+// fast password hasher
+func newTestOAuthManager(t *testing.T, cfg config.OAuthConfig) *OAuthManager {
+	t.Helper()
+
+	hasher, err := password.NewHasher(config.PasswordConfig{
+		HashAlgorithm: password.AlgorithmBcrypt,
+		BcryptCost:    bcrypt.MinCost,
+	})
+	if err != nil {
+		t.Fatalf("NewHasher: %v", err)
+	}
+
+	return NewOAuthManager(cfg, testDB(t), nil, nil, nil, hasher)
+}
+
+// newTestJWTManager creates a JWTManager validating tokens in the given
+// mode
+func newTestJWTManager(t *testing.T, validationMode string) *JWTManager {
+	t.Helper()
+
+	jwtManager, err := NewJWTManager(config.JWTConfig{
+		Secret:         "test-secret-with-at-least-32-bytes",
+		Issuer:         "test",
+		Expiration:     time.Hour,
+		ValidationMode: validationMode,
+	})
+	if err != nil {
+		t.Fatalf("NewJWTManager: %v", err)
+	}
+	return jwtManager
+}
+
+// testEmail returns an email no earlier run has used
+func testEmail(name string) string {
+	return name + "-" + uuid.NewString() + "@example.com"
+}
+
+// createTestUser creates a local account with the password, verified
+// unless stated otherwise
+func createTestUser(t *testing.T, o *OAuthManager, email, pw string, verified bool) *models.User {
+	t.Helper()
+
+	hashedPassword, err := o.hasher.Hash(pw)
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	user := &models.User{
+		Email:        email,
+		PasswordHash: hashedPassword,
+	}
+	if verified {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+	if err := o.db.Transaction(func(tx *gorm.DB) error {
+		return o.createUserWithDefaultRole(tx, user)
+	}); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	return user
+}
+
+// roleNames returns the names of the roles the user holds
+func roleNames(t *testing.T, rbac *RBAC, userID uuid.UUID) map[string]bool {
+	t.Helper()
+
+	roles, err := rbac.UserRoles(userID)
+	if err != nil {
+		t.Fatalf("UserRoles: %v", err)
+	}
+	names := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		names[role.Name] = true
+	}
+	return names
+}
@@ -0,0 +1,237 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+
+	"ishare-task-api/internal/config"
+)
+
+// JWE key management algorithms
+const (
+	EncryptionAlgDirect     = "dir"
+	EncryptionAlgRSAOAEP256 = "RSA-OAEP-256"
+)
+
+// contentEncryption is the only supported JWE content encryption algorithm
+const contentEncryption = "A256GCM"
+
+// tokenEncrypter wraps signed tokens into compact JWE (JWS-in-JWE)
+type tokenEncrypter struct {
+	alg     string
+	key     []byte          // content encryption key for "dir"
+	rsaKey  *rsa.PrivateKey // key pair for "RSA-OAEP-256"
+	clients map[string]bool
+}
+
+// newTokenEncrypter loads the encryption key configured for the given
+// algorithm; it returns nil when token encryption is disabled
+func newTokenEncrypter(cfg config.JWTConfig) (*tokenEncrypter, error) {
+	if cfg.EncryptionAlg == "" {
+		return nil, nil
+	}
+
+	e := &tokenEncrypter{
+		alg:     cfg.EncryptionAlg,
+		clients: make(map[string]bool),
+	}
+	for _, clientID := range cfg.EncryptedClients {
+		e.clients[clientID] = true
+	}
+
+	switch cfg.EncryptionAlg {
+	case EncryptionAlgDirect:
+		key, err := base64.StdEncoding.DecodeString(cfg.EncryptionKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWT encryption key: %w", err)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("JWT encryption key must be 32 bytes for %s", contentEncryption)
+		}
+		e.key = key
+	case EncryptionAlgRSAOAEP256:
+		key, err := loadRSAPrivateKey(cfg.EncryptionKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		e.rsaKey = key
+	default:
+		return nil, fmt.Errorf("unsupported JWT encryption algorithm: %s", cfg.EncryptionAlg)
+	}
+
+	return e, nil
+}
+
+// loadRSAPrivateKey reads a PEM encoded PKCS#1 or PKCS#8 RSA private key
+func loadRSAPrivateKey(path string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT encryption key: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("invalid PEM in JWT encryption key")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWT encryption key: %w", err)
+	}
+
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("JWT encryption key is not an RSA key")
+	}
+
+	return key, nil
+}
+
+// encryptsFor reports whether tokens issued to the client are encrypted
+func (e *tokenEncrypter) encryptsFor(clientID string) bool {
+	return e.clients["*"] || e.clients[clientID]
+}
+
+// encrypt wraps a JWS into a compact JWE
+func (e *tokenEncrypter) encrypt(jws string) (string, error) {
+	header := map[string]string{
+		"alg": e.alg,
+		"enc": contentEncryption,
+		"cty": "JWT",
+	}
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	headerB64 := base64.RawURLEncoding.EncodeToString(headerJSON)
+
+	// Determine the content encryption key
+	var cek, encryptedKey []byte
+	switch e.alg {
+	case EncryptionAlgDirect:
+		cek = e.key
+	case EncryptionAlgRSAOAEP256:
+		cek = make([]byte, 32)
+		if _, err := rand.Read(cek); err != nil {
+			return "", err
+		}
+		encryptedKey, err = rsa.EncryptOAEP(sha256.New(), rand.Reader, &e.rsaKey.PublicKey, cek, nil)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	gcm, err := newGCM(cek)
+	if err != nil {
+		return "", err
+	}
+
+	iv := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(iv); err != nil {
+		return "", err
+	}
+
+	// The protected header is the additional authenticated data
+	sealed := gcm.Seal(nil, iv, []byte(jws), []byte(headerB64))
+	ciphertext, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+
+	return strings.Join([]string{
+		headerB64,
+		base64.RawURLEncoding.EncodeToString(encryptedKey),
+		base64.RawURLEncoding.EncodeToString(iv),
+		base64.RawURLEncoding.EncodeToString(ciphertext),
+		base64.RawURLEncoding.EncodeToString(tag),
+	}, "."), nil
+}
+
+// decrypt unwraps a compact JWE and returns the nested JWS
+func (e *tokenEncrypter) decrypt(jwe string) (string, error) {
+	parts := strings.Split(jwe, ".")
+	if len(parts) != 5 {
+		return "", fmt.Errorf("invalid JWE format")
+	}
+
+	headerBytes, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", err
+	}
+
+	var header map[string]interface{}
+	if err := json.Unmarshal(headerBytes, &header); err != nil {
+		return "", err
+	}
+
+	// Only accept the configured algorithms
+	if alg, _ := header["alg"].(string); alg != e.alg {
+		return "", fmt.Errorf("unexpected key management algorithm: %v", header["alg"])
+	}
+	if enc, _ := header["enc"].(string); enc != contentEncryption {
+		return "", fmt.Errorf("unexpected content encryption algorithm: %v", header["enc"])
+	}
+
+	decoded := make([][]byte, 4)
+	for i, part := range parts[1:] {
+		if decoded[i], err = base64.RawURLEncoding.DecodeString(part); err != nil {
+			return "", err
+		}
+	}
+	encryptedKey, iv, ciphertext, tag := decoded[0], decoded[1], decoded[2], decoded[3]
+
+	var cek []byte
+	switch e.alg {
+	case EncryptionAlgDirect:
+		if len(encryptedKey) != 0 {
+			return "", fmt.Errorf("unexpected encrypted key for direct encryption")
+		}
+		cek = e.key
+	case EncryptionAlgRSAOAEP256:
+		cek, err = rsa.DecryptOAEP(sha256.New(), nil, e.rsaKey, encryptedKey, nil)
+		if err != nil {
+			return "", fmt.Errorf("failed to decrypt content encryption key")
+		}
+	}
+
+	gcm, err := newGCM(cek)
+	if err != nil {
+		return "", err
+	}
+	if len(iv) != gcm.NonceSize() {
+		return "", fmt.Errorf("invalid JWE initialization vector")
+	}
+
+	plaintext, err := gcm.Open(nil, iv, append(ciphertext, tag...), []byte(parts[0]))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt token")
+	}
+
+	return string(plaintext), nil
+}
+
+// newGCM creates an AES-GCM cipher for a 256-bit key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("invalid content encryption key length")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
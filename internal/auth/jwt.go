@@ -18,14 +18,21 @@ import (
 
 // JWTManager handles JWT token operations
 type JWTManager struct {
-	config config.JWTConfig
+	config    config.JWTConfig
+	encrypter *tokenEncrypter
 }
 
 // NewJWTManager creates a new JWT manager
-func NewJWTManager(cfg config.JWTConfig) *JWTManager {
-	return &JWTManager{
-		config: cfg,
+func NewJWTManager(cfg config.JWTConfig) (*JWTManager, error) {
+	encrypter, err := newTokenEncrypter(cfg)
+	if err != nil {
+		return nil, err
 	}
+
+	return &JWTManager{
+		config:    cfg,
+		encrypter: encrypter,
+	}, nil
 }
 
 // Claims represents JWT claims
@@ -33,6 +40,10 @@ type Claims struct {
 	UserID uuid.UUID `json:"sub"`
 	Email  string    `json:"email"`
 	Scope  string    `json:"scope"`
+	AMR    []string  `json:"amr,omitempty"`
+	ACR    string    `json:"acr,omitempty"`
+	Roles  []string  `json:"roles,omitempty"`
+	OrgID  uuid.UUID `json:"org_id,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -77,7 +88,11 @@ func (j *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
 }
 
 // GenerateJWS generates a JWS token (JWT with explicit JWS structure)
-func (j *JWTManager) GenerateJWS(user *models.User, scope string) (string, error) {
+// identified by the given jti. amr lists the authentication methods used to
+// sign in and determines the acr claim. The names of the user's loaded
+// roles are included in the roles claim and the active organization, if
+// any, in the org_id claim.
+func (j *JWTManager) GenerateJWS(user *models.User, orgID uuid.UUID, scope, jti string, amr []string) (string, error) {
 	now := time.Now()
 	
 	// Create JWS header
@@ -96,6 +111,17 @@ func (j *JWTManager) GenerateJWS(user *models.User, scope string) (string, error
 		"exp": now.Add(j.config.Expiration).Unix(),
 		"iat": now.Unix(),
 		"nbf": now.Unix(),
+		"jti": jti,
+		"acr": ACRForAMR(amr),
+	}
+	if len(amr) > 0 {
+		payload["amr"] = amr
+	}
+	if roles := user.RoleNames(); len(roles) > 0 {
+		payload["roles"] = roles
+	}
+	if orgID != uuid.Nil {
+		payload["org_id"] = orgID.String()
 	}
 
 	// Encode header and payload
@@ -121,8 +147,33 @@ func (j *JWTManager) GenerateJWS(user *models.User, scope string) (string, error
 	return jws, nil
 }
 
-// ValidateJWS validates a JWS token
+// EncryptsFor reports whether tokens issued to the client must be encrypted
+func (j *JWTManager) EncryptsFor(clientID string) bool {
+	return j.encrypter != nil && j.encrypter.encryptsFor(clientID)
+}
+
+// EncryptJWS wraps a signed token into a JWE (JWS-in-JWE)
+func (j *JWTManager) EncryptJWS(jws string) (string, error) {
+	if j.encrypter == nil {
+		return "", fmt.Errorf("token encryption is not configured")
+	}
+	return j.encrypter.encrypt(jws)
+}
+
+// ValidateJWS validates a JWS token, transparently decrypting JWE-wrapped tokens
 func (j *JWTManager) ValidateJWS(jws string) (*Claims, error) {
+	if strings.Count(jws, ".") == 4 {
+		if j.encrypter == nil {
+			return nil, fmt.Errorf("encrypted tokens are not supported")
+		}
+
+		nested, err := j.encrypter.decrypt(jws)
+		if err != nil {
+			return nil, err
+		}
+		jws = nested
+	}
+
 	parts := strings.Split(jws, ".")
 	if len(parts) != 3 {
 		return nil, fmt.Errorf("invalid JWS format")
@@ -130,11 +181,26 @@ func (j *JWTManager) ValidateJWS(jws string) (*Claims, error) {
 
 	headerB64, payloadB64, signatureB64 := parts[0], parts[1], parts[2]
 
+	// Verify header
+	headerBytes, err := base64.RawURLEncoding.DecodeString(headerB64)
+	if err != nil {
+		return nil, err
+	}
+
+	var header map[string]interface{}
+	if err := json.Unmarshal(headerBytes, &header); err != nil {
+		return nil, err
+	}
+
+	if alg, _ := header["alg"].(string); alg != "HS256" {
+		return nil, fmt.Errorf("unexpected signing method: %v", header["alg"])
+	}
+
 	// Verify signature
 	signingInput := headerB64 + "." + payloadB64
 	expectedSignature := j.sign(signingInput)
 	
-	if signatureB64 != expectedSignature {
+	if !hmac.Equal([]byte(signatureB64), []byte(expectedSignature)) {
 		return nil, fmt.Errorf("invalid signature")
 	}
 
@@ -149,13 +215,33 @@ func (j *JWTManager) ValidateJWS(jws string) (*Claims, error) {
 		return nil, err
 	}
 
+	now := time.Now()
+
 	// Check expiration
-	if exp, ok := payload["exp"].(float64); ok {
-		if time.Unix(int64(exp), 0).Before(time.Now()) {
-			return nil, fmt.Errorf("token expired")
+	exp, ok := payload["exp"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("missing expiration in token")
+	}
+	expiresAt := time.Unix(int64(exp), 0)
+	if expiresAt.Before(now) {
+		return nil, fmt.Errorf("token expired")
+	}
+
+	// Check not-before
+	if nbf, ok := payload["nbf"].(float64); ok {
+		if time.Unix(int64(nbf), 0).After(now) {
+			return nil, fmt.Errorf("token not yet valid")
 		}
 	}
 
+	// Check issuer and audience
+	if iss, _ := payload["iss"].(string); iss != j.config.Issuer {
+		return nil, fmt.Errorf("invalid token issuer")
+	}
+	if aud, _ := payload["aud"].(string); aud != j.config.Audience {
+		return nil, fmt.Errorf("invalid token audience")
+	}
+
 	// Extract claims
 	userIDStr, ok := payload["sub"].(string)
 	if !ok {
@@ -169,11 +255,34 @@ func (j *JWTManager) ValidateJWS(jws string) (*Claims, error) {
 
 	email, _ := payload["email"].(string)
 	scope, _ := payload["scope"].(string)
+	jti, _ := payload["jti"].(string)
+	acr, _ := payload["acr"].(string)
+
+	var orgID uuid.UUID
+	if orgIDStr, ok := payload["org_id"].(string); ok {
+		if orgID, err = uuid.Parse(orgIDStr); err != nil {
+			return nil, fmt.Errorf("invalid organization ID format")
+		}
+	}
 
 	claims := &Claims{
 		UserID: userID,
 		Email:  email,
 		Scope:  scope,
+		AMR:    stringList(payload["amr"]),
+		ACR:    acr,
+		Roles:  stringList(payload["roles"]),
+		OrgID:  orgID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Issuer:    j.config.Issuer,
+			Audience:  []string{j.config.Audience},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	if iat, ok := payload["iat"].(float64); ok {
+		claims.IssuedAt = jwt.NewNumericDate(time.Unix(int64(iat), 0))
 	}
 
 	return claims, nil
@@ -186,6 +295,19 @@ func (j *JWTManager) sign(input string) string {
 	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
 }
 
+// stringList converts a JSON array claim to a string slice
+func stringList(value interface{}) []string {
+	var list []string
+	if values, ok := value.([]interface{}); ok {
+		for _, item := range values {
+			if str, ok := item.(string); ok {
+				list = append(list, str)
+			}
+		}
+	}
+	return list
+}
+
 // HasScope checks if the token has the required scope
 func (j *JWTManager) HasScope(claims *Claims, requiredScope string) bool {
 	if claims.Scope == "" {
@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"ishare-task-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// lastUsedInterval limits how often the last-used time of a token is written
+const lastUsedInterval = time.Minute
+
+// lastUsedTracker records when tokens were last used without writing to the
+// database on every request
+type lastUsedTracker struct {
+	db     *gorm.DB
+	model  interface{}
+	column string
+
+	mu      sync.Mutex
+	touched map[string]time.Time // key -> last write
+}
+
+// newLastUsedTracker tracks access tokens by jti
+func newLastUsedTracker(db *gorm.DB) *lastUsedTracker {
+	return newLastUsedTrackerFor(db, &models.AccessToken{}, "jti")
+}
+
+// newLastUsedTrackerFor tracks rows of the model identified by column
+func newLastUsedTrackerFor(db *gorm.DB, model interface{}, column string) *lastUsedTracker {
+	return &lastUsedTracker{
+		db:      db,
+		model:   model,
+		column:  column,
+		touched: make(map[string]time.Time),
+	}
+}
+
+// touch records a use of the token, writing it together with the extra
+// column updates asynchronously at most once per lastUsedInterval
+func (t *lastUsedTracker) touch(key string, updates map[string]interface{}) {
+	now := time.Now()
+
+	t.mu.Lock()
+	if last, ok := t.touched[key]; ok && now.Sub(last) < lastUsedInterval {
+		t.mu.Unlock()
+		return
+	}
+	t.touched[key] = now
+	if len(t.touched) > 10000 {
+		t.prune(now)
+	}
+	t.mu.Unlock()
+
+	values := map[string]interface{}{"last_used_at": now}
+	for column, value := range updates {
+		values[column] = value
+	}
+
+	go func() {
+		if err := t.db.Model(t.model).Where(t.column+" = ?", key).
+			Updates(values).Error; err != nil {
+			log.Printf("Failed to record token use: %v", err)
+		}
+	}()
+}
+
+// prune drops entries that no longer throttle writes; callers hold t.mu
+func (t *lastUsedTracker) prune(now time.Time) {
+	for key, last := range t.touched {
+		if now.Sub(last) >= lastUsedInterval {
+			delete(t.touched, key)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package auth
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"ishare-task-api/internal/config"
+	"ishare-task-api/internal/directory"
+	"ishare-task-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// AuditDirectoryUserProvisioned is recorded when a directory user signs in
+// for the first time and gets a local account
+const AuditDirectoryUserProvisioned = "ldap.provisioned"
+
+// ErrDirectoryLocalUnverified is returned when a directory user signs in
+// with the email of an unverified local account
+var ErrDirectoryLocalUnverified = errors.New("an account with this email exists but its email is not verified")
+
+// LDAPAuthenticator checks passwords against an LDAP or Active Directory
+// server. Directory users are provisioned on their first sign-in and their
+// mapped roles follow group membership.
+type LDAPAuthenticator struct {
+	o      *OAuthManager
+	rbac   *RBAC
+	client *directory.Client
+	cfg    config.LDAPConfig
+}
+
+// NewLDAPAuthenticator creates an authenticator for directory accounts
+func NewLDAPAuthenticator(o *OAuthManager, rbac *RBAC, client *directory.Client, cfg config.LDAPConfig) *LDAPAuthenticator {
+	return &LDAPAuthenticator{
+		o:      o,
+		rbac:   rbac,
+		client: client,
+		cfg:    cfg,
+	}
+}
+
+// Name returns "ldap"
+func (a *LDAPAuthenticator) Name() string {
+	return AuthenticatorLDAP
+}
+
+// Authenticate binds to the directory as the user and returns the matching
+// local account
+func (a *LDAPAuthenticator) Authenticate(email, password string) (*models.User, error) {
+	entry, err := a.client.Authenticate(email, password)
+	if err != nil {
+		if err == directory.ErrInvalidCredentials {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	var user models.User
+	err = a.o.db.Where("LOWER(email) = LOWER(?)", entry.Email).First(&user).Error
+	switch {
+	case err == nil:
+		// The local password of an unverified account may belong to
+		// whoever registered the address first
+		if !user.EmailVerified() {
+			return nil, ErrDirectoryLocalUnverified
+		}
+	case err == gorm.ErrRecordNotFound:
+		if !a.cfg.AutoProvision {
+			return nil, ErrInvalidCredentials
+		}
+		provisioned, err := a.provision(entry)
+		if err != nil {
+			return nil, err
+		}
+		user = *provisioned
+	default:
+		return nil, err
+	}
+
+	a.syncRoles(&user, entry)
+	return &user, nil
+}
+
+// provision creates a verified local account for a directory user. The
+// account gets an unusable password; the directory checks the password.
+func (a *LDAPAuthenticator) provision(entry *directory.Entry) (*models.User, error) {
+	unusable, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	hashedPassword, err := a.o.hasher.Hash(unusable)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	user := &models.User{
+		Email:           entry.Email,
+		PasswordHash:    hashedPassword,
+		EmailVerifiedAt: &now,
+		DisplayName:     truncate(entry.DisplayName, 100),
+	}
+	if err := a.o.db.Transaction(func(tx *gorm.DB) error {
+		return a.o.createUserWithDefaultRole(tx, user)
+	}); err != nil {
+		return nil, err
+	}
+
+	a.o.RecordAuditEvent(models.AuditEvent{
+		Event:   AuditDirectoryUserProvisioned,
+		UserID:  &user.ID,
+		Email:   user.Email,
+		Details: "dn: " + entry.DN,
+	})
+
+	return user, nil
+}
+
+// syncRoles grants the roles mapped from the user's groups and revokes
+// mapped roles of groups the user left. Roles not named in the mapping are
+// left alone. Failures are logged; they do not block the sign-in.
+func (a *LDAPAuthenticator) syncRoles(user *models.User, entry *directory.Entry) {
+	if len(a.cfg.GroupRoles) == 0 {
+		return
+	}
+
+	granted := make(map[string]bool)
+	for groupDN, role := range a.cfg.GroupRoles {
+		if _, ok := granted[role]; !ok {
+			granted[role] = false
+		}
+		for _, group := range entry.Groups {
+			if strings.EqualFold(group, groupDN) {
+				granted[role] = true
+			}
+		}
+	}
+
+	current, err := a.rbac.UserRoles(user.ID)
+	if err != nil {
+		log.Printf("Failed to load roles of %s for LDAP group sync: %v", user.Email, err)
+		return
+	}
+	held := make(map[string]bool, len(current))
+	for _, role := range current {
+		held[role.Name] = true
+	}
+
+	for role, member := range granted {
+		switch {
+		case member && !held[role]:
+			if err := a.rbac.AssignRole(user.ID, role); err != nil {
+				log.Printf("Failed to grant role %s to %s from LDAP groups: %v", role, user.Email, err)
+				continue
+			}
+			a.o.RecordAuditEvent(models.AuditEvent{
+				Event:   AuditRoleAssigned,
+				UserID:  &user.ID,
+				Email:   user.Email,
+				Details: "role: " + role + ", from LDAP groups",
+			})
+		case !member && held[role]:
+			if err := a.rbac.RemoveRole(user.ID, role); err != nil {
+				log.Printf("Failed to revoke role %s from %s after LDAP group change: %v", role, user.Email, err)
+				continue
+			}
+			a.o.RecordAuditEvent(models.AuditEvent{
+				Event:   AuditRoleRemoved,
+				UserID:  &user.ID,
+				Email:   user.Email,
+				Details: "role: " + role + ", from LDAP groups",
+			})
+		}
+	}
+}
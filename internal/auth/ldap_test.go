@@ -0,0 +1,207 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"ishare-task-api/internal/config"
+	"ishare-task-api/internal/directory"
+	"ishare-task-api/internal/directory/directorytest"
+	"ishare-task-api/internal/models"
+)
+
+const (
+	testPeopleDN  = "ou=people,dc=example,dc=com"
+	testViewersDN = "cn=viewers,ou=groups,dc=example,dc=com"
+)
+
+func testLDAPConfig(url string) config.LDAPConfig {
+	return config.LDAPConfig{
+		URL:            url,
+		Timeout:        5 * time.Second,
+		BaseDN:         testPeopleDN,
+		UserFilter:     "(mail=%s)",
+		EmailAttribute: "mail",
+		NameAttribute:  "cn",
+		GroupAttribute: "memberOf",
+		GroupRoles:     map[string]string{testViewersDN: models.RoleViewer},
+		AutoProvision:  true,
+	}
+}
+
+func newTestLDAPAuthenticator(t *testing.T, o *OAuthManager, rbac *RBAC, cfg config.LDAPConfig) *LDAPAuthenticator {
+	t.Helper()
+
+	client, err := directory.NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	return NewLDAPAuthenticator(o, rbac, client, cfg)
+}
+
+// addDirectoryUser adds a person to the directory and returns its DN
+func addDirectoryUser(server *directorytest.Server, email, pw string, groups ...string) string {
+	dn := "uid=" + email + "," + testPeopleDN
+	server.AddEntry(dn, pw, map[string][]string{
+		"mail":     {email},
+		"cn":       {"Directory User"},
+		"memberOf": groups,
+	})
+	return dn
+}
+
+func TestLDAPAuthenticatorProvisionsUser(t *testing.T) {
+	o := newTestOAuthManager(t, config.OAuthConfig{})
+	rbac := NewRBAC(o.db, time.Minute)
+	server := directorytest.NewServer(t)
+	authenticator := newTestLDAPAuthenticator(t, o, rbac, testLDAPConfig(server.URL))
+
+	email := testEmail("ldap-provision")
+	addDirectoryUser(server, email, "directory-secret")
+
+	user, err := authenticator.Authenticate(email, "directory-secret")
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if user.Email != email || user.DisplayName != "Directory User" || !user.EmailVerified() {
+		t.Errorf("provisioned user = %+v", user)
+	}
+	if roles := roleNames(t, rbac, user.ID); !roles[models.RoleMember] {
+		t.Errorf("roles = %v; want member", roles)
+	}
+
+	var events int64
+	o.db.Model(&models.AuditEvent{}).
+		Where("event = ? AND user_id = ?", AuditDirectoryUserProvisioned, user.ID).Count(&events)
+	if events != 1 {
+		t.Errorf("%d provisioning audit events; want 1", events)
+	}
+
+	// The directory password, not the unusable local one, signs in again
+	again, err := authenticator.Authenticate(email, "directory-secret")
+	if err != nil {
+		t.Fatalf("second Authenticate: %v", err)
+	}
+	if again.ID != user.ID {
+		t.Errorf("second sign-in returned user %s; want %s", again.ID, user.ID)
+	}
+	if _, err := NewLocalAuthenticator(o).Authenticate(email, "directory-secret"); err != ErrInvalidCredentials {
+		t.Errorf("local Authenticate error = %v; want ErrInvalidCredentials", err)
+	}
+}
+
+func TestLDAPAuthenticatorRejects(t *testing.T) {
+	o := newTestOAuthManager(t, config.OAuthConfig{})
+	rbac := NewRBAC(o.db, time.Minute)
+	server := directorytest.NewServer(t)
+	cfg := testLDAPConfig(server.URL)
+	authenticator := newTestLDAPAuthenticator(t, o, rbac, cfg)
+
+	cfg.AutoProvision = false
+	withoutProvisioning := newTestLDAPAuthenticator(t, o, rbac, cfg)
+
+	unverified := testEmail("ldap-unverified")
+	createTestUser(t, o, unverified, "local-secret", false)
+	addDirectoryUser(server, unverified, "directory-secret")
+
+	unprovisioned := testEmail("ldap-unprovisioned")
+	addDirectoryUser(server, unprovisioned, "directory-secret")
+
+	tests := []struct {
+		name          string
+		authenticator *LDAPAuthenticator
+		email         string
+		password      string
+		want          error
+	}{
+		{"wrong password", authenticator, unprovisioned, "wrong", ErrInvalidCredentials},
+		{"unknown user", authenticator, testEmail("ldap-unknown"), "directory-secret", ErrInvalidCredentials},
+		{"unverified local account", authenticator, unverified, "directory-secret", ErrDirectoryLocalUnverified},
+		{"provisioning disabled", withoutProvisioning, unprovisioned, "directory-secret", ErrInvalidCredentials},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.authenticator.Authenticate(tt.email, tt.password); err != tt.want {
+				t.Fatalf("Authenticate error = %v; want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLDAPAuthenticatorSyncsRoles(t *testing.T) {
+	o := newTestOAuthManager(t, config.OAuthConfig{})
+	rbac := NewRBAC(o.db, time.Minute)
+	server := directorytest.NewServer(t)
+	authenticator := newTestLDAPAuthenticator(t, o, rbac, testLDAPConfig(server.URL))
+
+	email := testEmail("ldap-groups")
+	dn := addDirectoryUser(server, email, "directory-secret", testViewersDN)
+
+	user, err := authenticator.Authenticate(email, "directory-secret")
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if roles := roleNames(t, rbac, user.ID); !roles[models.RoleViewer] || !roles[models.RoleMember] {
+		t.Fatalf("roles after joining the group = %v; want viewer and member", roles)
+	}
+
+	// Leaving the group revokes the mapped role but keeps unmapped ones
+	server.SetAttribute(dn, "memberOf")
+	if _, err := authenticator.Authenticate(email, "directory-secret"); err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if roles := roleNames(t, rbac, user.ID); roles[models.RoleViewer] || !roles[models.RoleMember] {
+		t.Fatalf("roles after leaving the group = %v; want member only", roles)
+	}
+
+	// Group names compare case insensitively, as DNs do
+	server.SetAttribute(dn, "memberOf", "CN=Viewers,OU=Groups,DC=example,DC=com")
+	if _, err := authenticator.Authenticate(email, "directory-secret"); err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if roles := roleNames(t, rbac, user.ID); !roles[models.RoleViewer] {
+		t.Fatalf("roles after joining the group again = %v; want viewer", roles)
+	}
+}
+
+func TestAuthenticatorChainFallsBackToLocal(t *testing.T) {
+	o := newTestOAuthManager(t, config.OAuthConfig{})
+	rbac := NewRBAC(o.db, time.Minute)
+	server := directorytest.NewServer(t)
+	ldapAuthenticator := newTestLDAPAuthenticator(t, o, rbac, testLDAPConfig(server.URL))
+	o.SetAuthenticators(ldapAuthenticator, NewLocalAuthenticator(o))
+
+	local := testEmail("chain-local")
+	localUser := createTestUser(t, o, local, "local-secret", true)
+
+	directoryEmail := testEmail("chain-directory")
+	addDirectoryUser(server, directoryEmail, "directory-secret")
+
+	user, name, err := o.authenticate(local, "local-secret")
+	if err != nil || name != AuthenticatorLocal || user.ID != localUser.ID {
+		t.Fatalf("authenticate local user = %v, %q, %v; want the local account", user, name, err)
+	}
+
+	user, name, err = o.authenticate(directoryEmail, "directory-secret")
+	if err != nil || name != AuthenticatorLDAP || user.Email != directoryEmail {
+		t.Fatalf("authenticate directory user = %v, %q, %v; want the directory account", user, name, err)
+	}
+
+	if _, _, err := o.authenticate(local, "wrong"); err != ErrInvalidCredentials {
+		t.Fatalf("authenticate with a wrong password error = %v; want ErrInvalidCredentials", err)
+	}
+
+	// An unreachable directory does not lock out local accounts, but its
+	// error is reported when no authenticator accepts the credentials
+	unreachable := testLDAPConfig("ldap://127.0.0.1:1")
+	unreachable.Timeout = time.Second
+	o.SetAuthenticators(newTestLDAPAuthenticator(t, o, rbac, unreachable), NewLocalAuthenticator(o))
+
+	user, name, err = o.authenticate(local, "local-secret")
+	if err != nil || name != AuthenticatorLocal || user.ID != localUser.ID {
+		t.Fatalf("authenticate with the directory down = %v, %q, %v; want the local account", user, name, err)
+	}
+	if _, _, err := o.authenticate(local, "wrong"); err == nil || err == ErrInvalidCredentials {
+		t.Fatalf("authenticate with the directory down error = %v; want the directory error", err)
+	}
+}
@@ -0,0 +1,383 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"ishare-task-api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Authentication method references (RFC 8176) recorded in the amr claim
+const (
+	AMRPassword     = "pwd"
+	AMROTP          = "otp"
+	AMRRecoveryCode = "rcc" // single-use recovery code
+	AMRMFA          = "mfa"
+)
+
+// Authentication context class references recorded in the acr claim
+const (
+	ACRSingleFactor = "urn:ishare-task-api:acr:1fa"
+	ACRMultiFactor  = "urn:ishare-task-api:acr:2fa"
+)
+
+const (
+	recoveryCodeCount    = 10
+	mfaChallengeTTL      = 5 * time.Minute
+	mfaChallengeAttempts = 5
+)
+
+// MFA errors
+var (
+	ErrMFAAlreadyEnabled   = errors.New("multi-factor authentication is already enabled")
+	ErrMFANotEnabled       = errors.New("multi-factor authentication is not enabled")
+	ErrMFAEnrollmentNeeded = errors.New("no pending multi-factor enrollment")
+	ErrInvalidMFACode      = errors.New("invalid authentication code")
+	ErrInvalidMFAChallenge = errors.New("invalid or expired multi-factor challenge")
+)
+
+// ACRForAMR returns the acr value matching the authentication methods used
+func ACRForAMR(amr []string) string {
+	for _, method := range amr {
+		if method == AMRMFA {
+			return ACRMultiFactor
+		}
+	}
+	return ACRSingleFactor
+}
+
+// MFAEnabled reports whether the user has a confirmed TOTP authenticator
+func (o *OAuthManager) MFAEnabled(userID uuid.UUID) (bool, error) {
+	var count int64
+	if err := o.db.Model(&models.MFACredential{}).
+		Where("user_id = ? AND confirmed_at IS NOT NULL", userID).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// GetMFAStatus returns the confirmed credential (if any) and the number of
+// unused recovery codes
+func (o *OAuthManager) GetMFAStatus(userID uuid.UUID) (*models.MFACredential, int64, error) {
+	var remaining int64
+	if err := o.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&remaining).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var credential models.MFACredential
+	if err := o.db.Where("user_id = ? AND confirmed_at IS NOT NULL", userID).First(&credential).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, remaining, nil
+		}
+		return nil, 0, err
+	}
+
+	return &credential, remaining, nil
+}
+
+// BeginTOTPEnrollment generates a new TOTP secret for the user. The
+// credential stays inactive until confirmed with a valid code.
+func (o *OAuthManager) BeginTOTPEnrollment(user *models.User) (string, string, error) {
+	enabled, err := o.MFAEnabled(user.ID)
+	if err != nil {
+		return "", "", err
+	}
+	if enabled {
+		return "", "", ErrMFAAlreadyEnabled
+	}
+
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		return "", "", err
+	}
+
+	encrypted, err := o.encryptMFASecret(secret)
+	if err != nil {
+		return "", "", err
+	}
+
+	err = o.db.Transaction(func(tx *gorm.DB) error {
+		// Replace any earlier, unconfirmed enrollment
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.MFACredential{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.MFACredential{
+			UserID:          user.ID,
+			SecretEncrypted: encrypted,
+		}).Error
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	return secret, TOTPURI(o.jwt.config.Issuer, user.Email, secret), nil
+}
+
+// ConfirmTOTPEnrollment activates the pending TOTP credential and returns a
+// fresh set of recovery codes
+func (o *OAuthManager) ConfirmTOTPEnrollment(userID uuid.UUID, code string) ([]string, error) {
+	var credential models.MFACredential
+	if err := o.db.Where("user_id = ?", userID).First(&credential).Error; err != nil {
+		return nil, ErrMFAEnrollmentNeeded
+	}
+	if credential.ConfirmedAt != nil {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	if err := o.useTOTPCode(&credential, code); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := o.db.Model(&credential).Update("confirmed_at", now).Error; err != nil {
+		return nil, err
+	}
+
+	return o.replaceRecoveryCodes(userID)
+}
+
+// DisableTOTP removes the TOTP credential and recovery codes after checking
+// a TOTP or recovery code
+func (o *OAuthManager) DisableTOTP(userID uuid.UUID, code string) error {
+	if _, err := o.VerifyMFACode(userID, code); err != nil {
+		return err
+	}
+
+	return o.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.MFACredential{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+	})
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes after checking a TOTP
+// or recovery code
+func (o *OAuthManager) RegenerateRecoveryCodes(userID uuid.UUID, code string) ([]string, error) {
+	if _, err := o.VerifyMFACode(userID, code); err != nil {
+		return nil, err
+	}
+
+	return o.replaceRecoveryCodes(userID)
+}
+
+// VerifyMFACode checks a TOTP code or, failing that, consumes a recovery
+// code. It returns the authentication methods to record in the amr claim:
+// otp for a TOTP code and rcc for a recovery code, both with mfa.
+func (o *OAuthManager) VerifyMFACode(userID uuid.UUID, code string) ([]string, error) {
+	var credential models.MFACredential
+	if err := o.db.Where("user_id = ? AND confirmed_at IS NOT NULL", userID).First(&credential).Error; err != nil {
+		return nil, ErrMFANotEnabled
+	}
+
+	if err := o.useTOTPCode(&credential, code); err == nil {
+		return []string{AMROTP, AMRMFA}, nil
+	}
+
+	// Fall back to a recovery code
+	result := o.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, HashToken(normalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrInvalidMFACode
+	}
+
+	return []string{AMRRecoveryCode, AMRMFA}, nil
+}
+
+// CreateMFAChallenge records a pending second-factor step for an authorize
+// request and returns the opaque challenge token. amr lists the methods of
+// the first factor.
+func (o *OAuthManager) CreateMFAChallenge(userID uuid.UUID, clientID, redirectURI, scope, state string, amr []string) (string, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	challenge := &models.MFAChallenge{
+		TokenHash:   HashToken(token),
+		UserID:      userID,
+		ClientID:    clientID,
+		RedirectURI: redirectURI,
+		Scope:       scope,
+		State:       state,
+		AMR:         strings.Join(amr, " "),
+		ExpiresAt:   time.Now().Add(mfaChallengeTTL),
+	}
+
+	if err := o.db.Create(challenge).Error; err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// CompleteMFAChallenge verifies the code for a pending challenge. On success
+// the challenge is consumed and returned with the amr to record. Each
+// attempt uses up one of the challenge's tries, and wrong codes count
+// towards the account and IP address throttle like wrong passwords.
+func (o *OAuthManager) CompleteMFAChallenge(token, code, ipAddress string) (*models.MFAChallenge, []string, error) {
+	// Claim the attempt before checking the code, so concurrent requests
+	// cannot exceed the limit
+	var challenge models.MFAChallenge
+	if err := o.db.Raw(`UPDATE mfa_challenges SET attempts = attempts + 1
+		WHERE token_hash = ? AND attempts < ? AND expires_at > ?
+		RETURNING *`,
+		HashToken(token), mfaChallengeAttempts, time.Now()).Scan(&challenge).Error; err != nil {
+		return nil, nil, err
+	}
+	if challenge.ID == uuid.Nil {
+		return nil, nil, ErrInvalidMFAChallenge
+	}
+
+	user, err := o.GetUserByID(challenge.UserID)
+	if err != nil {
+		return nil, nil, ErrInvalidMFAChallenge
+	}
+	if err := o.checkLoginThrottle(user.Email, ipAddress); err != nil {
+		return nil, nil, err
+	}
+
+	amr, err := o.VerifyMFACode(challenge.UserID, code)
+	if err != nil {
+		if err == ErrInvalidMFACode {
+			if err := o.recordLoginFailure(&user.ID, user.Email, ipAddress); err != nil {
+				return nil, nil, err
+			}
+		}
+		return nil, nil, err
+	}
+
+	if err := o.db.Delete(&challenge).Error; err != nil {
+		return nil, nil, err
+	}
+
+	// Failed attempts are only forgotten once both factors succeeded
+	if err := o.clearAccountThrottle(user.Email); err != nil {
+		return nil, nil, err
+	}
+
+	return &challenge, append(strings.Fields(challenge.AMR), amr...), nil
+}
+
+// useTOTPCode validates a TOTP code and records its time step so the same
+// code cannot be used twice
+func (o *OAuthManager) useTOTPCode(credential *models.MFACredential, code string) error {
+	secret, err := o.decryptMFASecret(credential.SecretEncrypted)
+	if err != nil {
+		return err
+	}
+
+	step, ok := ValidateTOTP(secret, code, time.Now())
+	if !ok {
+		return ErrInvalidMFACode
+	}
+
+	result := o.db.Model(credential).Where("last_used_step < ?", step).Update("last_used_step", step)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidMFACode
+	}
+
+	return nil
+}
+
+// replaceRecoveryCodes discards the user's recovery codes and stores hashes
+// of a new set, returning the plaintext codes to show once
+func (o *OAuthManager) replaceRecoveryCodes(userID uuid.UUID) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	records := make([]models.RecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		raw := make([]byte, 10)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+
+		encoded := strings.ToLower(totpEncoding.EncodeToString(raw))
+		codes[i] = encoded[:8] + "-" + encoded[8:]
+		records[i] = models.RecoveryCode{
+			UserID:   userID,
+			CodeHash: HashToken(normalizeRecoveryCode(codes[i])),
+		}
+	}
+
+	err := o.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&records).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// normalizeRecoveryCode ignores case, spaces and dashes in recovery codes
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// encryptMFASecret encrypts a TOTP secret with a key derived from the JWT secret
+func (o *OAuthManager) encryptMFASecret(secret string) (string, error) {
+	gcm, err := newGCM(o.jwt.deriveKey("mfa-secret"))
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(secret), nil)
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// decryptMFASecret reverses encryptMFASecret
+func (o *OAuthManager) decryptMFASecret(encrypted string) (string, error) {
+	sealed, err := base64.RawURLEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", err
+	}
+
+	gcm, err := newGCM(o.jwt.deriveKey("mfa-secret"))
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("invalid encrypted MFA secret")
+	}
+
+	secret, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(secret), nil
+}
+
+// deriveKey derives a purpose-specific 256-bit key from the JWT secret
+func (j *JWTManager) deriveKey(purpose string) []byte {
+	mac := hmac.New(sha256.New, []byte(j.config.Secret))
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
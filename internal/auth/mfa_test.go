@@ -0,0 +1,187 @@
+package auth
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ishare-task-api/internal/config"
+	"ishare-task-api/internal/models"
+)
+
+// newTestMFAManager creates an OAuthManager with the JWT manager that MFA
+// secrets are encrypted with
+func newTestMFAManager(t *testing.T, cfg config.OAuthConfig) *OAuthManager {
+	t.Helper()
+
+	o := newTestOAuthManager(t, cfg)
+	o.jwt = newTestJWTManager(t, ValidationModeDatabase)
+	return o
+}
+
+// testTOTPCode returns the code for the time step offset from the current
+// one
+func testTOTPCode(t *testing.T, secret string, offset int64) string {
+	t.Helper()
+
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("invalid TOTP secret: %v", err)
+	}
+	return totpCode(key, time.Now().Unix()/totpPeriod+offset)
+}
+
+// createTestMFAUser creates an account with a confirmed TOTP credential and
+// returns it with the secret and recovery codes
+func createTestMFAUser(t *testing.T, o *OAuthManager, name string) (*models.User, string, []string) {
+	t.Helper()
+
+	user := createTestUser(t, o, testEmail(name), "local-secret", true)
+	secret, _, err := o.BeginTOTPEnrollment(user)
+	if err != nil {
+		t.Fatalf("BeginTOTPEnrollment: %v", err)
+	}
+	recoveryCodes, err := o.ConfirmTOTPEnrollment(user.ID, testTOTPCode(t, secret, -1))
+	if err != nil {
+		t.Fatalf("ConfirmTOTPEnrollment: %v", err)
+	}
+	return user, secret, recoveryCodes
+}
+
+func newTestMFAChallenge(t *testing.T, o *OAuthManager, user *models.User) string {
+	t.Helper()
+
+	token, err := o.CreateMFAChallenge(user.ID, "test-client", "https://client.example.com/callback",
+		"read", "", []string{AMRPassword})
+	if err != nil {
+		t.Fatalf("CreateMFAChallenge: %v", err)
+	}
+	return token
+}
+
+func accountFailures(t *testing.T, o *OAuthManager, email string) int {
+	t.Helper()
+
+	var throttle models.LoginThrottle
+	if err := o.db.Where("scope = ? AND subject = ?", ThrottleScopeAccount, normalizeEmail(email)).
+		Limit(1).Find(&throttle).Error; err != nil {
+		t.Fatalf("failed to load the throttle: %v", err)
+	}
+	return throttle.Failures
+}
+
+func TestCompleteMFAChallenge(t *testing.T) {
+	o := newTestMFAManager(t, config.OAuthConfig{LoginLockoutDuration: time.Minute})
+	user, secret, _ := createTestMFAUser(t, o, "mfa-complete")
+
+	// A password sign-in keeps earlier failures while the second factor is
+	// pending
+	if err := o.recordLoginFailure(&user.ID, user.Email, ""); err != nil {
+		t.Fatalf("recordLoginFailure: %v", err)
+	}
+	if _, err := o.AuthenticateUser(user.Email, "local-secret", ""); err != nil {
+		t.Fatalf("AuthenticateUser: %v", err)
+	}
+	if failures := accountFailures(t, o, user.Email); failures != 1 {
+		t.Fatalf("%d failures after the password; want 1", failures)
+	}
+
+	token := newTestMFAChallenge(t, o, user)
+	if _, _, err := o.CompleteMFAChallenge(token, "000000", ""); err != ErrInvalidMFACode {
+		t.Fatalf("wrong code error = %v; want ErrInvalidMFACode", err)
+	}
+	if failures := accountFailures(t, o, user.Email); failures != 2 {
+		t.Fatalf("%d failures after a wrong code; want 2", failures)
+	}
+
+	_, amr, err := o.CompleteMFAChallenge(token, testTOTPCode(t, secret, 0), "")
+	if err != nil {
+		t.Fatalf("CompleteMFAChallenge: %v", err)
+	}
+	if strings.Join(amr, " ") != "pwd otp mfa" {
+		t.Errorf("amr = %v", amr)
+	}
+	if failures := accountFailures(t, o, user.Email); failures != 0 {
+		t.Errorf("%d failures after the second factor; want 0", failures)
+	}
+	if _, _, err := o.CompleteMFAChallenge(token, testTOTPCode(t, secret, 1), ""); err != ErrInvalidMFAChallenge {
+		t.Errorf("reused challenge error = %v; want ErrInvalidMFAChallenge", err)
+	}
+}
+
+func TestCompleteMFAChallengeLocksAccount(t *testing.T) {
+	o := newTestMFAManager(t, config.OAuthConfig{
+		LoginMaxAttempts:     2,
+		LoginLockoutDuration: time.Minute,
+	})
+	user, secret, _ := createTestMFAUser(t, o, "mfa-lockout")
+	token := newTestMFAChallenge(t, o, user)
+
+	if _, _, err := o.CompleteMFAChallenge(token, "000000", ""); err != ErrInvalidMFACode {
+		t.Fatalf("first wrong code error = %v; want ErrInvalidMFACode", err)
+	}
+	_, _, err := o.CompleteMFAChallenge(token, "000000", "")
+	if throttled, ok := err.(*LoginThrottledError); !ok || !throttled.Locked {
+		t.Fatalf("second wrong code error = %v; want a lockout", err)
+	}
+	_, _, err = o.CompleteMFAChallenge(token, testTOTPCode(t, secret, 0), "")
+	if throttled, ok := err.(*LoginThrottledError); !ok || !throttled.Locked {
+		t.Fatalf("valid code while locked error = %v; want a lockout", err)
+	}
+}
+
+func TestCompleteMFAChallengeAttemptLimit(t *testing.T) {
+	o := newTestMFAManager(t, config.OAuthConfig{LoginLockoutDuration: time.Minute})
+	user, secret, _ := createTestMFAUser(t, o, "mfa-attempts")
+	token := newTestMFAChallenge(t, o, user)
+
+	// Concurrent attempts cannot use more than the challenge's tries
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*mfaChallengeAttempts)
+	for i := 0; i < 2*mfaChallengeAttempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := o.CompleteMFAChallenge(token, "000000", "")
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	wrongCodes := 0
+	for err := range errs {
+		switch err {
+		case ErrInvalidMFACode:
+			wrongCodes++
+		case ErrInvalidMFAChallenge:
+		default:
+			t.Fatalf("CompleteMFAChallenge error = %v", err)
+		}
+	}
+	if wrongCodes != mfaChallengeAttempts {
+		t.Errorf("%d codes checked; want %d", wrongCodes, mfaChallengeAttempts)
+	}
+
+	if _, _, err := o.CompleteMFAChallenge(token, testTOTPCode(t, secret, 0), ""); err != ErrInvalidMFAChallenge {
+		t.Errorf("valid code after the limit error = %v; want ErrInvalidMFAChallenge", err)
+	}
+}
+
+func TestCompleteMFAChallengeRecoveryCode(t *testing.T) {
+	o := newTestMFAManager(t, config.OAuthConfig{LoginLockoutDuration: time.Minute})
+	user, _, recoveryCodes := createTestMFAUser(t, o, "mfa-recovery")
+
+	_, amr, err := o.CompleteMFAChallenge(newTestMFAChallenge(t, o, user), recoveryCodes[0], "")
+	if err != nil {
+		t.Fatalf("CompleteMFAChallenge: %v", err)
+	}
+	if strings.Join(amr, " ") != "pwd rcc mfa" {
+		t.Errorf("amr = %v; want a recovery code method", amr)
+	}
+
+	if _, _, err := o.CompleteMFAChallenge(newTestMFAChallenge(t, o, user), recoveryCodes[0], ""); err != ErrInvalidMFACode {
+		t.Errorf("reused recovery code error = %v; want ErrInvalidMFACode", err)
+	}
+}
@@ -13,19 +13,30 @@ import (
 
 // AuthMiddleware provides authentication middleware
 type AuthMiddleware struct {
-	jwt *JWTManager
-	db  *gorm.DB
+	jwt         *JWTManager
+	db          *gorm.DB
+	revocations *RevocationCache
+	users       *UserCache
+	rbac        *RBAC
+	lastUsed    *lastUsedTracker
+	patLastUsed *lastUsedTracker
 }
 
 // NewAuthMiddleware creates a new authentication middleware
-func NewAuthMiddleware(jwt *JWTManager, db *gorm.DB) *AuthMiddleware {
+func NewAuthMiddleware(jwt *JWTManager, db *gorm.DB, revocations *RevocationCache, users *UserCache, rbac *RBAC) *AuthMiddleware {
 	return &AuthMiddleware{
-		jwt: jwt,
-		db:  db,
+		jwt:         jwt,
+		db:          db,
+		revocations: revocations,
+		users:       users,
+		rbac:        rbac,
+		lastUsed:    newLastUsedTracker(db),
+		patLastUsed: newLastUsedTrackerFor(db, &models.PersonalAccessToken{}, "id"),
 	}
 }
 
-// Authenticate middleware validates JWS tokens and sets user context
+// Authenticate middleware validates JWS tokens or personal access tokens and
+// sets user context
 func (a *AuthMiddleware) Authenticate() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get Authorization header
@@ -50,6 +61,11 @@ func (a *AuthMiddleware) Authenticate() gin.HandlerFunc {
 
 		tokenString := parts[1]
 
+		if IsPersonalAccessToken(tokenString) {
+			a.authenticatePersonalAccessToken(c, tokenString)
+			return
+		}
+
 		// Validate JWS token
 		claims, err := a.jwt.ValidateJWS(tokenString)
 		if err != nil {
@@ -60,9 +76,49 @@ func (a *AuthMiddleware) Authenticate() gin.HandlerFunc {
 			return
 		}
 
+		tokenID := TokenID(claims, tokenString)
+
+		if a.jwt.config.ValidationMode == ValidationModeStateless {
+			// Trust the signature and claims, only consult the revocation list
+			if a.revocations.IsRevoked(tokenID) {
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"error": "Token has been revoked",
+				})
+				c.Abort()
+				return
+			}
+
+			user, err := a.users.Get(claims.UserID)
+			if err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"error": "User not found",
+				})
+				c.Abort()
+				return
+			}
+
+			if user.Disabled() {
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"error": "Account is disabled",
+				})
+				c.Abort()
+				return
+			}
+
+			a.lastUsed.touch(tokenID, nil)
+
+			c.Set("user", user)
+			c.Set("claims", claims)
+			c.Set("token_id", tokenID)
+
+			c.Next()
+			return
+		}
+
 		// Verify token exists in database
 		var accessToken models.AccessToken
-		if err := a.db.Where("token = ? AND expires_at > NOW()", tokenString).First(&accessToken).Error; err != nil {
+		if err := a.db.Where("jti = ? AND token_hash = ? AND expires_at > NOW() AND revoked_at IS NULL",
+			tokenID, HashToken(tokenString)).First(&accessToken).Error; err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error": "Token not found or expired",
 			})
@@ -80,15 +136,84 @@ func (a *AuthMiddleware) Authenticate() gin.HandlerFunc {
 			return
 		}
 
+		if user.Disabled() {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Account is disabled",
+			})
+			c.Abort()
+			return
+		}
+
+		a.lastUsed.touch(tokenID, nil)
+
 		// Set user and claims in context
 		c.Set("user", &user)
 		c.Set("claims", claims)
 		c.Set("access_token", &accessToken)
+		c.Set("token_id", tokenID)
 
 		c.Next()
 	}
 }
 
+// authenticatePersonalAccessToken authenticates a request made with a
+// personal access token. Tokens are always checked against the database, so
+// revocation is immediate in every validation mode.
+func (a *AuthMiddleware) authenticatePersonalAccessToken(c *gin.Context, tokenString string) {
+	var pat models.PersonalAccessToken
+	if err := a.db.Where("token_hash = ? AND expires_at > NOW() AND revoked_at IS NULL",
+		HashToken(tokenString)).First(&pat).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Invalid, expired or revoked personal access token",
+		})
+		c.Abort()
+		return
+	}
+
+	// Like access tokens, only stateless validation trusts the user cache
+	var user *models.User
+	var err error
+	if a.jwt.config.ValidationMode == ValidationModeStateless {
+		user, err = a.users.Get(pat.UserID)
+	} else {
+		user = &models.User{}
+		err = a.db.Where("id = ?", pat.UserID).First(user).Error
+	}
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not found",
+		})
+		c.Abort()
+		return
+	}
+
+	if user.Disabled() {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Account is disabled",
+		})
+		c.Abort()
+		return
+	}
+
+	a.patLastUsed.touch(pat.ID.String(), map[string]interface{}{"last_used_ip": c.ClientIP()})
+
+	claims := &Claims{
+		UserID: user.ID,
+		Email:  user.Email,
+		Scope:  pat.Scope,
+	}
+	if pat.OrgID != nil {
+		claims.OrgID = *pat.OrgID
+	}
+
+	c.Set("user", user)
+	c.Set("claims", claims)
+	c.Set("personal_access_token", &pat)
+	c.Set("token_id", pat.ID.String())
+
+	c.Next()
+}
+
 // RequireScope middleware checks if the user has the required scope
 func (a *AuthMiddleware) RequireScope(requiredScope string) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -122,6 +247,86 @@ func (a *AuthMiddleware) RequireScope(requiredScope string) gin.HandlerFunc {
 	}
 }
 
+// RequirePermission middleware checks that the user's roles grant the
+// permission. Organization-scoped permissions, such as those on tasks, are
+// granted by the user's role in the organization active in the token
+// instead. Roles are resolved from the database, not from the token, so
+// role changes apply without waiting for new tokens. Personal access tokens
+// must also carry the permission as a scope.
+func (a *AuthMiddleware) RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := GetUserFromContext(c)
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Authentication required",
+			})
+			c.Abort()
+			return
+		}
+
+		var allowed bool
+		var err error
+		if models.OrgScopedPermissions[permission] {
+			orgID, ok := GetOrganizationIDFromContext(c)
+			if !ok {
+				c.JSON(http.StatusForbidden, gin.H{
+					"error": "No active organization",
+				})
+				c.Abort()
+				return
+			}
+			allowed, err = a.rbac.HasOrgPermission(user.ID, orgID, permission)
+		} else {
+			allowed, err = a.rbac.HasPermission(user.ID, permission)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to check permissions",
+			})
+			c.Abort()
+			return
+		}
+
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":               "Insufficient permissions",
+				"required_permission": permission,
+			})
+			c.Abort()
+			return
+		}
+
+		if pat, ok := GetPersonalAccessTokenFromContext(c); ok && !containsString(pat.Scopes(), permission) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":          "Personal access token lacks the required scope",
+				"required_scope": permission,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RejectPersonalAccessTokens middleware refuses requests made with a
+// personal access token, so that a leaked token cannot take over the
+// account: sessions, MFA, profile, tokens and organization switching need
+// an interactive sign-in
+func (a *AuthMiddleware) RejectPersonalAccessTokens() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := GetPersonalAccessTokenFromContext(c); ok {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Personal access tokens cannot manage the account",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
 // GetUserFromContext gets the user from the Gin context
 func GetUserFromContext(c *gin.Context) (*models.User, bool) {
 	userInterface, exists := c.Get("user")
@@ -144,6 +349,16 @@ func GetClaimsFromContext(c *gin.Context) (*Claims, bool) {
 	return claims, ok
 }
 
+// GetOrganizationIDFromContext gets the organization the request acts in,
+// as carried by the token
+func GetOrganizationIDFromContext(c *gin.Context) (uuid.UUID, bool) {
+	claims, exists := GetClaimsFromContext(c)
+	if !exists || claims.OrgID == uuid.Nil {
+		return uuid.Nil, false
+	}
+	return claims.OrgID, true
+}
+
 // GetAccessTokenFromContext gets the access token from the Gin context
 func GetAccessTokenFromContext(c *gin.Context) (*models.AccessToken, bool) {
 	tokenInterface, exists := c.Get("access_token")
@@ -155,52 +370,25 @@ func GetAccessTokenFromContext(c *gin.Context) (*models.AccessToken, bool) {
 	return token, ok
 }
 
-// ValidateUserOwnership middleware ensures the user owns the resource
-func (a *AuthMiddleware) ValidateUserOwnership() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		_, exists := GetUserFromContext(c)
-		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Authentication required",
-			})
-			c.Abort()
-			return
-		}
-
-		// Get resource ID from URL parameter
-		resourceID := c.Param("id")
-		if resourceID == "" {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Resource ID required",
-			})
-			c.Abort()
-			return
-		}
-
-		// Parse UUID
-		resourceUUID, err := uuid.Parse(resourceID)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Invalid resource ID format",
-			})
-			c.Abort()
-			return
-		}
-
-		// Check if user owns the resource (for tasks)
-		var task models.Task
-		if err := a.db.Where("id = ?", resourceUUID).First(&task).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error": "Resource not found",
-			})
-			c.Abort()
-			return
-		}
+// GetPersonalAccessTokenFromContext gets the personal access token the
+// request was authenticated with, if any
+func GetPersonalAccessTokenFromContext(c *gin.Context) (*models.PersonalAccessToken, bool) {
+	tokenInterface, exists := c.Get("personal_access_token")
+	if !exists {
+		return nil, false
+	}
 
-		// For now, we'll allow all authenticated users to access all tasks
-		// In a real application, you might want to add user_id to tasks table
-		// and check ownership here
+	token, ok := tokenInterface.(*models.PersonalAccessToken)
+	return token, ok
+}
 
-		c.Next()
+// GetTokenIDFromContext gets the jti of the current access token from the Gin context
+func GetTokenIDFromContext(c *gin.Context) (string, bool) {
+	tokenID, exists := c.Get("token_id")
+	if !exists {
+		return "", false
 	}
+
+	id, ok := tokenID.(string)
+	return id, ok
 }
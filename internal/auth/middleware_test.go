@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ishare-task-api/internal/config"
+	"ishare-task-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// newTestRouter returns a router with /me behind the middleware the way
+// routes.Setup wires it, plus a task route any token may use
+func newTestRouter(t *testing.T, o *OAuthManager, validationMode string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	a := NewAuthMiddleware(newTestJWTManager(t, validationMode), o.db, NewRevocationCache(o.db, time.Minute),
+		NewUserCache(o.db, time.Hour), NewRBAC(o.db, time.Minute))
+
+	ok := func(c *gin.Context) { c.Status(http.StatusNoContent) }
+	router := gin.New()
+	router.GET("/tasks", a.Authenticate(), ok)
+	me := router.Group("/me")
+	me.Use(a.Authenticate(), a.RejectPersonalAccessTokens())
+	{
+		me.POST("/mfa/totp", ok)
+		me.DELETE("/sessions", ok)
+	}
+	return router
+}
+
+func serveWithToken(router *gin.Engine, method, path, token string) int {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func createTestPAT(t *testing.T, o *OAuthManager, user *models.User) string {
+	t.Helper()
+
+	token, _, err := o.CreatePersonalAccessToken(user, uuid.Nil, "test",
+		[]string{models.PermissionTasksRead}, time.Hour, "")
+	if err != nil {
+		t.Fatalf("CreatePersonalAccessToken: %v", err)
+	}
+	return token
+}
+
+func TestPersonalAccessTokensRejectedOnMe(t *testing.T) {
+	o := newTestOAuthManager(t, config.OAuthConfig{PATMaxLifetime: time.Hour})
+	router := newTestRouter(t, o, ValidationModeDatabase)
+	user := createTestUser(t, o, testEmail("pat-me"), "local-secret", true)
+	token := createTestPAT(t, o, user)
+
+	if code := serveWithToken(router, http.MethodGet, "/tasks", token); code != http.StatusNoContent {
+		t.Fatalf("GET /tasks status = %d; want %d", code, http.StatusNoContent)
+	}
+	for _, route := range []struct{ method, path string }{
+		{http.MethodPost, "/me/mfa/totp"},
+		{http.MethodDelete, "/me/sessions"},
+	} {
+		if code := serveWithToken(router, route.method, route.path, token); code != http.StatusForbidden {
+			t.Errorf("%s %s status = %d; want %d", route.method, route.path, code, http.StatusForbidden)
+		}
+	}
+}
+
+func TestPersonalAccessTokenUserNotCached(t *testing.T) {
+	o := newTestOAuthManager(t, config.OAuthConfig{PATMaxLifetime: time.Hour})
+	router := newTestRouter(t, o, ValidationModeDatabase)
+	user := createTestUser(t, o, testEmail("pat-disabled"), "local-secret", true)
+	token := createTestPAT(t, o, user)
+
+	if code := serveWithToken(router, http.MethodGet, "/tasks", token); code != http.StatusNoContent {
+		t.Fatalf("GET /tasks status = %d; want %d", code, http.StatusNoContent)
+	}
+
+	// Disabling the account takes effect at once outside stateless mode
+	if err := o.db.Model(user).Update("disabled_at", time.Now()).Error; err != nil {
+		t.Fatalf("failed to disable the user: %v", err)
+	}
+	if code := serveWithToken(router, http.MethodGet, "/tasks", token); code != http.StatusUnauthorized {
+		t.Errorf("GET /tasks status for a disabled account = %d; want %d", code, http.StatusUnauthorized)
+	}
+}
@@ -3,31 +3,54 @@ package auth
 import (
 	"crypto/rand"
 	"encoding/base64"
+	"errors"
 	"fmt"
+	"log"
+	"strings"
 	"time"
 
 	"ishare-task-api/internal/config"
 	"ishare-task-api/internal/models"
+	"ishare-task-api/internal/password"
 
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
 // OAuthManager handles OAuth 2.0 operations
 type OAuthManager struct {
-	config config.OAuthConfig
-	db     *gorm.DB
-	jwt    *JWTManager
+	config      config.OAuthConfig
+	db          *gorm.DB
+	jwt         *JWTManager
+	revocations *RevocationCache
+	policy      *password.Policy
+	hasher      password.Hasher
+	dummyHash   string
+
+	authenticators []Authenticator
 }
 
 // NewOAuthManager creates a new OAuth manager
-func NewOAuthManager(cfg config.OAuthConfig, db *gorm.DB, jwt *JWTManager) *OAuthManager {
-	return &OAuthManager{
-		config: cfg,
-		db:     db,
-		jwt:    jwt,
+func NewOAuthManager(cfg config.OAuthConfig, db *gorm.DB, jwt *JWTManager, revocations *RevocationCache, policy *password.Policy, hasher password.Hasher) *OAuthManager {
+	// Verified against for unknown emails so that the response time does
+	// not reveal whether an account exists
+	dummyHash, err := hasher.Hash("dummy-password")
+	if err != nil {
+		log.Printf("Failed to create dummy password hash: %v", err)
+	}
+
+	o := &OAuthManager{
+		config:      cfg,
+		db:          db,
+		jwt:         jwt,
+		revocations: revocations,
+		policy:      policy,
+		hasher:      hasher,
+		dummyHash:   dummyHash,
 	}
+	o.authenticators = []Authenticator{NewLocalAuthenticator(o)}
+
+	return o
 }
 
 // AuthorizationRequest represents an OAuth authorization request
@@ -37,6 +60,8 @@ type AuthorizationRequest struct {
 	RedirectURI  string `form:"redirect_uri" binding:"required"`
 	Scope        string `form:"scope"`
 	State        string `form:"state"`
+	Prompt       string `form:"prompt"`
+	MaxAge       string `form:"max_age"`
 }
 
 // TokenRequest represents an OAuth token request
@@ -48,6 +73,14 @@ type TokenRequest struct {
 	ClientSecret string `form:"client_secret"`
 }
 
+// RevocationRequest represents an OAuth token revocation request (RFC 7009)
+type RevocationRequest struct {
+	Token         string `form:"token" binding:"required"`
+	TokenTypeHint string `form:"token_type_hint"`
+	ClientID      string `form:"client_id" binding:"required"`
+	ClientSecret  string `form:"client_secret" binding:"required"`
+}
+
 // TokenResponse represents an OAuth token response
 type TokenResponse struct {
 	AccessToken  string `json:"access_token"`
@@ -57,8 +90,15 @@ type TokenResponse struct {
 	Scope        string `json:"scope"`
 }
 
+// SessionInfo describes how and from where a user signed in
+type SessionInfo struct {
+	IPAddress string
+	UserAgent string
+	AMR       []string // authentication methods used, e.g. pwd, otp, mfa
+}
+
 // CreateAuthorizationCode creates a new authorization code for OAuth flow
-func (o *OAuthManager) CreateAuthorizationCode(userID uuid.UUID, clientID, scope string) (*models.AuthorizationCode, error) {
+func (o *OAuthManager) CreateAuthorizationCode(userID uuid.UUID, clientID, scope string, session SessionInfo) (*models.AuthorizationCode, error) {
 	// Generate random authorization code
 	codeBytes := make([]byte, 32)
 	if _, err := rand.Read(codeBytes); err != nil {
@@ -72,6 +112,9 @@ func (o *OAuthManager) CreateAuthorizationCode(userID uuid.UUID, clientID, scope
 		UserID:    userID,
 		ClientID:  clientID,
 		Scope:     scope,
+		AMR:       strings.Join(session.AMR, " "),
+		IPAddress: session.IPAddress,
+		UserAgent: truncate(session.UserAgent, 512),
 		ExpiresAt: time.Now().Add(10 * time.Minute), // Authorization codes expire in 10 minutes
 	}
 
@@ -102,79 +145,245 @@ func (o *OAuthManager) ValidateAuthorizationCode(code, clientID, clientSecret st
 	return &authCode, nil
 }
 
-// CreateAccessToken creates a new access token
-func (o *OAuthManager) CreateAccessToken(userID uuid.UUID, clientID, scope string) (*models.AccessToken, error) {
-	// Generate JWS token
+// CreateAccessToken creates a new access token and returns the bearer token
+// alongside its stored record. The token acts in the given organization;
+// uuid.Nil selects the organization the user joined first.
+func (o *OAuthManager) CreateAccessToken(userID, orgID uuid.UUID, clientID, scope string, session SessionInfo) (string, *models.AccessToken, error) {
+	if orgID == uuid.Nil {
+		defaultOrgID, err := o.defaultOrganization(userID)
+		if err != nil {
+			return "", nil, err
+		}
+		orgID = defaultOrgID
+	}
+
+	// Generate JWS token carrying the user's current roles
 	user := &models.User{ID: userID}
-	tokenString, err := o.jwt.GenerateJWS(user, scope)
+	if err := o.db.Model(user).Association("Roles").Find(&user.Roles); err != nil {
+		return "", nil, err
+	}
+	jti := uuid.New().String()
+	tokenString, err := o.jwt.GenerateJWS(user, orgID, scope, jti, session.AMR)
 	if err != nil {
-		return nil, err
+		return "", nil, err
+	}
+
+	// Wrap the token in a JWE for clients that require encrypted tokens
+	if o.jwt.EncryptsFor(clientID) {
+		if tokenString, err = o.jwt.EncryptJWS(tokenString); err != nil {
+			return "", nil, err
+		}
 	}
 
 	// Create access token record
 	accessToken := &models.AccessToken{
-		Token:     tokenString,
+		JTI:       jti,
+		TokenHash: HashToken(tokenString),
 		UserID:    userID,
 		ClientID:  clientID,
 		Scope:     scope,
+		IPAddress: session.IPAddress,
+		UserAgent: truncate(session.UserAgent, 512),
 		ExpiresAt: time.Now().Add(24 * time.Hour), // Access tokens expire in 24 hours
 	}
+	if orgID != uuid.Nil {
+		accessToken.OrgID = &orgID
+	}
 
 	if err := o.db.Create(accessToken).Error; err != nil {
-		return nil, err
+		return "", nil, err
 	}
 
-	return accessToken, nil
+	return tokenString, accessToken, nil
 }
 
 // ValidateAccessToken validates an access token
 func (o *OAuthManager) ValidateAccessToken(tokenString string) (*models.AccessToken, error) {
+	claims, err := o.jwt.ValidateJWS(tokenString)
+	if err != nil {
+		return nil, fmt.Errorf("invalid or expired access token")
+	}
+
 	var accessToken models.AccessToken
 	
-	if err := o.db.Where("token = ? AND expires_at > ?", 
-		tokenString, time.Now()).First(&accessToken).Error; err != nil {
+	if err := o.db.Where("jti = ? AND token_hash = ? AND expires_at > ? AND revoked_at IS NULL", 
+		TokenID(claims, tokenString), HashToken(tokenString), time.Now()).First(&accessToken).Error; err != nil {
 		return nil, fmt.Errorf("invalid or expired access token")
 	}
 
 	return &accessToken, nil
 }
 
-// AuthenticateUser authenticates a user with email and password
-func (o *OAuthManager) AuthenticateUser(email, password string) (*models.User, error) {
-	var user models.User
-	
-	if err := o.db.Where("email = ?", email).First(&user).Error; err != nil {
-		return nil, fmt.Errorf("invalid credentials")
+// RevokeAccessToken marks an access token as revoked and pushes it into the
+// revocation cache so stateless validation rejects it immediately
+func (o *OAuthManager) RevokeAccessToken(tokenString string) error {
+	var accessToken models.AccessToken
+	if err := o.db.Where("token_hash = ? AND revoked_at IS NULL", HashToken(tokenString)).First(&accessToken).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			// Unknown or already revoked tokens are not an error (RFC 7009)
+			return nil
+		}
+		return err
 	}
 
-	// Compare password hash
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
-		return nil, fmt.Errorf("invalid credentials")
+	now := time.Now()
+	if err := o.db.Model(&accessToken).Update("revoked_at", now).Error; err != nil {
+		return err
 	}
 
-	return &user, nil
+	o.revocations.Revoke(accessToken.JTI, accessToken.ExpiresAt)
+	return nil
+}
+
+// ErrInvalidCredentials is returned when the email or password is wrong
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// AuthenticateUser authenticates a user with email and password through
+// the configured authenticators. Failed attempts are throttled per account
+// and per client IP address.
+func (o *OAuthManager) AuthenticateUser(email, password, ipAddress string) (*models.User, error) {
+	if err := o.checkLoginThrottle(email, ipAddress); err != nil {
+		if throttled, ok := err.(*LoginThrottledError); ok {
+			o.RecordAuditEvent(models.AuditEvent{
+				Event:     AuditLoginThrottled,
+				Email:     email,
+				IPAddress: ipAddress,
+				Details:   throttled.Error(),
+			})
+		}
+		return nil, err
+	}
+
+	user, authenticator, err := o.authenticate(email, password)
+	if err != nil {
+		// Every failed attempt counts, including those a backend could not
+		// answer, so an outage does not lift the throttle
+		if err := o.recordLoginFailure(o.userIDForEmail(email), email, ipAddress); err != nil {
+			return nil, err
+		}
+		return nil, err
+	}
+
+	// Disabled accounts are rejected even with the correct password
+	if user.Disabled() {
+		o.RecordAuditEvent(models.AuditEvent{
+			Event:     AuditLoginFailed,
+			UserID:    &user.ID,
+			Email:     user.Email,
+			IPAddress: ipAddress,
+			Details:   ErrUserDisabled.Error(),
+		})
+		return nil, ErrUserDisabled
+	}
+
+	// With a second factor pending, failed attempts are kept until the MFA
+	// challenge succeeds so that wrong codes add to them
+	mfaEnabled, err := o.MFAEnabled(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !mfaEnabled {
+		if err := o.clearAccountThrottle(email); err != nil {
+			return nil, err
+		}
+	}
+
+	// Apply the email verification policy
+	if err := o.checkEmailVerified(user); err != nil {
+		return nil, err
+	}
+
+	// Signing in during the grace period restores a deleted account
+	if user.DeletionScheduledAt != nil {
+		if err := o.cancelAccountDeletion(user, ipAddress); err != nil {
+			return nil, err
+		}
+	}
+
+	o.RecordAuditEvent(models.AuditEvent{
+		Event:     AuditLoginSucceeded,
+		UserID:    &user.ID,
+		Email:     user.Email,
+		IPAddress: ipAddress,
+		Details:   "authenticator: " + authenticator,
+	})
+
+	return user, nil
+}
+
+// userIDForEmail returns the ID of the account with the email, if any, to
+// attribute a failed sign-in
+func (o *OAuthManager) userIDForEmail(email string) *uuid.UUID {
+	var user models.User
+	if err := o.db.Select("id").Where("email = ?", email).First(&user).Error; err != nil {
+		return nil
+	}
+	return &user.ID
 }
 
-// CreateUser creates a new user with hashed password
-func (o *OAuthManager) CreateUser(email, password string) (*models.User, error) {
+// CreateUser creates a new user with hashed password. The password must
+// satisfy the password policy.
+func (o *OAuthManager) CreateUser(email, newPassword string) (*models.User, error) {
+	if err := o.policy.Validate(newPassword, email); err != nil {
+		return nil, err
+	}
+
 	// Hash password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hashedPassword, err := o.hasher.Hash(newPassword)
 	if err != nil {
 		return nil, err
 	}
 
 	user := &models.User{
 		Email:        email,
-		PasswordHash: string(hashedPassword),
+		PasswordHash: hashedPassword,
 	}
 
-	if err := o.db.Create(user).Error; err != nil {
+	err = o.db.Transaction(func(tx *gorm.DB) error {
+		return o.createUserWithDefaultRole(tx, user)
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return user, nil
 }
 
+// createUserWithDefaultRole inserts a user holding the member role, which
+// every new user starts with, and adds it to the default organization
+func (o *OAuthManager) createUserWithDefaultRole(tx *gorm.DB, user *models.User) error {
+	var member models.Role
+	if err := tx.Where("name = ?", models.RoleMember).First(&member).Error; err != nil {
+		return err
+	}
+	user.Roles = []models.Role{member}
+	if err := tx.Omit("Roles.*").Create(user).Error; err != nil {
+		return err
+	}
+	return o.joinDefaultOrganization(tx, user)
+}
+
+// rehashPassword stores a new hash of the verified password. A failure is
+// logged only, the old hash keeps working.
+func (o *OAuthManager) rehashPassword(user *models.User, password string) {
+	hashedPassword, err := o.hasher.Hash(password)
+	if err != nil {
+		log.Printf("Failed to rehash password for user %s: %v", user.ID, err)
+		return
+	}
+
+	// Only replace the hash that was verified, not a concurrently changed one
+	result := o.db.Model(&models.User{}).
+		Where("id = ? AND password_hash = ?", user.ID, user.PasswordHash).
+		Update("password_hash", hashedPassword)
+	if result.Error != nil {
+		log.Printf("Failed to rehash password for user %s: %v", user.ID, result.Error)
+		return
+	}
+
+	user.PasswordHash = hashedPassword
+}
+
 // CleanupExpiredTokens removes expired tokens from the database
 func (o *OAuthManager) CleanupExpiredTokens() error {
 	// Delete expired authorization codes
@@ -187,6 +396,43 @@ func (o *OAuthManager) CleanupExpiredTokens() error {
 		return err
 	}
 
+	// Delete expired SSO sessions
+	if err := o.db.Where("expires_at < ?", time.Now()).Delete(&models.SSOSession{}).Error; err != nil {
+		return err
+	}
+
+	// Delete expired MFA challenges
+	if err := o.db.Where("expires_at < ?", time.Now()).Delete(&models.MFAChallenge{}).Error; err != nil {
+		return err
+	}
+
+	// Delete login throttles whose failures have been forgotten
+	if err := o.db.Where("last_failure_at < ? AND (locked_until IS NULL OR locked_until < ?)",
+		time.Now().Add(-o.config.LoginLockoutDuration), time.Now()).
+		Delete(&models.LoginThrottle{}).Error; err != nil {
+		return err
+	}
+
+	// Delete abandoned upstream sign-ins
+	if err := o.db.Where("expires_at < ?", time.Now()).Delete(&models.FederatedLoginState{}).Error; err != nil {
+		return err
+	}
+
+	// Delete expired personal access tokens
+	if err := o.db.Where("expires_at < ?", time.Now()).Delete(&models.PersonalAccessToken{}).Error; err != nil {
+		return err
+	}
+
+	// Delete expired password reset tokens
+	if err := o.db.Where("expires_at < ?", time.Now()).Delete(&models.PasswordResetToken{}).Error; err != nil {
+		return err
+	}
+
+	// Purge accounts whose deletion grace period has passed
+	if _, err := o.PurgeDeletedAccounts(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -194,9 +440,18 @@ func (o *OAuthManager) CleanupExpiredTokens() error {
 func (o *OAuthManager) GetUserByID(userID uuid.UUID) (*models.User, error) {
 	var user models.User
 	
-	if err := o.db.Where("id = ?", userID).First(&user).Error; err != nil {
+	if err := o.db.Preload("Roles").Where("id = ?", userID).First(&user).Error; err != nil {
 		return nil, err
 	}
 
 	return &user, nil
-} 
\ No newline at end of file
+}
+
+// truncate limits a string to the given number of bytes without splitting
+// a UTF-8 sequence
+func truncate(s string, max int) string {
+	if len(s) > max {
+		return strings.ToValidUTF8(s[:max], "")
+	}
+	return s
+}
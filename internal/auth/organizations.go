@@ -0,0 +1,383 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"ishare-task-api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Organization audit event names
+const (
+	AuditOrganizationCreated       = "org.created"
+	AuditOrganizationMemberAdded   = "org.member_added"
+	AuditOrganizationMemberUpdated = "org.member_updated"
+	AuditOrganizationMemberRemoved = "org.member_removed"
+	AuditOrganizationSwitched      = "org.switched"
+)
+
+// Organization errors
+var (
+	ErrOrganizationNotFound = errors.New("organization not found")
+	ErrOrganizationExists   = errors.New("organization already exists")
+	ErrInvalidSlug          = errors.New("slug must consist of lowercase letters, digits and hyphens")
+	ErrNotMember            = errors.New("not a member of the organization")
+	ErrAlreadyMember        = errors.New("already a member of the organization")
+	ErrLastOrgAdmin         = errors.New("cannot remove the last admin of the organization")
+)
+
+// slugPattern matches organization slugs such as "acme-eu"
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// orgMembership keys cached organization permissions
+type orgMembership struct {
+	orgID  uuid.UUID
+	userID uuid.UUID
+}
+
+// OrgPermissions returns the permissions the user's role in the
+// organization grants. Users outside the organization have none.
+func (r *RBAC) OrgPermissions(userID, orgID uuid.UUID) (map[string]bool, error) {
+	now := time.Now()
+	key := orgMembership{orgID: orgID, userID: userID}
+
+	r.mu.RLock()
+	entry, ok := r.orgEntries[key]
+	r.mu.RUnlock()
+
+	if ok && now.Before(entry.expiresAt) {
+		return entry.permissions, nil
+	}
+
+	var names []string
+	if err := r.db.Table("permissions").
+		Distinct("permissions.name").
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Joins("JOIN organization_members ON organization_members.role_id = role_permissions.role_id").
+		Where("organization_members.org_id = ? AND organization_members.user_id = ?", orgID, userID).
+		Pluck("permissions.name", &names).Error; err != nil {
+		return nil, err
+	}
+
+	permissions := make(map[string]bool, len(names))
+	for _, name := range names {
+		permissions[name] = true
+	}
+
+	if r.ttl > 0 {
+		r.mu.Lock()
+		r.orgEntries[key] = permissionEntry{permissions: permissions, expiresAt: now.Add(r.ttl)}
+		r.mu.Unlock()
+	}
+
+	return permissions, nil
+}
+
+// HasOrgPermission reports whether the user's role in the organization
+// grants the permission
+func (r *RBAC) HasOrgPermission(userID, orgID uuid.UUID, permission string) (bool, error) {
+	permissions, err := r.OrgPermissions(userID, orgID)
+	if err != nil {
+		return false, err
+	}
+	return permissions[permission], nil
+}
+
+// ListOrganizations returns all organizations
+func (r *RBAC) ListOrganizations() ([]models.Organization, error) {
+	var orgs []models.Organization
+	if err := r.db.Order("name").Find(&orgs).Error; err != nil {
+		return nil, err
+	}
+	return orgs, nil
+}
+
+// GetOrganization returns an organization by ID
+func (r *RBAC) GetOrganization(orgID uuid.UUID) (*models.Organization, error) {
+	var org models.Organization
+	if err := r.db.Where("id = ?", orgID).First(&org).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrOrganizationNotFound
+		}
+		return nil, err
+	}
+	return &org, nil
+}
+
+// CreateOrganization creates an organization with a unique slug. When
+// adminEmail is set, the user with that email becomes its first admin and
+// is returned as well.
+func (r *RBAC) CreateOrganization(name, slug, adminEmail string) (*models.Organization, *models.User, error) {
+	slug = strings.ToLower(strings.TrimSpace(slug))
+	if !slugPattern.MatchString(slug) {
+		return nil, nil, ErrInvalidSlug
+	}
+
+	org := &models.Organization{
+		Name: strings.TrimSpace(name),
+		Slug: slug,
+	}
+	var admin *models.User
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.Organization{}).Where("slug = ?", slug).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrOrganizationExists
+		}
+
+		if err := tx.Create(org).Error; err != nil {
+			return err
+		}
+
+		if adminEmail == "" {
+			return nil
+		}
+
+		var role models.Role
+		if err := tx.Where("name = ?", models.RoleAdmin).First(&role).Error; err != nil {
+			return err
+		}
+
+		var user models.User
+		if err := tx.Where("LOWER(email) = LOWER(?)", strings.TrimSpace(adminEmail)).First(&user).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrUserNotFound
+			}
+			return err
+		}
+
+		if err := tx.Create(&models.OrganizationMember{OrgID: org.ID, UserID: user.ID, RoleID: role.ID}).Error; err != nil {
+			return err
+		}
+		admin = &user
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if admin != nil {
+		r.Invalidate(admin.ID)
+	}
+	return org, admin, nil
+}
+
+// UserOrganizations returns the memberships of a user with their
+// organizations and roles, oldest first
+func (r *RBAC) UserOrganizations(userID uuid.UUID) ([]models.OrganizationMember, error) {
+	var memberships []models.OrganizationMember
+	if err := r.db.Preload("Organization").Preload("Role").
+		Where("user_id = ?", userID).
+		Order("created_at, org_id").
+		Find(&memberships).Error; err != nil {
+		return nil, err
+	}
+	return memberships, nil
+}
+
+// OrganizationMembers returns the members of an organization with their
+// users and roles
+func (r *RBAC) OrganizationMembers(orgID uuid.UUID) ([]models.OrganizationMember, error) {
+	var members []models.OrganizationMember
+	if err := r.db.Preload("User").Preload("Role").
+		Joins("JOIN users ON users.id = organization_members.user_id").
+		Where("organization_members.org_id = ?", orgID).
+		Order("users.email").
+		Find(&members).Error; err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// AddOrganizationMember adds the user with the email to an organization
+// with the named role and returns the user
+func (r *RBAC) AddOrganizationMember(orgID uuid.UUID, email, roleName string) (*models.User, error) {
+	if _, err := r.GetOrganization(orgID); err != nil {
+		return nil, err
+	}
+	role, err := r.GetRole(roleName)
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	if err := r.db.Where("LOWER(email) = LOWER(?)", strings.TrimSpace(email)).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	result := r.db.Exec("INSERT INTO organization_members (org_id, user_id, role_id, created_at) VALUES (?, ?, ?, ?) ON CONFLICT DO NOTHING",
+		orgID, user.ID, role.ID, time.Now())
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrAlreadyMember
+	}
+
+	r.Invalidate(user.ID)
+	return &user, nil
+}
+
+// SetOrganizationRole changes the role of a member. The last admin of an
+// organization cannot be demoted.
+func (r *RBAC) SetOrganizationRole(orgID, userID uuid.UUID, roleName string) error {
+	role, err := r.GetRole(roleName)
+	if err != nil {
+		return err
+	}
+
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		if role.Name != models.RoleAdmin {
+			if err := ensureOrgAdminRemains(tx, orgID, userID); err != nil {
+				return err
+			}
+		}
+
+		result := tx.Model(&models.OrganizationMember{}).
+			Where("org_id = ? AND user_id = ?", orgID, userID).
+			Update("role_id", role.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotMember
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	r.Invalidate(userID)
+	return nil
+}
+
+// RemoveOrganizationMember removes a user from an organization. The last
+// admin of an organization cannot be removed.
+func (r *RBAC) RemoveOrganizationMember(orgID, userID uuid.UUID) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := ensureOrgAdminRemains(tx, orgID, userID); err != nil {
+			return err
+		}
+
+		result := tx.Where("org_id = ? AND user_id = ?", orgID, userID).Delete(&models.OrganizationMember{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotMember
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	r.Invalidate(userID)
+	return nil
+}
+
+// ensureOrgAdminRemains fails if the user is the only admin of the
+// organization
+func ensureOrgAdminRemains(tx *gorm.DB, orgID, userID uuid.UUID) error {
+	var admins []uuid.UUID
+	if err := tx.Table("organization_members").
+		Joins("JOIN roles ON roles.id = organization_members.role_id").
+		Where("organization_members.org_id = ? AND roles.name = ?", orgID, models.RoleAdmin).
+		Pluck("organization_members.user_id", &admins).Error; err != nil {
+		return err
+	}
+
+	if len(admins) == 1 && admins[0] == userID {
+		return ErrLastOrgAdmin
+	}
+	return nil
+}
+
+// joinDefaultOrganization makes a new user a member of the configured
+// default organization, if it exists
+func (o *OAuthManager) joinDefaultOrganization(tx *gorm.DB, user *models.User) error {
+	if o.config.DefaultOrganization == "" {
+		return nil
+	}
+
+	return tx.Exec(`INSERT INTO organization_members (org_id, user_id, role_id, created_at)
+		SELECT organizations.id, ?, roles.id, ? FROM organizations, roles
+		WHERE organizations.slug = ? AND roles.name = ?
+		ON CONFLICT DO NOTHING`,
+		user.ID, time.Now(), o.config.DefaultOrganization, models.RoleMember).Error
+}
+
+// defaultOrganization returns the organization a new token starts in: the
+// one the user joined first, or uuid.Nil without any membership
+func (o *OAuthManager) defaultOrganization(userID uuid.UUID) (uuid.UUID, error) {
+	var membership models.OrganizationMember
+	err := o.db.Where("user_id = ?", userID).Order("created_at, org_id").First(&membership).Error
+	if err == gorm.ErrRecordNotFound {
+		return uuid.Nil, nil
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return membership.OrgID, nil
+}
+
+// SwitchOrganization replaces the access token identified by tokenID with a
+// token for another organization the user belongs to. The new token keeps
+// the client, scope and authentication methods; the old one is revoked.
+func (o *OAuthManager) SwitchOrganization(tokenID string, amr []string, orgID uuid.UUID, ipAddress string) (string, *models.AccessToken, error) {
+	var current models.AccessToken
+	if err := o.db.Where("jti = ? AND expires_at > ? AND revoked_at IS NULL", tokenID, time.Now()).
+		First(&current).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return "", nil, ErrSessionNotFound
+		}
+		return "", nil, err
+	}
+
+	var count int64
+	if err := o.db.Model(&models.OrganizationMember{}).
+		Where("org_id = ? AND user_id = ?", orgID, current.UserID).
+		Count(&count).Error; err != nil {
+		return "", nil, err
+	}
+	if count == 0 {
+		return "", nil, ErrNotMember
+	}
+
+	tokenString, accessToken, err := o.CreateAccessToken(current.UserID, orgID, current.ClientID, current.Scope, SessionInfo{
+		IPAddress: current.IPAddress,
+		UserAgent: current.UserAgent,
+		AMR:       amr,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	if err := o.db.Model(&current).Update("revoked_at", time.Now()).Error; err != nil {
+		return "", nil, err
+	}
+	o.revocations.Revoke(current.JTI, current.ExpiresAt)
+
+	o.RecordAuditEvent(models.AuditEvent{
+		Event:     AuditOrganizationSwitched,
+		UserID:    &current.UserID,
+		ActorID:   &current.UserID,
+		IPAddress: ipAddress,
+		Details:   fmt.Sprintf("org: %s", orgID),
+	})
+
+	return tokenString, accessToken, nil
+}
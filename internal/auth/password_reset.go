@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"ishare-task-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Password reset errors
+var (
+	ErrUserNotFound      = errors.New("user not found")
+	ErrInvalidResetToken = errors.New("invalid or expired password reset token")
+)
+
+// CreatePasswordResetToken issues a single-use password reset token for the
+// user with the given email. Earlier unused tokens of the user are discarded.
+func (o *OAuthManager) CreatePasswordResetToken(email string) (string, *models.User, error) {
+	var user models.User
+	if err := o.db.Where("email = ?", email).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return "", nil, ErrUserNotFound
+		}
+		return "", nil, err
+	}
+
+	token, err := o.issuePasswordResetToken(&user)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return token, &user, nil
+}
+
+// issuePasswordResetToken creates a reset token for the user, discarding
+// earlier unused ones
+func (o *OAuthManager) issuePasswordResetToken(user *models.User) (string, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	resetToken := &models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: HashToken(token),
+		ExpiresAt: time.Now().Add(o.config.PasswordResetTTL),
+	}
+
+	err = o.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND used_at IS NULL", user.ID).
+			Delete(&models.PasswordResetToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(resetToken).Error
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// ResetPassword consumes a password reset token, sets the new password and
+// signs the user out of all sessions. The password must satisfy the policy.
+func (o *OAuthManager) ResetPassword(token, newPassword string) (*models.User, error) {
+	var user models.User
+	err := o.db.Transaction(func(tx *gorm.DB) error {
+		var resetToken models.PasswordResetToken
+		if err := tx.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?",
+			HashToken(token), time.Now()).First(&resetToken).Error; err != nil {
+			return ErrInvalidResetToken
+		}
+
+		// Mark the token used; the condition guards against concurrent use
+		result := tx.Model(&resetToken).Where("used_at IS NULL").Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidResetToken
+		}
+
+		if err := tx.Where("id = ?", resetToken.UserID).First(&user).Error; err != nil {
+			return ErrInvalidResetToken
+		}
+
+		// A rejected password rolls back and keeps the token usable
+		if err := o.policy.Validate(newPassword, user.Email); err != nil {
+			return err
+		}
+
+		hashedPassword, err := o.hasher.Hash(newPassword)
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&user).Update("password_hash", hashedPassword).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := o.RevokeAllSessions(user.ID, ""); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"net/http"
+
+	"ishare-task-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ProjectRole returns the user's role on the project, or an empty string
+// without access
+func (a *TaskAuthorizer) ProjectRole(db *gorm.DB, projectID, userID uuid.UUID) (string, error) {
+	var roles []string
+	if err := db.Model(&models.ProjectMember{}).
+		Where("project_id = ? AND user_id = ?", projectID, userID).
+		Pluck("role", &roles).Error; err != nil {
+		return "", err
+	}
+	return highestTaskRole(roles), nil
+}
+
+// AuthorizeProject loads the project named by the id parameter and checks
+// that the user holds at least the required role on it. On failure it
+// writes the response like Authorize.
+func (a *TaskAuthorizer) AuthorizeProject(c *gin.Context, required string) (*models.Project, string, bool) {
+	user, orgID, projectID, ok := resourceRequest(c, "Invalid project ID format")
+	if !ok {
+		return nil, "", false
+	}
+
+	db := a.DB(c)
+
+	var project models.Project
+	if err := db.Where("id = ? AND org_id = ?", projectID, orgID).First(&project).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Project not found",
+			})
+			return nil, "", false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve project",
+		})
+		return nil, "", false
+	}
+
+	role, err := a.ProjectRole(db, project.ID, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to check project access",
+		})
+		return nil, "", false
+	}
+
+	if role == "" {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Project not found",
+		})
+		return nil, "", false
+	}
+
+	if models.TaskRoleRank[role] < models.TaskRoleRank[required] {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":         "Insufficient access to the project",
+			"role":          role,
+			"required_role": required,
+		})
+		return nil, "", false
+	}
+
+	return &project, role, true
+}
+
+// VisibleProjects limits a project query to the projects the user is a
+// member of
+func (a *TaskAuthorizer) VisibleProjects(query *gorm.DB, userID uuid.UUID) *gorm.DB {
+	return query.Where("EXISTS (SELECT 1 FROM project_members WHERE project_members.project_id = projects.id AND project_members.user_id = ?)", userID)
+}
@@ -0,0 +1,219 @@
+package auth
+
+import (
+	"strings"
+	"time"
+
+	"ishare-task-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Provisioning audit event names
+const (
+	AuditUserProvisioned = "user.provisioned"
+	AuditUserUpdated     = "user.updated"
+)
+
+// UserAttributes are the account attributes managed by a provisioning
+// client such as an HR system
+type UserAttributes struct {
+	Email       string
+	DisplayName string
+	Locale      string
+	Timezone    string
+	ExternalID  string
+	Active      bool
+	Password    string // optional; empty keeps the current password
+}
+
+// normalize trims and validates the attributes
+func (attrs *UserAttributes) normalize() error {
+	attrs.Email = strings.TrimSpace(attrs.Email)
+	attrs.DisplayName = truncate(strings.TrimSpace(attrs.DisplayName), 100)
+	attrs.ExternalID = strings.TrimSpace(attrs.ExternalID)
+
+	locale, err := normalizeLocale(attrs.Locale)
+	if err != nil {
+		return err
+	}
+	attrs.Locale = locale
+
+	timezone, err := normalizeTimezone(attrs.Timezone)
+	if err != nil {
+		return err
+	}
+	attrs.Timezone = timezone
+
+	return nil
+}
+
+// ProvisionUser creates an account for a provisioning client. The email is
+// trusted and marked verified. Without a password the account gets an
+// unusable one; the user can set one through the password reset flow.
+func (o *OAuthManager) ProvisionUser(attrs UserAttributes, ipAddress string) (*models.User, error) {
+	if err := attrs.normalize(); err != nil {
+		return nil, err
+	}
+
+	secret := attrs.Password
+	if secret != "" {
+		if err := o.policy.Validate(secret, attrs.Email); err != nil {
+			return nil, err
+		}
+	} else {
+		unusable, err := randomToken()
+		if err != nil {
+			return nil, err
+		}
+		secret = unusable
+	}
+	hashedPassword, err := o.hasher.Hash(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	user := &models.User{
+		Email:           attrs.Email,
+		PasswordHash:    hashedPassword,
+		EmailVerifiedAt: &now,
+		DisplayName:     attrs.DisplayName,
+		Locale:          attrs.Locale,
+		Timezone:        attrs.Timezone,
+		ExternalID:      attrs.ExternalID,
+	}
+	if !attrs.Active {
+		user.DisabledAt = &now
+	}
+
+	err = o.db.Transaction(func(tx *gorm.DB) error {
+		taken, err := o.emailTaken(tx, user.Email, user.ID)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return ErrEmailTaken
+		}
+		return o.createUserWithDefaultRole(tx, user)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	o.RecordAuditEvent(models.AuditEvent{
+		Event:     AuditUserProvisioned,
+		UserID:    &user.ID,
+		Email:     user.Email,
+		IPAddress: ipAddress,
+	})
+
+	return user, nil
+}
+
+// UpdateProvisionedUser replaces the managed attributes of an account.
+// Deactivating the account revokes all of its tokens and sessions, as does
+// setting a new password.
+func (o *OAuthManager) UpdateProvisionedUser(user *models.User, attrs UserAttributes, ipAddress string) error {
+	if err := attrs.normalize(); err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{
+		"display_name": attrs.DisplayName,
+		"locale":       attrs.Locale,
+		"timezone":     attrs.Timezone,
+		"external_id":  attrs.ExternalID,
+	}
+
+	now := time.Now()
+	if attrs.Email != user.Email {
+		taken, err := o.emailTaken(o.db, attrs.Email, user.ID)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return ErrEmailTaken
+		}
+		updates["email"] = attrs.Email
+		updates["email_verified_at"] = now
+	}
+
+	revoke := false
+	if attrs.Password != "" {
+		if err := o.policy.Validate(attrs.Password, attrs.Email); err != nil {
+			return err
+		}
+		hashedPassword, err := o.hasher.Hash(attrs.Password)
+		if err != nil {
+			return err
+		}
+		updates["password_hash"] = hashedPassword
+		revoke = true
+	}
+
+	wasDisabled := user.Disabled()
+	switch {
+	case !attrs.Active && !wasDisabled:
+		updates["disabled_at"] = now
+		revoke = true
+	case attrs.Active && wasDisabled:
+		updates["disabled_at"] = nil
+	}
+
+	if err := o.db.Model(user).Updates(updates).Error; err != nil {
+		return err
+	}
+
+	if revoke {
+		if _, err := o.revokeUserCredentials(user.ID); err != nil {
+			return err
+		}
+	}
+
+	o.RecordAuditEvent(models.AuditEvent{
+		Event:     AuditUserUpdated,
+		UserID:    &user.ID,
+		Email:     attrs.Email,
+		IPAddress: ipAddress,
+	})
+	if attrs.Active == wasDisabled {
+		event := AuditUserEnabled
+		if !attrs.Active {
+			event = AuditUserDisabled
+		}
+		o.RecordAuditEvent(models.AuditEvent{
+			Event:     event,
+			UserID:    &user.ID,
+			Email:     attrs.Email,
+			IPAddress: ipAddress,
+		})
+	}
+
+	return o.db.Where("id = ?", user.ID).First(user).Error
+}
+
+// DeprovisionUser permanently deletes an account on behalf of a
+// provisioning client. The last admin cannot be deleted.
+func (o *OAuthManager) DeprovisionUser(user *models.User, ipAddress string) error {
+	if err := o.ensureNotLastAdmin(user); err != nil {
+		return err
+	}
+
+	// Revoke first so stateless validation rejects outstanding tokens
+	if _, err := o.RevokeAllSessions(user.ID, ""); err != nil {
+		return err
+	}
+	if err := o.deleteUser(user); err != nil {
+		return err
+	}
+
+	o.RecordAuditEvent(models.AuditEvent{
+		Event:     AuditUserDeleted,
+		UserID:    &user.ID,
+		Email:     user.Email,
+		IPAddress: ipAddress,
+	})
+
+	return nil
+}
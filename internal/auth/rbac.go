@@ -0,0 +1,383 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"ishare-task-api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Role audit event names
+const (
+	AuditRoleCreated  = "role.created"
+	AuditRoleUpdated  = "role.updated"
+	AuditRoleDeleted  = "role.deleted"
+	AuditRoleAssigned = "role.assigned"
+	AuditRoleRemoved  = "role.removed"
+)
+
+// Role management errors
+var (
+	ErrRoleNotFound      = errors.New("role not found")
+	ErrRoleExists        = errors.New("role already exists")
+	ErrBuiltinRole       = errors.New("built-in roles cannot be changed")
+	ErrUnknownPermission = errors.New("unknown permission")
+	ErrLastAdmin         = errors.New("cannot remove the last admin")
+)
+
+// RBAC resolves and manages role-based permissions. Resolved permissions are
+// cached per user for a short time; every change through RBAC invalidates
+// the affected entries.
+type RBAC struct {
+	db  *gorm.DB
+	ttl time.Duration
+
+	mu         sync.RWMutex
+	entries    map[uuid.UUID]permissionEntry
+	orgEntries map[orgMembership]permissionEntry
+}
+
+type permissionEntry struct {
+	permissions map[string]bool
+	expiresAt   time.Time
+}
+
+// NewRBAC creates a new RBAC manager; a zero TTL disables caching
+func NewRBAC(db *gorm.DB, ttl time.Duration) *RBAC {
+	return &RBAC{
+		db:         db,
+		ttl:        ttl,
+		entries:    make(map[uuid.UUID]permissionEntry),
+		orgEntries: make(map[orgMembership]permissionEntry),
+	}
+}
+
+// Permissions returns the set of permissions granted to the user by its roles
+func (r *RBAC) Permissions(userID uuid.UUID) (map[string]bool, error) {
+	now := time.Now()
+
+	r.mu.RLock()
+	entry, ok := r.entries[userID]
+	r.mu.RUnlock()
+
+	if ok && now.Before(entry.expiresAt) {
+		return entry.permissions, nil
+	}
+
+	var names []string
+	if err := r.db.Table("permissions").
+		Distinct("permissions.name").
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Joins("JOIN user_roles ON user_roles.role_id = role_permissions.role_id").
+		Where("user_roles.user_id = ?", userID).
+		Pluck("permissions.name", &names).Error; err != nil {
+		return nil, err
+	}
+
+	permissions := make(map[string]bool, len(names))
+	for _, name := range names {
+		permissions[name] = true
+	}
+
+	if r.ttl > 0 {
+		r.mu.Lock()
+		r.entries[userID] = permissionEntry{permissions: permissions, expiresAt: now.Add(r.ttl)}
+		r.mu.Unlock()
+	}
+
+	return permissions, nil
+}
+
+// HasPermission reports whether the user's roles grant the permission
+func (r *RBAC) HasPermission(userID uuid.UUID, permission string) (bool, error) {
+	permissions, err := r.Permissions(userID)
+	if err != nil {
+		return false, err
+	}
+	return permissions[permission], nil
+}
+
+// Invalidate removes a user's cached permissions, including those in
+// organizations
+func (r *RBAC) Invalidate(userID uuid.UUID) {
+	r.mu.Lock()
+	delete(r.entries, userID)
+	for membership := range r.orgEntries {
+		if membership.userID == userID {
+			delete(r.orgEntries, membership)
+		}
+	}
+	r.mu.Unlock()
+}
+
+// InvalidateAll clears the permission cache
+func (r *RBAC) InvalidateAll() {
+	r.mu.Lock()
+	r.entries = make(map[uuid.UUID]permissionEntry)
+	r.orgEntries = make(map[orgMembership]permissionEntry)
+	r.mu.Unlock()
+}
+
+// ListRoles returns all roles with their permissions
+func (r *RBAC) ListRoles() ([]models.Role, error) {
+	var roles []models.Role
+	if err := r.db.Preload("Permissions").Order("name").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// ListPermissions returns all known permissions
+func (r *RBAC) ListPermissions() ([]models.Permission, error) {
+	var permissions []models.Permission
+	if err := r.db.Order("name").Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+// GetRole returns a role by name
+func (r *RBAC) GetRole(name string) (*models.Role, error) {
+	var role models.Role
+	if err := r.db.Preload("Permissions").Where("name = ?", name).First(&role).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRoleNotFound
+		}
+		return nil, err
+	}
+	return &role, nil
+}
+
+// CreateRole creates a custom role with the given permissions
+func (r *RBAC) CreateRole(name, description string, permissionNames []string) (*models.Role, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+
+	var count int64
+	if err := r.db.Model(&models.Role{}).Where("name = ?", name).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrRoleExists
+	}
+
+	permissions, err := r.findPermissions(permissionNames)
+	if err != nil {
+		return nil, err
+	}
+
+	role := &models.Role{
+		Name:        name,
+		Description: description,
+		Permissions: permissions,
+	}
+	if err := r.db.Create(role).Error; err != nil {
+		return nil, err
+	}
+
+	return role, nil
+}
+
+// UpdateRole changes the description and, if given, the permissions of a
+// custom role
+func (r *RBAC) UpdateRole(name string, description *string, permissionNames []string) (*models.Role, error) {
+	role, err := r.GetRole(name)
+	if err != nil {
+		return nil, err
+	}
+	if role.Builtin {
+		return nil, ErrBuiltinRole
+	}
+
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		if description != nil {
+			if err := tx.Model(role).Update("description", *description).Error; err != nil {
+				return err
+			}
+		}
+
+		if permissionNames != nil {
+			permissions, err := r.findPermissions(permissionNames)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(role).Association("Permissions").Replace(permissions); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r.InvalidateAll()
+	return r.GetRole(name)
+}
+
+// DeleteRole deletes a custom role and removes it from all users
+func (r *RBAC) DeleteRole(name string) error {
+	role, err := r.GetRole(name)
+	if err != nil {
+		return err
+	}
+	if role.Builtin {
+		return ErrBuiltinRole
+	}
+
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM user_roles WHERE role_id = ?", role.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(role).Association("Permissions").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(role).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	r.InvalidateAll()
+	return nil
+}
+
+// UserRoles returns the roles assigned to a user
+func (r *RBAC) UserRoles(userID uuid.UUID) ([]models.Role, error) {
+	var user models.User
+	if err := r.db.Preload("Roles.Permissions").Where("id = ?", userID).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return user.Roles, nil
+}
+
+// AssignRole grants a role to a user
+func (r *RBAC) AssignRole(userID uuid.UUID, roleName string) error {
+	role, err := r.GetRole(roleName)
+	if err != nil {
+		return err
+	}
+
+	var user models.User
+	if err := r.db.Where("id = ?", userID).First(&user).Error; err != nil {
+		return ErrUserNotFound
+	}
+
+	if err := r.db.Exec("INSERT INTO user_roles (user_id, role_id) VALUES (?, ?) ON CONFLICT DO NOTHING",
+		userID, role.ID).Error; err != nil {
+		return err
+	}
+
+	r.Invalidate(userID)
+	return nil
+}
+
+// RemoveRole takes a role away from a user. The admin role cannot be
+// removed from the last admin.
+func (r *RBAC) RemoveRole(userID uuid.UUID, roleName string) error {
+	role, err := r.GetRole(roleName)
+	if err != nil {
+		return err
+	}
+
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		if role.Name == models.RoleAdmin {
+			var admins int64
+			if err := tx.Table("user_roles").Where("role_id = ? AND user_id <> ?", role.ID, userID).
+				Count(&admins).Error; err != nil {
+				return err
+			}
+			if admins == 0 {
+				return ErrLastAdmin
+			}
+		}
+
+		return tx.Exec("DELETE FROM user_roles WHERE user_id = ? AND role_id = ?", userID, role.ID).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	r.Invalidate(userID)
+	return nil
+}
+
+// BootstrapAdmins grants the admin role, globally and in the default
+// organization, to the verified accounts with the given emails and returns
+// how many were granted
+func (r *RBAC) BootstrapAdmins(emails []string) (int, error) {
+	granted := 0
+	for _, email := range emails {
+		var user models.User
+		if err := r.db.Where("LOWER(email) = LOWER(?) AND email_verified_at IS NOT NULL", email).
+			First(&user).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				continue
+			}
+			return granted, err
+		}
+
+		if err := r.AssignRole(user.ID, models.RoleAdmin); err != nil {
+			return granted, err
+		}
+		if err := r.db.Exec(`INSERT INTO organization_members (org_id, user_id, role_id, created_at)
+			SELECT organizations.id, ?, roles.id, NOW() FROM organizations, roles
+			WHERE organizations.slug = ? AND roles.name = ?
+			ON CONFLICT (org_id, user_id) DO UPDATE SET role_id = EXCLUDED.role_id`,
+			user.ID, models.DefaultOrganizationSlug, models.RoleAdmin).Error; err != nil {
+			return granted, err
+		}
+		r.Invalidate(user.ID)
+		granted++
+	}
+
+	return granted, nil
+}
+
+// findPermissions loads the named permissions, failing on unknown names
+func (r *RBAC) findPermissions(names []string) ([]models.Permission, error) {
+	if len(names) == 0 {
+		return []models.Permission{}, nil
+	}
+
+	var permissions []models.Permission
+	if err := r.db.Where("name IN ?", names).Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+
+	if len(permissions) != len(uniqueStrings(names)) {
+		found := make(map[string]bool, len(permissions))
+		for _, permission := range permissions {
+			found[permission.Name] = true
+		}
+		for _, name := range names {
+			if !found[name] {
+				return nil, fmt.Errorf("%w: %s", ErrUnknownPermission, name)
+			}
+		}
+	}
+
+	return permissions, nil
+}
+
+// uniqueStrings returns the distinct values of a slice
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := values[:0:0]
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
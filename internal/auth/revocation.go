@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"ishare-task-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Token validation modes
+const (
+	ValidationModeDatabase  = "database"
+	ValidationModeStateless = "stateless"
+)
+
+// RevocationCache keeps an in-memory list of revoked access tokens so that
+// stateless validation does not have to query the database on every request
+type RevocationCache struct {
+	db       *gorm.DB
+	interval time.Duration
+
+	mu      sync.RWMutex
+	revoked map[string]time.Time // jti -> token expiry
+
+	stop chan struct{}
+}
+
+// NewRevocationCache creates a new revocation cache
+func NewRevocationCache(db *gorm.DB, interval time.Duration) *RevocationCache {
+	return &RevocationCache{
+		db:       db,
+		interval: interval,
+		revoked:  make(map[string]time.Time),
+	}
+}
+
+// Start loads the revocation list and refreshes it periodically
+func (r *RevocationCache) Start() {
+	if err := r.Refresh(); err != nil {
+		log.Printf("Failed to load token revocation list: %v", err)
+	}
+
+	if r.interval <= 0 {
+		return
+	}
+
+	r.stop = make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := r.Refresh(); err != nil {
+					log.Printf("Failed to refresh token revocation list: %v", err)
+				}
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the periodic refresh
+func (r *RevocationCache) Stop() {
+	if r.stop != nil {
+		close(r.stop)
+		r.stop = nil
+	}
+}
+
+// Refresh reloads revoked, not yet expired tokens from the database
+func (r *RevocationCache) Refresh() error {
+	var tokens []models.AccessToken
+	if err := r.db.Select("jti", "expires_at").
+		Where("revoked_at IS NOT NULL AND expires_at > ?", time.Now()).
+		Find(&tokens).Error; err != nil {
+		return err
+	}
+
+	revoked := make(map[string]time.Time, len(tokens))
+	for _, token := range tokens {
+		revoked[token.JTI] = token.ExpiresAt
+	}
+
+	r.mu.Lock()
+	r.revoked = revoked
+	r.mu.Unlock()
+
+	return nil
+}
+
+// Revoke pushes a revoked token into the cache without waiting for a refresh.
+// Expired entries are dropped here too, since Refresh only runs when the
+// cache was started for stateless validation.
+func (r *RevocationCache) Revoke(jti string, expiresAt time.Time) {
+	now := time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for revokedJTI, expiry := range r.revoked {
+		if !expiry.After(now) {
+			delete(r.revoked, revokedJTI)
+		}
+	}
+	if expiresAt.After(now) {
+		r.revoked[jti] = expiresAt
+	}
+}
+
+// IsRevoked reports whether the token with the given jti has been revoked
+func (r *RevocationCache) IsRevoked(jti string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, revoked := r.revoked[jti]
+	return revoked
+}
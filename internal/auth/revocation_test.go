@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRevocationCacheDropsExpiredEntries(t *testing.T) {
+	cache := NewRevocationCache(nil, 0)
+	now := time.Now()
+
+	cache.Revoke("expired", now.Add(-time.Second))
+	cache.Revoke("expiring", now.Add(50*time.Millisecond))
+	cache.Revoke("valid", now.Add(time.Hour))
+
+	if cache.IsRevoked("expired") {
+		t.Error("an already expired token was added to the cache")
+	}
+	if !cache.IsRevoked("expiring") || !cache.IsRevoked("valid") {
+		t.Fatal("revoked tokens are not reported as revoked")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	cache.Revoke("later", now.Add(time.Hour))
+
+	if cache.IsRevoked("expiring") {
+		t.Error("an expired entry was kept after the next revocation")
+	}
+	if len(cache.revoked) != 2 {
+		t.Errorf("cache holds %d entries; want 2", len(cache.revoked))
+	}
+}
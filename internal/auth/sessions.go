@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"ishare-task-api/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// ErrSessionNotFound is returned when a session does not exist or belongs to
+// another user
+var ErrSessionNotFound = errors.New("session not found")
+
+// ListSessions returns the active sessions (access tokens) of a user
+func (o *OAuthManager) ListSessions(userID uuid.UUID) ([]models.AccessToken, error) {
+	var sessions []models.AccessToken
+	if err := o.db.Where("user_id = ? AND expires_at > ? AND revoked_at IS NULL", userID, time.Now()).
+		Order("created_at DESC").
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession revokes a single session of a user
+func (o *OAuthManager) RevokeSession(userID, sessionID uuid.UUID) error {
+	var session models.AccessToken
+	if err := o.db.Where("id = ? AND user_id = ? AND expires_at > ? AND revoked_at IS NULL",
+		sessionID, userID, time.Now()).First(&session).Error; err != nil {
+		return ErrSessionNotFound
+	}
+
+	if err := o.db.Model(&session).Update("revoked_at", time.Now()).Error; err != nil {
+		return err
+	}
+
+	o.revocations.Revoke(session.JTI, session.ExpiresAt)
+	return nil
+}
+
+// RevokeAllSessions revokes every active session of a user except the one
+// identified by exceptJTI (which may be empty) and returns how many were
+// revoked. Browser SSO sessions are ended as well.
+func (o *OAuthManager) RevokeAllSessions(userID uuid.UUID, exceptJTI string) (int64, error) {
+	if err := o.DeleteUserSSOSessions(userID); err != nil {
+		return 0, err
+	}
+
+	sessions, err := o.ListSessions(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	var revoked int64
+	now := time.Now()
+	for _, session := range sessions {
+		if session.JTI == exceptJTI {
+			continue
+		}
+
+		if err := o.db.Model(&session).Update("revoked_at", now).Error; err != nil {
+			return revoked, err
+		}
+
+		o.revocations.Revoke(session.JTI, session.ExpiresAt)
+		revoked++
+	}
+
+	return revoked, nil
+}
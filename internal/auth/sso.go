@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"ishare-task-api/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// CreateSSOSession establishes a browser SSO session for a user who just
+// authenticated and returns the opaque cookie value
+func (o *OAuthManager) CreateSSOSession(userID uuid.UUID, ttl time.Duration, info SessionInfo) (string, *models.SSOSession, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now()
+	session := &models.SSOSession{
+		TokenHash: HashToken(token),
+		UserID:    userID,
+		AuthTime:  now,
+		AMR:       strings.Join(info.AMR, " "),
+		IPAddress: info.IPAddress,
+		UserAgent: truncate(info.UserAgent, 512),
+		ExpiresAt: now.Add(ttl),
+	}
+
+	if err := o.db.Create(session).Error; err != nil {
+		return "", nil, err
+	}
+
+	return token, session, nil
+}
+
+// GetSSOSession returns the active SSO session for a cookie value
+func (o *OAuthManager) GetSSOSession(token string) (*models.SSOSession, error) {
+	var session models.SSOSession
+	if err := o.db.Where("token_hash = ? AND expires_at > ?", HashToken(token), time.Now()).
+		First(&session).Error; err != nil {
+		return nil, fmt.Errorf("invalid or expired SSO session")
+	}
+
+	return &session, nil
+}
+
+// DeleteSSOSession ends the SSO session for a cookie value
+func (o *OAuthManager) DeleteSSOSession(token string) error {
+	return o.db.Where("token_hash = ?", HashToken(token)).Delete(&models.SSOSession{}).Error
+}
+
+// DeleteUserSSOSessions ends all SSO sessions of a user
+func (o *OAuthManager) DeleteUserSSOSessions(userID uuid.UUID) error {
+	return o.db.Where("user_id = ?", userID).Delete(&models.SSOSession{}).Error
+}
@@ -0,0 +1,188 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+
+	"ishare-task-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TaskLinkPrefix marks share-by-link tokens
+const TaskLinkPrefix = "ishare_link_"
+
+// taskLinkDisplayLength is how much of a link token is kept to identify it
+// in listings
+const taskLinkDisplayLength = len(TaskLinkPrefix) + 6
+
+// TaskAuthorizer decides what a user may do with a task or project. The
+// task's owner holds the owner role; other members of the organization hold
+// the highest of the role of their share and their role on the task's
+// project, if any. Tasks are only found inside the active organization.
+type TaskAuthorizer struct {
+	db *gorm.DB
+}
+
+// NewTaskAuthorizer creates a new task authorizer
+func NewTaskAuthorizer(db *gorm.DB) *TaskAuthorizer {
+	return &TaskAuthorizer{
+		db: db,
+	}
+}
+
+// DB returns the request's tenant-scoped transaction; outside of it the
+// row-level security policies hide all tasks
+func (a *TaskAuthorizer) DB(c *gin.Context) *gorm.DB {
+	if tx, ok := GetTransactionFromContext(c); ok {
+		return tx
+	}
+	return a.db
+}
+
+// Role returns the user's role on the task, or an empty string without
+// access
+func (a *TaskAuthorizer) Role(db *gorm.DB, task *models.Task, userID uuid.UUID) (string, error) {
+	if task.OwnerID == userID {
+		return models.TaskRoleOwner, nil
+	}
+
+	var roles []string
+	if err := db.Model(&models.TaskShare{}).
+		Where("task_id = ? AND user_id = ?", task.ID, userID).
+		Pluck("role", &roles).Error; err != nil {
+		return "", err
+	}
+
+	if task.ProjectID != nil {
+		projectRole, err := a.ProjectRole(db, *task.ProjectID, userID)
+		if err != nil {
+			return "", err
+		}
+		roles = append(roles, projectRole)
+	}
+
+	return highestTaskRole(roles), nil
+}
+
+// Authorize loads the task named by the id parameter and checks that the
+// user holds at least the required role on it. On failure it writes the
+// response: tasks the user cannot access answer 404 so that their existence
+// is not revealed, insufficient roles answer 403.
+func (a *TaskAuthorizer) Authorize(c *gin.Context, required string) (*models.Task, string, bool) {
+	user, orgID, taskID, ok := resourceRequest(c, "Invalid task ID format")
+	if !ok {
+		return nil, "", false
+	}
+
+	db := a.DB(c)
+
+	var task models.Task
+	if err := db.Where("id = ? AND org_id = ?", taskID, orgID).First(&task).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Task not found",
+			})
+			return nil, "", false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve task",
+		})
+		return nil, "", false
+	}
+
+	role, err := a.Role(db, &task, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to check task access",
+		})
+		return nil, "", false
+	}
+
+	if role == "" {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Task not found",
+		})
+		return nil, "", false
+	}
+
+	if models.TaskRoleRank[role] < models.TaskRoleRank[required] {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":         "Insufficient access to the task",
+			"role":          role,
+			"required_role": required,
+		})
+		return nil, "", false
+	}
+
+	return &task, role, true
+}
+
+// Visible limits a task query to the tasks the user owns, that are shared
+// with them or that belong to a project they are a member of
+func (a *TaskAuthorizer) Visible(query *gorm.DB, userID uuid.UUID) *gorm.DB {
+	return query.Where(`(tasks.owner_id = ?
+		OR EXISTS (SELECT 1 FROM task_shares WHERE task_shares.task_id = tasks.id AND task_shares.user_id = ?)
+		OR EXISTS (SELECT 1 FROM project_members WHERE project_members.project_id = tasks.project_id AND project_members.user_id = ?))`,
+		userID, userID, userID)
+}
+
+// resourceRequest returns the user, the active organization and the id
+// parameter of a request, writing the response when one is missing
+func resourceRequest(c *gin.Context, invalidID string) (*models.User, uuid.UUID, uuid.UUID, bool) {
+	user, exists := GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+		})
+		return nil, uuid.Nil, uuid.Nil, false
+	}
+
+	orgID, ok := GetOrganizationIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "No active organization",
+		})
+		return nil, uuid.Nil, uuid.Nil, false
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": invalidID,
+		})
+		return nil, uuid.Nil, uuid.Nil, false
+	}
+
+	return user, orgID, id, true
+}
+
+// highestTaskRole returns the role granting the most access, or an empty
+// string for none
+func highestTaskRole(roles []string) string {
+	highest := ""
+	for _, role := range roles {
+		if models.TaskRoleRank[role] > models.TaskRoleRank[highest] {
+			highest = role
+		}
+	}
+	return highest
+}
+
+// IsTaskLink reports whether a token is a share-by-link token
+func IsTaskLink(token string) bool {
+	return strings.HasPrefix(token, TaskLinkPrefix)
+}
+
+// NewTaskLink generates a share-by-link token and returns it with its
+// hash and display prefix
+func NewTaskLink() (token, hash, prefix string, err error) {
+	secret, err := randomToken()
+	if err != nil {
+		return "", "", "", err
+	}
+	token = TaskLinkPrefix + secret
+	return token, HashToken(token), token[:taskLinkDisplayLength], nil
+}
@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// TenantScope middleware runs the rest of the request inside a database
+// transaction that carries the authenticated user and active organization
+// in the app.current_user_id and app.current_org_id settings, which the
+// row-level security policies on tenant tables check. Handlers reach the
+// transaction through GetTransactionFromContext. The transaction is
+// committed before the response is sent, unless the request fails.
+func (a *AuthMiddleware) TenantScope() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := GetUserFromContext(c)
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Authentication required",
+			})
+			c.Abort()
+			return
+		}
+
+		orgID := ""
+		if id, ok := GetOrganizationIDFromContext(c); ok {
+			orgID = id.String()
+		}
+
+		tx := a.db.WithContext(c.Request.Context()).Begin()
+		if tx.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to start transaction",
+			})
+			c.Abort()
+			return
+		}
+
+		if err := tx.Exec("SELECT set_config('app.current_user_id', ?, true), set_config('app.current_org_id', ?, true)",
+			user.ID.String(), orgID).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to start transaction",
+			})
+			c.Abort()
+			return
+		}
+
+		writer := &bufferedWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = writer
+		c.Set("tx", tx)
+
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				tx.Rollback()
+				c.Writer = writer.ResponseWriter
+				panic(recovered)
+			}
+		}()
+
+		c.Next()
+
+		c.Writer = writer.ResponseWriter
+		if writer.status >= http.StatusBadRequest {
+			tx.Rollback()
+			writer.flush()
+			return
+		}
+
+		if err := tx.Commit().Error; err != nil {
+			log.Printf("Failed to commit request transaction: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to save changes",
+			})
+			return
+		}
+		writer.flush()
+	}
+}
+
+// GetTransactionFromContext gets the tenant-scoped transaction of the
+// request, if TenantScope started one
+func GetTransactionFromContext(c *gin.Context) (*gorm.DB, bool) {
+	txInterface, exists := c.Get("tx")
+	if !exists {
+		return nil, false
+	}
+
+	tx, ok := txInterface.(*gorm.DB)
+	return tx, ok
+}
+
+// bufferedWriter holds back the response until the request transaction has
+// been committed, so a failed commit can still be reported
+type bufferedWriter struct {
+	gin.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	return false
+}
+
+// flush sends the buffered response
+func (w *bufferedWriter) flush() {
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.WriteHeaderNow()
+	if w.body.Len() > 0 {
+		w.ResponseWriter.Write(w.body.Bytes())
+	}
+}
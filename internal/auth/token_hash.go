@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// HashToken returns the hex-encoded SHA-256 hash stored in place of a raw token
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomToken returns a URL-safe random token with 256 bits of entropy
+func randomToken() (string, error) {
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(tokenBytes), nil
+}
+
+// TokenID returns the identifier an access token is stored under. Tokens
+// issued before jti was introduced are identified by their hash, which is
+// what the access_tokens migration used as their jti.
+func TokenID(claims *Claims, tokenString string) string {
+	if claims.ID != "" {
+		return claims.ID
+	}
+	return HashToken(tokenString)
+}
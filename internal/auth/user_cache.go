@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"sync"
+	"time"
+
+	"ishare-task-api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// UserCache caches users loaded by the authentication middleware
+type UserCache struct {
+	db  *gorm.DB
+	ttl time.Duration
+
+	mu      sync.RWMutex
+	entries map[uuid.UUID]userCacheEntry
+}
+
+type userCacheEntry struct {
+	user      models.User
+	expiresAt time.Time
+}
+
+// NewUserCache creates a new user cache; a zero TTL disables caching
+func NewUserCache(db *gorm.DB, ttl time.Duration) *UserCache {
+	return &UserCache{
+		db:      db,
+		ttl:     ttl,
+		entries: make(map[uuid.UUID]userCacheEntry),
+	}
+}
+
+// Get returns the user, loading it from the database when not cached
+func (u *UserCache) Get(userID uuid.UUID) (*models.User, error) {
+	now := time.Now()
+
+	u.mu.RLock()
+	entry, ok := u.entries[userID]
+	u.mu.RUnlock()
+
+	if ok && now.Before(entry.expiresAt) {
+		user := entry.user
+		return &user, nil
+	}
+
+	var user models.User
+	if err := u.db.Where("id = ?", userID).First(&user).Error; err != nil {
+		return nil, err
+	}
+
+	if u.ttl > 0 {
+		u.mu.Lock()
+		u.entries[userID] = userCacheEntry{user: user, expiresAt: now.Add(u.ttl)}
+		u.mu.Unlock()
+	}
+
+	return &user, nil
+}
+
+// Invalidate removes a user from the cache
+func (u *UserCache) Invalidate(userID uuid.UUID) {
+	u.mu.Lock()
+	delete(u.entries, userID)
+	u.mu.Unlock()
+}
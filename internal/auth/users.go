@@ -0,0 +1,264 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"ishare-task-api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// User administration audit event names
+const (
+	AuditUserDisabled      = "user.disabled"
+	AuditUserEnabled       = "user.enabled"
+	AuditUserPasswordReset = "user.password_reset_forced"
+	AuditUserTokensRevoked = "user.tokens_revoked"
+	AuditUserDeleted       = "user.deleted"
+)
+
+// User administration errors
+var (
+	ErrUserDisabled = errors.New("account is disabled")
+	ErrSelfAction   = errors.New("administrators cannot apply this action to their own account")
+)
+
+// ListUsers returns a page of users, optionally filtered by a search term
+// matched against email and display name, together with the total count
+func (o *OAuthManager) ListUsers(search string, offset, limit int) ([]models.User, int64, error) {
+	query := o.db.Model(&models.User{})
+	if search = strings.TrimSpace(search); search != "" {
+		pattern := "%" + escapeLike(search) + "%"
+		query = query.Where("email ILIKE ? OR display_name ILIKE ?", pattern, pattern)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var users []models.User
+	if err := query.Preload("Roles").Order("created_at DESC").
+		Offset(offset).Limit(limit).Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+// FindUser returns a user with its roles, or ErrUserNotFound
+func (o *OAuthManager) FindUser(userID uuid.UUID) (*models.User, error) {
+	user, err := o.GetUserByID(userID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// DisableUser blocks the account from signing in and revokes all of its
+// tokens and sessions
+func (o *OAuthManager) DisableUser(userID, actorID uuid.UUID, ipAddress string) (*models.User, error) {
+	if userID == actorID {
+		return nil, ErrSelfAction
+	}
+
+	user, err := o.FindUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.Disabled() {
+		now := time.Now()
+		if err := o.db.Model(user).Update("disabled_at", now).Error; err != nil {
+			return nil, err
+		}
+		user.DisabledAt = &now
+	}
+
+	if _, err := o.revokeUserCredentials(user.ID); err != nil {
+		return nil, err
+	}
+
+	o.RecordAuditEvent(models.AuditEvent{
+		Event:     AuditUserDisabled,
+		UserID:    &user.ID,
+		ActorID:   &actorID,
+		Email:     user.Email,
+		IPAddress: ipAddress,
+	})
+
+	return user, nil
+}
+
+// EnableUser lets a disabled account sign in again
+func (o *OAuthManager) EnableUser(userID, actorID uuid.UUID, ipAddress string) (*models.User, error) {
+	user, err := o.FindUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Disabled() {
+		if err := o.db.Model(user).Update("disabled_at", nil).Error; err != nil {
+			return nil, err
+		}
+		user.DisabledAt = nil
+	}
+
+	o.RecordAuditEvent(models.AuditEvent{
+		Event:     AuditUserEnabled,
+		UserID:    &user.ID,
+		ActorID:   &actorID,
+		Email:     user.Email,
+		IPAddress: ipAddress,
+	})
+
+	return user, nil
+}
+
+// ForcePasswordReset replaces the user's password with an unusable one,
+// signs the user out everywhere and returns a password reset token for the
+// user to choose a new password
+func (o *OAuthManager) ForcePasswordReset(userID, actorID uuid.UUID, ipAddress string) (string, *models.User, error) {
+	user, err := o.FindUser(userID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	unusable, err := randomToken()
+	if err != nil {
+		return "", nil, err
+	}
+	hashedPassword, err := o.hasher.Hash(unusable)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := o.db.Model(user).Update("password_hash", hashedPassword).Error; err != nil {
+		return "", nil, err
+	}
+
+	if _, err := o.revokeUserCredentials(user.ID); err != nil {
+		return "", nil, err
+	}
+
+	token, err := o.issuePasswordResetToken(user)
+	if err != nil {
+		return "", nil, err
+	}
+
+	o.RecordAuditEvent(models.AuditEvent{
+		Event:     AuditUserPasswordReset,
+		UserID:    &user.ID,
+		ActorID:   &actorID,
+		Email:     user.Email,
+		IPAddress: ipAddress,
+	})
+
+	return token, user, nil
+}
+
+// RevokeUserTokens revokes all tokens and sessions of a user and returns
+// how many tokens were revoked
+func (o *OAuthManager) RevokeUserTokens(userID, actorID uuid.UUID, ipAddress string) (int64, error) {
+	user, err := o.FindUser(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	revoked, err := o.revokeUserCredentials(user.ID)
+	if err != nil {
+		return revoked, err
+	}
+
+	o.RecordAuditEvent(models.AuditEvent{
+		Event:     AuditUserTokensRevoked,
+		UserID:    &user.ID,
+		ActorID:   &actorID,
+		Email:     user.Email,
+		IPAddress: ipAddress,
+	})
+
+	return revoked, nil
+}
+
+// DeleteUser permanently deletes a user right away. The last admin cannot
+// be deleted.
+func (o *OAuthManager) DeleteUser(userID, actorID uuid.UUID, ipAddress string) error {
+	if userID == actorID {
+		return ErrSelfAction
+	}
+
+	user, err := o.FindUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := o.ensureNotLastAdmin(user); err != nil {
+		return err
+	}
+
+	// Revoke first so stateless validation rejects outstanding tokens
+	if _, err := o.RevokeAllSessions(user.ID, ""); err != nil {
+		return err
+	}
+	if err := o.deleteUser(user); err != nil {
+		return err
+	}
+
+	o.RecordAuditEvent(models.AuditEvent{
+		Event:     AuditUserDeleted,
+		UserID:    &user.ID,
+		ActorID:   &actorID,
+		Email:     user.Email,
+		IPAddress: ipAddress,
+	})
+
+	return nil
+}
+
+// ensureNotLastAdmin returns ErrLastAdmin if the user, loaded with roles,
+// is the only admin
+func (o *OAuthManager) ensureNotLastAdmin(user *models.User) error {
+	for _, role := range user.Roles {
+		if role.Name != models.RoleAdmin {
+			continue
+		}
+		var admins int64
+		if err := o.db.Table("user_roles").Where("role_id = ? AND user_id <> ?", role.ID, user.ID).
+			Count(&admins).Error; err != nil {
+			return err
+		}
+		if admins == 0 {
+			return ErrLastAdmin
+		}
+	}
+	return nil
+}
+
+// revokeUserCredentials revokes every token, personal access token, session
+// and pending authorization code of a user and returns how many tokens were
+// revoked
+func (o *OAuthManager) revokeUserCredentials(userID uuid.UUID) (int64, error) {
+	revoked, err := o.RevokeAllSessions(userID, "")
+	if err != nil {
+		return revoked, err
+	}
+
+	revokedPATs, err := o.revokePersonalAccessTokens(userID)
+	revoked += revokedPATs
+	if err != nil {
+		return revoked, err
+	}
+
+	return revoked, o.db.Where("user_id = ?", userID).Delete(&models.AuthorizationCode{}).Error
+}
+
+// escapeLike escapes the wildcard characters of a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,200 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"ishare-task-api/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Email verification policies
+const (
+	EmailVerificationAllow  = "allow"
+	EmailVerificationLimit  = "limit"
+	EmailVerificationRefuse = "refuse"
+)
+
+// emailVerificationAudience keeps verification tokens from being accepted
+// as access tokens and vice versa
+const emailVerificationAudience = "email-verification"
+
+// Email verification errors
+var (
+	ErrEmailNotVerified          = errors.New("email address not verified")
+	ErrInvalidVerificationToken  = errors.New("invalid or expired verification token")
+	ErrVerificationEmailMismatch = errors.New("verification link does not match the account email")
+)
+
+// EmailVerificationClaims are the claims of an email verification token
+type EmailVerificationClaims struct {
+	UserID        uuid.UUID
+	Email         string
+	PreviousEmail string // set when the token confirms an email change
+}
+
+// GenerateEmailVerificationToken creates a signed, expiring token that binds
+// a user to the email address being verified. For an email change,
+// previousEmail is the address the change starts from.
+func (j *JWTManager) GenerateEmailVerificationToken(userID uuid.UUID, email, previousEmail string, ttl time.Duration) (string, error) {
+	header := map[string]string{
+		"alg": "HS256",
+		"typ": "JWT",
+	}
+
+	payload := map[string]interface{}{
+		"sub":   userID.String(),
+		"email": email,
+		"iss":   j.config.Issuer,
+		"aud":   emailVerificationAudience,
+		"exp":   time.Now().Add(ttl).Unix(),
+	}
+	if previousEmail != "" {
+		payload["previous_email"] = previousEmail
+	}
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." +
+		base64.RawURLEncoding.EncodeToString(payloadJSON)
+
+	return signingInput + "." + j.sign(signingInput), nil
+}
+
+// ValidateEmailVerificationToken verifies a token created by
+// GenerateEmailVerificationToken and returns the claims it binds
+func (j *JWTManager) ValidateEmailVerificationToken(token string) (*EmailVerificationClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	expectedSignature := j.sign(parts[0] + "." + parts[1])
+	if !hmac.Equal([]byte(parts[2]), []byte(expectedSignature)) {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	payloadBytes, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(payloadBytes, &payload); err != nil {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	if aud, _ := payload["aud"].(string); aud != emailVerificationAudience {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	exp, ok := payload["exp"].(float64)
+	if !ok || time.Unix(int64(exp), 0).Before(time.Now()) {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	sub, _ := payload["sub"].(string)
+	userID, err := uuid.Parse(sub)
+	if err != nil {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	email, _ := payload["email"].(string)
+	if email == "" {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	previousEmail, _ := payload["previous_email"].(string)
+
+	return &EmailVerificationClaims{
+		UserID:        userID,
+		Email:         email,
+		PreviousEmail: previousEmail,
+	}, nil
+}
+
+// CreateEmailVerificationToken creates a verification token for the user's
+// current email address
+func (o *OAuthManager) CreateEmailVerificationToken(user *models.User) (string, error) {
+	return o.jwt.GenerateEmailVerificationToken(user.ID, user.Email, "", o.config.EmailVerificationTTL)
+}
+
+// VerifyEmail marks the email address bound by the token as verified. For
+// an email change token the new address replaces the current one.
+func (o *OAuthManager) VerifyEmail(token string) (*models.User, error) {
+	claims, err := o.jwt.ValidateEmailVerificationToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	if err := o.db.Where("id = ?", claims.UserID).First(&user).Error; err != nil {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	if claims.PreviousEmail != "" {
+		return o.applyEmailChange(&user, claims)
+	}
+
+	if user.Email != claims.Email {
+		return nil, ErrVerificationEmailMismatch
+	}
+
+	if user.EmailVerified() {
+		return &user, nil
+	}
+
+	now := time.Now()
+	if err := o.db.Model(&user).Update("email_verified_at", now).Error; err != nil {
+		return nil, err
+	}
+	user.EmailVerifiedAt = &now
+
+	return &user, nil
+}
+
+// FindUnverifiedUser returns the user with the given email if it still needs
+// to verify its address
+func (o *OAuthManager) FindUnverifiedUser(email string) (*models.User, error) {
+	var user models.User
+	if err := o.db.Where("email = ? AND email_verified_at IS NULL", email).First(&user).Error; err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	return &user, nil
+}
+
+// checkEmailVerified applies the email verification policy at sign-in
+func (o *OAuthManager) checkEmailVerified(user *models.User) error {
+	if user.EmailVerified() {
+		return nil
+	}
+
+	switch o.config.EmailVerificationPolicy {
+	case EmailVerificationAllow:
+		return nil
+	case EmailVerificationLimit:
+		if time.Since(user.CreatedAt) <= o.config.EmailVerificationGrace {
+			return nil
+		}
+		return ErrEmailNotVerified
+	case EmailVerificationRefuse:
+		return ErrEmailNotVerified
+	default:
+		return fmt.Errorf("unknown email verification policy: %s", o.config.EmailVerificationPolicy)
+	}
+}
@@ -3,15 +3,22 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
 // Config holds all configuration for the application
 type Config struct {
-	Database DatabaseConfig
-	JWT      JWTConfig
-	OAuth    OAuthConfig
-	Server   ServerConfig
+	Database   DatabaseConfig
+	JWT        JWTConfig
+	OAuth      OAuthConfig
+	Session    SessionConfig
+	Mail       MailConfig
+	Password   PasswordConfig
+	Federation FederationConfig
+	LDAP       LDAPConfig
+	SCIM       SCIMConfig
+	Server     ServerConfig
 }
 
 // DatabaseConfig holds database configuration
@@ -30,6 +37,22 @@ type JWTConfig struct {
 	Issuer     string
 	Audience   string
 	Expiration time.Duration
+
+	// ValidationMode selects how bearer tokens are validated: "database"
+	// looks every token up in access_tokens, "stateless" trusts the signature
+	// and claims and only consults the in-memory revocation list
+	ValidationMode    string
+	RevocationRefresh time.Duration
+	UserCacheTTL      time.Duration
+
+	// EncryptionAlg enables nested JWS-in-JWE tokens: "dir" uses
+	// EncryptionKey (base64, 32 bytes), "RSA-OAEP-256" uses the PEM private
+	// key in EncryptionKeyFile. EncryptedClients lists the client IDs that
+	// receive encrypted tokens ("*" for all clients).
+	EncryptionAlg     string
+	EncryptionKey     string
+	EncryptionKeyFile string
+	EncryptedClients  []string
 }
 
 // OAuthConfig holds OAuth configuration
@@ -37,17 +60,188 @@ type OAuthConfig struct {
 	ClientID     string
 	ClientSecret string
 	RedirectURI  string
+
+	PasswordResetTTL time.Duration
+
+	// EmailVerificationPolicy controls sign-in of unverified accounts:
+	// "allow", "limit" (only within EmailVerificationGrace after
+	// registration) or "refuse"
+	EmailVerificationPolicy string
+	EmailVerificationGrace  time.Duration
+	EmailVerificationTTL    time.Duration
+
+	// Failed login throttling: after a few free attempts each failure doubles
+	// the wait starting at LoginBackoffBase; reaching the maximum for an
+	// account or IP address locks it for LoginLockoutDuration
+	LoginMaxAttempts      int
+	LoginMaxAttemptsPerIP int
+	LoginBackoffBase      time.Duration
+	LoginLockoutDuration  time.Duration
+
+	// AccountDeletionGrace is how long a deleted account can still be
+	// restored by signing in before it is purged
+	AccountDeletionGrace time.Duration
+
+	// PATDefaultLifetime and PATMaxLifetime bound the expiry of personal
+	// access tokens
+	PATDefaultLifetime time.Duration
+	PATMaxLifetime     time.Duration
+
+	// AdminEmails lists the verified accounts granted the admin role at startup
+	AdminEmails []string
+
+	// Authenticators lists the credential backends tried in order at
+	// password sign-in: "local" (the users table) and "ldap"
+	Authenticators []string
+
+	// DefaultOrganization is the slug of the organization new users join
+	// as members; empty leaves new users without an organization
+	DefaultOrganization string
+}
+
+// SessionConfig holds browser SSO session configuration
+type SessionConfig struct {
+	CookieName   string
+	CookieSecure bool
+	TTL          time.Duration
+}
+
+// MailConfig holds outgoing email configuration
+type MailConfig struct {
+	Driver       string // "smtp", "log" or "memory"
+	From         string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+}
+
+// PasswordConfig holds the password policy
+type PasswordConfig struct {
+	MinLength       int
+	MaxLength       int
+	RequiredClasses []string // any of "lower", "upper", "digit", "symbol"
+	RejectEmail     bool     // reject passwords containing the account email
+
+	// BreachedList points to breached password SHA-1 hashes: either a
+	// directory of k-anonymity range files named by the 5 character hash
+	// prefix (each line "SUFFIX:COUNT"), or a single file of full hashes
+	BreachedList string
+
+	// HashAlgorithm selects how new passwords are hashed: "argon2id" or
+	// "bcrypt". Hashes of either algorithm are always verified and upgraded
+	// to the current algorithm and parameters on sign-in.
+	HashAlgorithm     string
+	Argon2Memory      uint32 // KiB
+	Argon2Iterations  uint32
+	Argon2Parallelism uint8
+	BcryptCost        int
+}
+
+// FederationConfig holds the upstream OpenID Connect providers users can
+// sign in with
+type FederationConfig struct {
+	Providers []OIDCProviderConfig
+	StateTTL  time.Duration // how long an upstream sign-in may take
+}
+
+// OIDCProviderConfig describes an upstream OpenID Connect provider
+type OIDCProviderConfig struct {
+	Name         string // identifier used in URLs
+	DisplayName  string // shown on the "Sign in with" button
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+
+	// AutoProvision creates local accounts for unknown users; LinkByEmail
+	// links the upstream identity to an existing account with the same
+	// email. Both require a verified email, which TrustEmail assumes for
+	// providers that do not send the email_verified claim.
+	AutoProvision  bool
+	LinkByEmail    bool
+	TrustEmail     bool
+	AllowedDomains []string // email domains allowed to sign in, empty for all
+}
+
+// LDAPConfig holds the LDAP / Active Directory authentication backend
+type LDAPConfig struct {
+	URL                string // ldap:// or ldaps://
+	StartTLS           bool
+	InsecureSkipVerify bool
+	CAFile             string // PEM certificates trusted for the server
+	Timeout            time.Duration
+
+	// BindDN and BindPassword are the service account used to search for
+	// users; empty for an anonymous search
+	BindDN       string
+	BindPassword string
+
+	// UserFilter finds the user entry below BaseDN; %s is replaced by the
+	// escaped email entered at sign-in
+	BaseDN     string
+	UserFilter string
+
+	EmailAttribute string
+	NameAttribute  string
+	GroupAttribute string
+
+	// GroupRoles maps group DNs to role names. Mapped roles are granted and
+	// revoked at every sign-in to follow group membership.
+	GroupRoles map[string]string
+
+	// AutoProvision creates local accounts for directory users on their
+	// first sign-in
+	AutoProvision bool
+}
+
+// SCIMConfig holds the SCIM provisioning API configuration
+type SCIMConfig struct {
+	// BearerToken authenticates the provisioning client; SCIM is disabled
+	// while it is empty
+	BearerToken string
 }
 
 // ServerConfig holds server configuration
 type ServerConfig struct {
 	Environment string
 	Port        string
+	BaseURL     string // public URL used in links sent by email
 }
 
 // Load loads configuration from environment variables
 func Load() *Config {
 	expiration, _ := strconv.Atoi(getEnv("JWT_EXPIRATION_HOURS", "24"))
+	revocationRefresh, _ := strconv.Atoi(getEnv("TOKEN_REVOCATION_REFRESH_SECONDS", "30"))
+	userCacheTTL, _ := strconv.Atoi(getEnv("TOKEN_USER_CACHE_TTL_SECONDS", "60"))
+	ssoTTL, _ := strconv.Atoi(getEnv("SSO_SESSION_TTL_HOURS", "12"))
+	passwordResetTTL, _ := strconv.Atoi(getEnv("PASSWORD_RESET_TTL_MINUTES", "60"))
+	verificationGrace, _ := strconv.Atoi(getEnv("EMAIL_VERIFICATION_GRACE_HOURS", "72"))
+	verificationTTL, _ := strconv.Atoi(getEnv("EMAIL_VERIFICATION_TTL_HOURS", "48"))
+	loginMaxAttempts, _ := strconv.Atoi(getEnv("LOGIN_MAX_ATTEMPTS", "5"))
+	loginMaxAttemptsPerIP, _ := strconv.Atoi(getEnv("LOGIN_MAX_ATTEMPTS_PER_IP", "50"))
+	loginBackoffBase, _ := strconv.Atoi(getEnv("LOGIN_BACKOFF_BASE_SECONDS", "1"))
+	loginLockout, _ := strconv.Atoi(getEnv("LOGIN_LOCKOUT_MINUTES", "15"))
+	deletionGrace, _ := strconv.Atoi(getEnv("ACCOUNT_DELETION_GRACE_DAYS", "14"))
+	patDefaultLifetime, _ := strconv.Atoi(getEnv("PAT_DEFAULT_LIFETIME_DAYS", "30"))
+	patMaxLifetime, _ := strconv.Atoi(getEnv("PAT_MAX_LIFETIME_DAYS", "365"))
+	passwordMinLength, _ := strconv.Atoi(getEnv("PASSWORD_MIN_LENGTH", "8"))
+	passwordMaxLength, _ := strconv.Atoi(getEnv("PASSWORD_MAX_LENGTH", "64"))
+	passwordRejectEmail, _ := strconv.ParseBool(getEnv("PASSWORD_REJECT_EMAIL", "true"))
+	argon2Memory, _ := strconv.ParseUint(getEnv("ARGON2_MEMORY_KIB", "19456"), 10, 32)
+	argon2Iterations, _ := strconv.ParseUint(getEnv("ARGON2_ITERATIONS", "2"), 10, 32)
+	argon2Parallelism, _ := strconv.ParseUint(getEnv("ARGON2_PARALLELISM", "1"), 10, 8)
+	bcryptCost, _ := strconv.Atoi(getEnv("BCRYPT_COST", "10"))
+	federationStateTTL, _ := strconv.Atoi(getEnv("OIDC_STATE_TTL_MINUTES", "10"))
+	ldapStartTLS, _ := strconv.ParseBool(getEnv("LDAP_START_TLS", "false"))
+	ldapInsecureSkipVerify, _ := strconv.ParseBool(getEnv("LDAP_TLS_INSECURE_SKIP_VERIFY", "false"))
+	ldapTimeout, _ := strconv.Atoi(getEnv("LDAP_TIMEOUT_SECONDS", "5"))
+	ldapAutoProvision, _ := strconv.ParseBool(getEnv("LDAP_AUTO_PROVISION", "true"))
+	environment := getEnv("ENVIRONMENT", "development")
+	cookieSecure, err := strconv.ParseBool(getEnv("SSO_COOKIE_SECURE", ""))
+	if err != nil {
+		cookieSecure = environment != "development"
+	}
 	
 	return &Config{
 		Database: DatabaseConfig{
@@ -63,15 +257,101 @@ func Load() *Config {
 			Issuer:     getEnv("JWT_ISSUER", "ishare-task-api"),
 			Audience:   getEnv("JWT_AUDIENCE", "ishare-clients"),
 			Expiration: time.Duration(expiration) * time.Hour,
+
+			ValidationMode:    getEnv("TOKEN_VALIDATION_MODE", "database"),
+			RevocationRefresh: time.Duration(revocationRefresh) * time.Second,
+			UserCacheTTL:      time.Duration(userCacheTTL) * time.Second,
+
+			EncryptionAlg:     getEnv("JWT_ENCRYPTION_ALG", ""),
+			EncryptionKey:     getEnv("JWT_ENCRYPTION_KEY", ""),
+			EncryptionKeyFile: getEnv("JWT_ENCRYPTION_KEY_FILE", ""),
+			EncryptedClients:  getEnvList("JWT_ENCRYPTED_CLIENTS", "*"),
 		},
 		OAuth: OAuthConfig{
 			ClientID:     getEnv("OAUTH_CLIENT_ID", "test-client"),
 			ClientSecret: getEnv("OAUTH_CLIENT_SECRET", "test-secret"),
 			RedirectURI:  getEnv("OAUTH_REDIRECT_URI", "http://localhost:8080/oauth/callback"),
+
+			PasswordResetTTL: time.Duration(passwordResetTTL) * time.Minute,
+
+			EmailVerificationPolicy: getEnv("EMAIL_VERIFICATION_POLICY", "limit"),
+			EmailVerificationGrace:  time.Duration(verificationGrace) * time.Hour,
+			EmailVerificationTTL:    time.Duration(verificationTTL) * time.Hour,
+
+			LoginMaxAttempts:      loginMaxAttempts,
+			LoginMaxAttemptsPerIP: loginMaxAttemptsPerIP,
+			LoginBackoffBase:      time.Duration(loginBackoffBase) * time.Second,
+			LoginLockoutDuration:  time.Duration(loginLockout) * time.Minute,
+
+			AccountDeletionGrace: time.Duration(deletionGrace) * 24 * time.Hour,
+
+			PATDefaultLifetime: time.Duration(patDefaultLifetime) * 24 * time.Hour,
+			PATMaxLifetime:     time.Duration(patMaxLifetime) * 24 * time.Hour,
+
+			AdminEmails: getEnvList("ADMIN_EMAILS", ""),
+
+			Authenticators: getEnvList("AUTHENTICATORS", "local"),
+
+			DefaultOrganization: getEnv("DEFAULT_ORGANIZATION", "default"),
+		},
+		Session: SessionConfig{
+			CookieName:   getEnv("SSO_COOKIE_NAME", "ishare_sso"),
+			CookieSecure: cookieSecure,
+			TTL:          time.Duration(ssoTTL) * time.Hour,
+		},
+		Mail: MailConfig{
+			Driver:       getEnv("MAIL_DRIVER", "log"),
+			From:         getEnv("MAIL_FROM", "no-reply@ishare-task-api.local"),
+			SMTPHost:     getEnv("SMTP_HOST", "localhost"),
+			SMTPPort:     getEnv("SMTP_PORT", "587"),
+			SMTPUsername: getEnv("SMTP_USERNAME", ""),
+			SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		},
+		Password: PasswordConfig{
+			MinLength:       passwordMinLength,
+			MaxLength:       passwordMaxLength,
+			RequiredClasses: getEnvList("PASSWORD_REQUIRED_CLASSES", ""),
+			RejectEmail:     passwordRejectEmail,
+			BreachedList:    getEnv("PASSWORD_BREACHED_LIST", ""),
+
+			HashAlgorithm:     getEnv("PASSWORD_HASH_ALGORITHM", "argon2id"),
+			Argon2Memory:      uint32(argon2Memory),
+			Argon2Iterations:  uint32(argon2Iterations),
+			Argon2Parallelism: uint8(argon2Parallelism),
+			BcryptCost:        bcryptCost,
+		},
+		Federation: FederationConfig{
+			Providers: loadOIDCProviders(),
+			StateTTL:  time.Duration(federationStateTTL) * time.Minute,
+		},
+		LDAP: LDAPConfig{
+			URL:                getEnv("LDAP_URL", ""),
+			StartTLS:           ldapStartTLS,
+			InsecureSkipVerify: ldapInsecureSkipVerify,
+			CAFile:             getEnv("LDAP_TLS_CA_FILE", ""),
+			Timeout:            time.Duration(ldapTimeout) * time.Second,
+
+			BindDN:       getEnv("LDAP_BIND_DN", ""),
+			BindPassword: getEnv("LDAP_BIND_PASSWORD", ""),
+
+			BaseDN:     getEnv("LDAP_BASE_DN", ""),
+			UserFilter: getEnv("LDAP_USER_FILTER", "(&(objectClass=person)(mail=%s))"),
+
+			EmailAttribute: getEnv("LDAP_EMAIL_ATTRIBUTE", "mail"),
+			NameAttribute:  getEnv("LDAP_NAME_ATTRIBUTE", "displayName"),
+			GroupAttribute: getEnv("LDAP_GROUP_ATTRIBUTE", "memberOf"),
+
+			GroupRoles: loadLDAPGroupRoles(),
+
+			AutoProvision: ldapAutoProvision,
+		},
+		SCIM: SCIMConfig{
+			BearerToken: getEnv("SCIM_BEARER_TOKEN", ""),
 		},
 		Server: ServerConfig{
-			Environment: getEnv("ENVIRONMENT", "development"),
+			Environment: environment,
 			Port:        getEnv("SERVER_PORT", "8080"),
+			BaseURL:     getEnv("BASE_URL", "http://localhost:8080"),
 		},
 	}
 }
@@ -82,4 +362,59 @@ func getEnv(key, defaultValue string) string {
 		return value
 	}
 	return defaultValue
-} 
\ No newline at end of file
+}
+
+// loadOIDCProviders reads the providers listed in OIDC_PROVIDERS, each
+// configured by OIDC_<NAME>_* variables
+func loadOIDCProviders() []OIDCProviderConfig {
+	var providers []OIDCProviderConfig
+	for _, name := range getEnvList("OIDC_PROVIDERS", "") {
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		autoProvision, _ := strconv.ParseBool(getEnv(prefix+"AUTO_PROVISION", "false"))
+		linkByEmail, _ := strconv.ParseBool(getEnv(prefix+"LINK_BY_EMAIL", "true"))
+		trustEmail, _ := strconv.ParseBool(getEnv(prefix+"TRUST_EMAIL", "false"))
+
+		providers = append(providers, OIDCProviderConfig{
+			Name:           strings.ToLower(name),
+			DisplayName:    getEnv(prefix+"DISPLAY_NAME", name),
+			Issuer:         getEnv(prefix+"ISSUER", ""),
+			ClientID:       getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret:   getEnv(prefix+"CLIENT_SECRET", ""),
+			Scopes:         getEnvList(prefix+"SCOPES", "openid,email,profile"),
+			AutoProvision:  autoProvision,
+			LinkByEmail:    linkByEmail,
+			TrustEmail:     trustEmail,
+			AllowedDomains: getEnvList(prefix+"ALLOWED_DOMAINS", ""),
+		})
+	}
+	return providers
+}
+
+// loadLDAPGroupRoles reads LDAP_GROUP_ROLES, a semicolon-separated list of
+// "group DN:role" pairs
+func loadLDAPGroupRoles() map[string]string {
+	groupRoles := make(map[string]string)
+	for _, pair := range strings.Split(getEnv("LDAP_GROUP_ROLES", ""), ";") {
+		separator := strings.LastIndex(pair, ":")
+		if separator < 0 {
+			continue
+		}
+		groupDN := strings.TrimSpace(pair[:separator])
+		role := strings.TrimSpace(pair[separator+1:])
+		if groupDN != "" && role != "" {
+			groupRoles[groupDN] = role
+		}
+	}
+	return groupRoles
+}
+
+// getEnvList gets a comma-separated environment variable as a list
+func getEnvList(key, defaultValue string) []string {
+	var values []string
+	for _, value := range strings.Split(getEnv(key, defaultValue), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
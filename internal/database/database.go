@@ -33,27 +33,312 @@ func Init(cfg config.DatabaseConfig) (*gorm.DB, error) {
 	return db, nil
 }
 
+// Migrate runs the migrations on a database opened elsewhere, such as the
+// test database
+func Migrate(db *gorm.DB) error {
+	return runMigrations(db)
+}
+
 // runMigrations runs database migrations
 func runMigrations(db *gorm.DB) error {
+	// Convert access tokens stored in plaintext before the schema changes
+	if err := migrateAccessTokenHashes(db); err != nil {
+		return err
+	}
+
+	// Accounts created before email verification existed count as verified
+	if err := migrateEmailVerification(db); err != nil {
+		return err
+	}
+
+	// Tasks that exist before organizations are introduced move into the
+	// default organization
+	if err := migrateTaskOrganizations(db); err != nil {
+		return err
+	}
+
+	// Tasks that exist before ownership is introduced get an owner
+	if err := migrateTaskOwners(db); err != nil {
+		return err
+	}
+
+	// Tasks completed before workflows are introduced get a completion time
+	if err := migrateTaskCompletion(db); err != nil {
+		return err
+	}
+
+	// Users that exist before roles are introduced become members
+	backfillRoles := db.Migrator().HasTable("users") && !db.Migrator().HasTable("user_roles")
+
+	// Users that exist before organizations are introduced join the default
+	// organization
+	backfillMembers := db.Migrator().HasTable("users") && !db.Migrator().HasTable("organization_members")
+
 	// Auto migrate all models
 	err := db.AutoMigrate(
+		&models.Permission{},
+		&models.Role{},
 		&models.User{},
 		&models.Task{},
 		&models.AuthorizationCode{},
 		&models.AccessToken{},
+		&models.SSOSession{},
+		&models.PasswordResetToken{},
+		&models.MFACredential{},
+		&models.RecoveryCode{},
+		&models.MFAChallenge{},
+		&models.LoginThrottle{},
+		&models.AuditEvent{},
+		&models.PersonalAccessToken{},
+		&models.FederatedIdentity{},
+		&models.FederatedLoginState{},
+		&models.Group{},
+		&models.Organization{},
+		&models.OrganizationMember{},
+		&models.TaskShare{},
+		&models.TaskLink{},
+		&models.Project{},
+		&models.ProjectMember{},
+		&models.Workflow{},
 	)
 	if err != nil {
 		return err
 	}
 
+	if err := seedRoles(db); err != nil {
+		return err
+	}
+
+	if backfillRoles {
+		if err := db.Exec(`INSERT INTO user_roles (user_id, role_id)
+			SELECT users.id, roles.id FROM users, roles WHERE roles.name = ?`, models.RoleMember).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := seedDefaultOrganization(db); err != nil {
+		return err
+	}
+
+	// Admins keep full access to the existing tasks, everyone else is a member
+	if backfillMembers {
+		if err := db.Exec(`INSERT INTO organization_members (org_id, user_id, role_id, created_at)
+			SELECT organizations.id, users.id, roles.id, NOW() FROM users, organizations, roles
+			WHERE organizations.slug = ? AND roles.name = CASE WHEN EXISTS (
+				SELECT 1 FROM user_roles JOIN roles admin ON admin.id = user_roles.role_id
+				WHERE user_roles.user_id = users.id AND admin.name = ?) THEN ? ELSE ? END`,
+			models.DefaultOrganizationSlug, models.RoleAdmin, models.RoleAdmin, models.RoleMember).Error; err != nil {
+			return err
+		}
+	}
+
 	// Create indexes for better performance
 	if err := createIndexes(db); err != nil {
 		return err
 	}
 
+	// Let Postgres enforce tenant isolation on tenant data
+	if err := enableRowLevelSecurity(db); err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// migrateAccessTokenHashes replaces the plaintext token column of
+// access_tokens with a jti and a SHA-256 hash of the token. Tokens issued
+// before jti was introduced do not carry one, so their hash doubles as jti.
+func migrateAccessTokenHashes(db *gorm.DB) error {
+	if !db.Migrator().HasTable("access_tokens") || !db.Migrator().HasColumn("access_tokens", "token") {
+		return nil
+	}
+
+	log.Println("Migrating access tokens to hashed storage")
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		statements := []string{
+			"ALTER TABLE access_tokens ADD COLUMN IF NOT EXISTS jti VARCHAR(64)",
+			"ALTER TABLE access_tokens ADD COLUMN IF NOT EXISTS token_hash VARCHAR(64)",
+			"UPDATE access_tokens SET token_hash = encode(sha256(convert_to(token, 'UTF8')), 'hex') WHERE token_hash IS NULL",
+			"UPDATE access_tokens SET jti = token_hash WHERE jti IS NULL",
+			"ALTER TABLE access_tokens ALTER COLUMN jti SET NOT NULL",
+			"ALTER TABLE access_tokens ALTER COLUMN token_hash SET NOT NULL",
+			"DROP INDEX IF EXISTS idx_access_tokens_token",
+			"ALTER TABLE access_tokens DROP COLUMN token",
+		}
+
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// migrateEmailVerification adds users.email_verified_at and marks existing
+// accounts as verified so that enabling verification does not lock them out
+func migrateEmailVerification(db *gorm.DB) error {
+	if !db.Migrator().HasTable("users") || db.Migrator().HasColumn("users", "email_verified_at") {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMPTZ").Error; err != nil {
+			return err
+		}
+		return tx.Exec("UPDATE users SET email_verified_at = created_at").Error
+	})
+}
+
+// migrateTaskOrganizations adds tasks.org_id and assigns the existing tasks
+// to the default organization, so the column can be NOT NULL
+func migrateTaskOrganizations(db *gorm.DB) error {
+	if !db.Migrator().HasTable("tasks") || db.Migrator().HasColumn("tasks", "org_id") {
+		return nil
+	}
+
+	log.Println("Moving existing tasks into the default organization")
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&models.Organization{}); err != nil {
+			return err
+		}
+		if err := seedDefaultOrganization(tx); err != nil {
+			return err
+		}
+
+		statements := []string{
+			"ALTER TABLE tasks ADD COLUMN org_id UUID",
+			"UPDATE tasks SET org_id = (SELECT id FROM organizations WHERE slug = '" + models.DefaultOrganizationSlug + "')",
+			"ALTER TABLE tasks ALTER COLUMN org_id SET NOT NULL",
+		}
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// migrateTaskOwners adds tasks.owner_id and tasks.created_by. Existing tasks
+// are given to an admin of their organization, or another member, so the
+// owner can be NOT NULL; their creator is unknown and left empty.
+func migrateTaskOwners(db *gorm.DB) error {
+	if !db.Migrator().HasTable("tasks") || db.Migrator().HasColumn("tasks", "owner_id") {
+		return nil
+	}
+
+	log.Println("Assigning owners to existing tasks")
+
+	// Before organizations existed every user joins the default organization
+	owner := `SELECT organization_members.user_id FROM organization_members
+		JOIN roles ON roles.id = organization_members.role_id
+		WHERE organization_members.org_id = tasks.org_id
+		ORDER BY roles.name = '` + models.RoleAdmin + `' DESC, organization_members.created_at
+		LIMIT 1`
+	if !db.Migrator().HasTable("organization_members") {
+		owner = `SELECT users.id FROM users ORDER BY users.created_at LIMIT 1`
+		// Before roles existed there are no admins to prefer
+		if db.Migrator().HasTable("user_roles") {
+			owner = `SELECT users.id FROM users
+				ORDER BY EXISTS (SELECT 1 FROM user_roles JOIN roles ON roles.id = user_roles.role_id
+					WHERE user_roles.user_id = users.id AND roles.name = '` + models.RoleAdmin + `') DESC, users.created_at
+				LIMIT 1`
+		}
+	}
+
+	return withoutRowSecurity(db, "tasks", func(tx *gorm.DB) error {
+		if err := tx.Exec("ALTER TABLE tasks ADD COLUMN owner_id UUID, ADD COLUMN created_by UUID").Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("UPDATE tasks SET owner_id = (" + owner + ")").Error; err != nil {
+			return err
+		}
+
+		// Without any user the tasks cannot be reached anyway
+		result := tx.Exec("DELETE FROM tasks WHERE owner_id IS NULL")
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			log.Printf("Deleted %d task(s) without a possible owner", result.RowsAffected)
+		}
+
+		return tx.Exec("ALTER TABLE tasks ALTER COLUMN owner_id SET NOT NULL").Error
+	})
+}
+
+// migrateTaskCompletion adds tasks.completed_at. Existing tasks in a
+// terminal status of the default workflow count as completed when they were
+// last updated.
+func migrateTaskCompletion(db *gorm.DB) error {
+	if !db.Migrator().HasTable("tasks") || db.Migrator().HasColumn("tasks", "completed_at") {
+		return nil
+	}
+
+	var terminal []string
+	for _, status := range models.DefaultWorkflow().Statuses {
+		if status.Terminal {
+			terminal = append(terminal, status.Name)
+		}
+	}
+
+	return withoutRowSecurity(db, "tasks", func(tx *gorm.DB) error {
+		if err := tx.Exec("ALTER TABLE tasks ADD COLUMN completed_at TIMESTAMPTZ").Error; err != nil {
+			return err
+		}
+		return tx.Exec("UPDATE tasks SET completed_at = updated_at WHERE status IN ?", terminal).Error
+	})
+}
+
+// seedDefaultOrganization creates the default organization
+func seedDefaultOrganization(db *gorm.DB) error {
+	org := models.Organization{Slug: models.DefaultOrganizationSlug}
+	return db.Where(models.Organization{Slug: models.DefaultOrganizationSlug}).
+		Attrs(models.Organization{Name: "Default"}).
+		FirstOrCreate(&org).Error
+}
+
+// seedRoles creates the known permissions and the built-in roles, and
+// resets the permissions of built-in roles to their definition
+func seedRoles(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		permissions := make(map[string]models.Permission)
+		for name, description := range models.BuiltinPermissions {
+			permission := models.Permission{Name: name}
+			if err := tx.Where(models.Permission{Name: name}).
+				Attrs(models.Permission{Description: description}).
+				FirstOrCreate(&permission).Error; err != nil {
+				return err
+			}
+			permissions[name] = permission
+		}
+
+		for _, builtin := range models.BuiltinRoles {
+			role := models.Role{Name: builtin.Name}
+			if err := tx.Where(models.Role{Name: builtin.Name}).
+				Attrs(models.Role{Description: builtin.Description, Builtin: true}).
+				FirstOrCreate(&role).Error; err != nil {
+				return err
+			}
+
+			granted := make([]models.Permission, len(builtin.Permissions))
+			for i, name := range builtin.Permissions {
+				granted[i] = permissions[name]
+			}
+			if err := tx.Model(&role).Association("Permissions").Replace(granted); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
 // createIndexes creates database indexes for better performance
 func createIndexes(db *gorm.DB) error {
 	// User indexes
@@ -77,13 +362,188 @@ func createIndexes(db *gorm.DB) error {
 		return err
 	}
 
-	// Access token indexes
-	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_access_tokens_token ON access_tokens(token)").Error; err != nil {
+	// Access token indexes; jti and token_hash are indexed by their unique
+	// constraints
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_access_tokens_expires_at ON access_tokens(expires_at)").Error; err != nil {
 		return err
 	}
-	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_access_tokens_expires_at ON access_tokens(expires_at)").Error; err != nil {
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_access_tokens_user_id ON access_tokens(user_id)").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_access_tokens_revoked_at ON access_tokens(revoked_at) WHERE revoked_at IS NOT NULL").Error; err != nil {
+		return err
+	}
+
+	// SSO session indexes
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_sso_sessions_user_id ON sso_sessions(user_id)").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_sso_sessions_expires_at ON sso_sessions(expires_at)").Error; err != nil {
+		return err
+	}
+
+	// Password reset token indexes
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user_id ON password_reset_tokens(user_id)").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_expires_at ON password_reset_tokens(expires_at)").Error; err != nil {
+		return err
+	}
+
+	// MFA indexes
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_recovery_codes_user_id ON recovery_codes(user_id)").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_mfa_challenges_expires_at ON mfa_challenges(expires_at)").Error; err != nil {
+		return err
+	}
+
+	// Audit indexes
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_audit_events_created_at ON audit_events(created_at)").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_audit_events_event ON audit_events(event)").Error; err != nil {
+		return err
+	}
+
+	// Role indexes
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_user_roles_role_id ON user_roles(role_id)").Error; err != nil {
+		return err
+	}
+
+	// Personal access token indexes
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_personal_access_tokens_user_id ON personal_access_tokens(user_id)").Error; err != nil {
+		return err
+	}
+
+	// Federated identity indexes
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_federated_identities_user_id ON federated_identities(user_id)").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_federated_login_states_expires_at ON federated_login_states(expires_at)").Error; err != nil {
+		return err
+	}
+
+	// Provisioning indexes
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_users_external_id ON users(external_id) WHERE external_id <> ''").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_group_members_user_id ON group_members(user_id)").Error; err != nil {
+		return err
+	}
+
+	// Organization indexes
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_tasks_org_id_created_at ON tasks(org_id, created_at)").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_organization_members_user_id ON organization_members(user_id)").Error; err != nil {
+		return err
+	}
+
+	// Task ownership indexes
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_tasks_org_id_owner_id_created_at ON tasks(org_id, owner_id, created_at)").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_task_shares_user_id ON task_shares(user_id)").Error; err != nil {
+		return err
+	}
+
+	// Project indexes
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_tasks_project_id_created_at ON tasks(project_id, created_at) WHERE project_id IS NOT NULL").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_project_members_user_id ON project_members(user_id)").Error; err != nil {
+		return err
+	}
+
+	// Task date indexes
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_tasks_org_id_due_at ON tasks(org_id, due_at) WHERE due_at IS NOT NULL").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_tasks_org_id_start_at ON tasks(org_id, start_at) WHERE start_at IS NOT NULL").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_tasks_org_id_completed_at ON tasks(org_id, completed_at) WHERE completed_at IS NOT NULL").Error; err != nil {
+		return err
+	}
+
+	// Workflow indexes; an organization and a project have one workflow each
+	if err := db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_workflows_org_id ON workflows(org_id) WHERE project_id IS NULL").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_workflows_project_id ON workflows(project_id) WHERE project_id IS NOT NULL").Error; err != nil {
 		return err
 	}
 
 	return nil
-} 
\ No newline at end of file
+} 
+
+// tenantTables lists the tables holding organization data. Rows can only
+// be read or written inside a transaction whose app.current_org_id setting
+// names their organization and whose app.current_user_id setting names a
+// member of it.
+var tenantTables = []string{"tasks", "task_shares", "task_links", "projects", "project_members", "workflows"}
+
+// enableRowLevelSecurity installs the tenant isolation policy on the tenant
+// tables. The policy is forced so it also applies to the table owner, which
+// is usually the role the application connects as.
+func enableRowLevelSecurity(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		functions := []string{
+			`CREATE OR REPLACE FUNCTION app_current_user_id() RETURNS UUID LANGUAGE sql STABLE AS
+				$$ SELECT NULLIF(current_setting('app.current_user_id', true), '')::uuid $$`,
+			`CREATE OR REPLACE FUNCTION app_current_org_id() RETURNS UUID LANGUAGE sql STABLE AS
+				$$ SELECT NULLIF(current_setting('app.current_org_id', true), '')::uuid $$`,
+		}
+		for _, statement := range functions {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, table := range tenantTables {
+			statements := []string{
+				"ALTER TABLE " + table + " ENABLE ROW LEVEL SECURITY",
+				"ALTER TABLE " + table + " FORCE ROW LEVEL SECURITY",
+				"DROP POLICY IF EXISTS tenant_isolation ON " + table,
+				`CREATE POLICY tenant_isolation ON ` + table + `
+					USING (org_id = app_current_org_id() AND EXISTS (
+						SELECT 1 FROM organization_members
+						WHERE organization_members.org_id = app_current_org_id()
+						AND organization_members.user_id = app_current_user_id()))`,
+			}
+			for _, statement := range statements {
+				if err := tx.Exec(statement).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		var bypass bool
+		if err := tx.Raw("SELECT rolsuper OR rolbypassrls FROM pg_roles WHERE rolname = current_user").
+			Scan(&bypass).Error; err != nil {
+			return err
+		}
+		if bypass {
+			log.Println("Warning: the database role bypasses row-level security; connect as a role without SUPERUSER and BYPASSRLS to enforce tenant isolation")
+		}
+
+		return nil
+	})
+}
+
+// withoutRowSecurity runs fn in a transaction in which the row-level
+// security policy of the table no longer applies to its owner, so data
+// migrations see every row. ALTER TABLE locks the table until the
+// transaction ends, so other sessions never see the policy lifted.
+func withoutRowSecurity(db *gorm.DB, table string, fn func(tx *gorm.DB) error) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("ALTER TABLE " + table + " NO FORCE ROW LEVEL SECURITY").Error; err != nil {
+			return err
+		}
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return tx.Exec("ALTER TABLE " + table + " FORCE ROW LEVEL SECURITY").Error
+	})
+}
@@ -0,0 +1,267 @@
+// Package directorytest provides an in-process LDAP server for tests. It
+// understands simple binds and searches with the filters used by directory
+// clients: and, or, not, equality and presence.
+package directorytest
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// Server is an LDAP server holding entries in memory
+type Server struct {
+	// URL is the ldap:// URL the server listens on
+	URL string
+
+	listener net.Listener
+	mu       sync.Mutex
+	entries  map[string]*entry
+	order    []string
+}
+
+type entry struct {
+	dn         string
+	password   string
+	attributes map[string][]string
+}
+
+// NewServer starts a server on a local port and stops it when the test ends
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	s := &Server{
+		URL:      "ldap://" + listener.Addr().String(),
+		listener: listener,
+		entries:  make(map[string]*entry),
+	}
+	go s.serve()
+	t.Cleanup(func() { listener.Close() })
+
+	return s
+}
+
+// AddEntry adds or replaces an entry. An entry without password cannot
+// bind.
+func (s *Server) AddEntry(dn, password string, attributes map[string][]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := strings.ToLower(dn)
+	if _, ok := s.entries[key]; !ok {
+		s.order = append(s.order, key)
+	}
+	copied := make(map[string][]string, len(attributes))
+	for name, values := range attributes {
+		copied[name] = append([]string(nil), values...)
+	}
+	s.entries[key] = &entry{dn: dn, password: password, attributes: copied}
+}
+
+// SetAttribute replaces the values of an attribute of an entry
+func (s *Server) SetAttribute(dn, name string, values ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[strings.ToLower(dn)]; ok {
+		for existing := range e.attributes {
+			if strings.EqualFold(existing, name) {
+				delete(e.attributes, existing)
+			}
+		}
+		e.attributes[name] = values
+	}
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		messageID, _ := packet.Children[0].Value.(int64)
+		request := packet.Children[1]
+
+		switch request.Tag {
+		case ldap.ApplicationBindRequest:
+			code := s.bind(request)
+			if err := writeResult(conn, messageID, ldap.ApplicationBindResponse, code); err != nil {
+				return
+			}
+		case ldap.ApplicationSearchRequest:
+			if err := s.search(conn, messageID, request); err != nil {
+				return
+			}
+		default:
+			// Unbind and anything not supported end the connection
+			return
+		}
+	}
+}
+
+func (s *Server) bind(request *ber.Packet) uint16 {
+	if len(request.Children) < 3 {
+		return ldap.LDAPResultProtocolError
+	}
+	dn := stringValue(request.Children[1])
+	password := stringValue(request.Children[2])
+	if dn == "" && password == "" {
+		return ldap.LDAPResultSuccess
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[strings.ToLower(dn)]
+	if !ok || e.password == "" || e.password != password {
+		return ldap.LDAPResultInvalidCredentials
+	}
+	return ldap.LDAPResultSuccess
+}
+
+func (s *Server) search(conn net.Conn, messageID int64, request *ber.Packet) error {
+	if len(request.Children) < 7 {
+		return writeResult(conn, messageID, ldap.ApplicationSearchResultDone, ldap.LDAPResultProtocolError)
+	}
+	baseDN := strings.ToLower(stringValue(request.Children[0]))
+	sizeLimit, _ := request.Children[3].Value.(int64)
+	filter := request.Children[6]
+
+	s.mu.Lock()
+	var matches []*entry
+	for _, key := range s.order {
+		e := s.entries[key]
+		if (key == baseDN || strings.HasSuffix(key, ","+baseDN)) && matchFilter(e, filter) {
+			matches = append(matches, e)
+		}
+	}
+	s.mu.Unlock()
+
+	code := uint16(ldap.LDAPResultSuccess)
+	if sizeLimit > 0 && int64(len(matches)) > sizeLimit {
+		matches = matches[:sizeLimit]
+		code = ldap.LDAPResultSizeLimitExceeded
+	}
+
+	for _, e := range matches {
+		if err := writeEntry(conn, messageID, e); err != nil {
+			return err
+		}
+	}
+	return writeResult(conn, messageID, ldap.ApplicationSearchResultDone, code)
+}
+
+func matchFilter(e *entry, filter *ber.Packet) bool {
+	switch filter.Tag {
+	case ldap.FilterAnd:
+		for _, child := range filter.Children {
+			if !matchFilter(e, child) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, child := range filter.Children {
+			if matchFilter(e, child) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterNot:
+		return len(filter.Children) == 1 && !matchFilter(e, filter.Children[0])
+	case ldap.FilterEqualityMatch:
+		if len(filter.Children) != 2 {
+			return false
+		}
+		name := stringValue(filter.Children[0])
+		value := stringValue(filter.Children[1])
+		for _, v := range e.values(name) {
+			if strings.EqualFold(v, value) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterPresent:
+		return len(e.values(stringValue(filter))) > 0
+	default:
+		return false
+	}
+}
+
+// values returns the values of an attribute, whose name is case
+// insensitive
+func (e *entry) values(name string) []string {
+	for existing, values := range e.attributes {
+		if strings.EqualFold(existing, name) {
+			return values
+		}
+	}
+	return nil
+}
+
+func writeEntry(conn net.Conn, messageID int64, e *entry) error {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.dn, "Object Name"))
+
+	attributes := ber.NewSequence("Attributes")
+	for name, values := range e.attributes {
+		attribute := ber.NewSequence("Attribute")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		for _, value := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "Value"))
+		}
+		attribute.AppendChild(set)
+		attributes.AppendChild(attribute)
+	}
+	op.AppendChild(attributes)
+
+	return writeMessage(conn, messageID, op)
+}
+
+func writeResult(conn net.Conn, messageID int64, tag ber.Tag, code uint16) error {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "Result Code"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, ldap.LDAPResultCodeMap[code], "Diagnostic Message"))
+
+	return writeMessage(conn, messageID, op)
+}
+
+func writeMessage(conn net.Conn, messageID int64, op *ber.Packet) error {
+	packet := ber.NewSequence("LDAP Response")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "Message ID"))
+	packet.AppendChild(op)
+
+	_, err := conn.Write(packet.Bytes())
+	return err
+}
+
+// stringValue returns the content of a primitive element, whatever its
+// class
+func stringValue(p *ber.Packet) string {
+	if p.Data == nil {
+		return ""
+	}
+	return p.Data.String()
+}
@@ -0,0 +1,173 @@
+package directory
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+
+	"ishare-task-api/internal/config"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// ErrInvalidCredentials is returned when the directory does not know the
+// user or rejects the password
+var ErrInvalidCredentials = errors.New("invalid directory credentials")
+
+// Entry is the directory account of an authenticated user
+type Entry struct {
+	DN          string
+	Email       string
+	DisplayName string
+	Groups      []string
+}
+
+// Client authenticates users against an LDAP or Active Directory server.
+// Every authentication uses its own connection.
+type Client struct {
+	cfg       config.LDAPConfig
+	tlsConfig *tls.Config
+}
+
+// NewClient creates a client for the configured server
+func NewClient(cfg config.LDAPConfig) (*Client, error) {
+	if cfg.URL == "" || cfg.BaseDN == "" {
+		return nil, errors.New("LDAP authentication needs LDAP_URL and LDAP_BASE_DN")
+	}
+	if !strings.Contains(cfg.UserFilter, "%s") {
+		return nil, errors.New("LDAP_USER_FILTER must contain %s for the email")
+	}
+
+	serverURL, err := url.Parse(cfg.URL)
+	if err != nil || (serverURL.Scheme != "ldap" && serverURL.Scheme != "ldaps") {
+		return nil, fmt.Errorf("invalid LDAP_URL %q", cfg.URL)
+	}
+	if serverURL.Scheme == "ldaps" && cfg.StartTLS {
+		return nil, errors.New("LDAP_START_TLS cannot be used with an ldaps:// URL")
+	}
+
+	host := serverURL.Host
+	if h, _, err := net.SplitHostPort(serverURL.Host); err == nil {
+		host = h
+	}
+	tlsConfig := &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read LDAP CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("LDAP CA file contains no certificates")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return &Client{
+		cfg:       cfg,
+		tlsConfig: tlsConfig,
+	}, nil
+}
+
+// Authenticate finds the user by email and binds as the user with the
+// password
+func (c *Client) Authenticate(email, password string) (*Entry, error) {
+	// An empty password would be an unauthenticated bind, which many
+	// servers accept for any DN
+	if email == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if c.cfg.BindDN != "" {
+		if err := conn.Bind(c.cfg.BindDN, c.cfg.BindPassword); err != nil {
+			return nil, fmt.Errorf("LDAP service account bind failed: %w", err)
+		}
+	}
+
+	entry, err := c.findUser(conn, email)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("LDAP bind failed: %w", err)
+	}
+
+	return entry, nil
+}
+
+// findUser searches for the single entry matching the user filter
+func (c *Client) findUser(conn *ldap.Conn, email string) (*Entry, error) {
+	request := ldap.NewSearchRequest(
+		c.cfg.BaseDN,
+		ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases,
+		2, // more than one match is rejected
+		int(c.cfg.Timeout.Seconds()),
+		false,
+		strings.ReplaceAll(c.cfg.UserFilter, "%s", ldap.EscapeFilter(email)),
+		[]string{c.cfg.EmailAttribute, c.cfg.NameAttribute, c.cfg.GroupAttribute},
+		nil,
+	)
+
+	result, err := conn.Search(request)
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("LDAP search failed: %w", err)
+	}
+	if len(result.Entries) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+
+	found := result.Entries[0]
+	entry := &Entry{
+		DN:          found.DN,
+		Email:       found.GetAttributeValue(c.cfg.EmailAttribute),
+		DisplayName: found.GetAttributeValue(c.cfg.NameAttribute),
+		Groups:      found.GetAttributeValues(c.cfg.GroupAttribute),
+	}
+	if entry.Email == "" {
+		return nil, fmt.Errorf("LDAP entry %s has no %s attribute", entry.DN, c.cfg.EmailAttribute)
+	}
+
+	return entry, nil
+}
+
+// dial connects to the server, upgrading to TLS when configured
+func (c *Client) dial() (*ldap.Conn, error) {
+	dialer := &net.Dialer{Timeout: c.cfg.Timeout}
+	conn, err := ldap.DialURL(c.cfg.URL, ldap.DialWithDialer(dialer), ldap.DialWithTLSConfig(c.tlsConfig))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to LDAP server: %w", err)
+	}
+	conn.SetTimeout(c.cfg.Timeout)
+
+	if c.cfg.StartTLS {
+		if err := conn.StartTLS(c.tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("LDAP StartTLS failed: %w", err)
+		}
+	}
+
+	return conn, nil
+}
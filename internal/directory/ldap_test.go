@@ -0,0 +1,123 @@
+package directory_test
+
+import (
+	"testing"
+	"time"
+
+	"ishare-task-api/internal/config"
+	"ishare-task-api/internal/directory"
+	"ishare-task-api/internal/directory/directorytest"
+)
+
+const (
+	baseDN    = "ou=people,dc=example,dc=com"
+	serviceDN = "cn=service,dc=example,dc=com"
+	adminsDN  = "cn=admins,ou=groups,dc=example,dc=com"
+)
+
+func newDirectory(t *testing.T) (*directorytest.Server, *directory.Client) {
+	t.Helper()
+
+	server := directorytest.NewServer(t)
+	server.AddEntry(serviceDN, "service-secret", nil)
+	server.AddEntry("uid=alice,"+baseDN, "alice-secret", map[string][]string{
+		"objectClass": {"person"},
+		"mail":        {"alice@example.com"},
+		"cn":          {"Alice Example"},
+		"memberOf":    {adminsDN},
+	})
+
+	client, err := directory.NewClient(config.LDAPConfig{
+		URL:            server.URL,
+		Timeout:        5 * time.Second,
+		BindDN:         serviceDN,
+		BindPassword:   "service-secret",
+		BaseDN:         baseDN,
+		UserFilter:     "(&(objectClass=person)(mail=%s))",
+		EmailAttribute: "mail",
+		NameAttribute:  "cn",
+		GroupAttribute: "memberOf",
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	return server, client
+}
+
+func TestAuthenticate(t *testing.T) {
+	_, client := newDirectory(t)
+
+	entry, err := client.Authenticate("alice@example.com", "alice-secret")
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if entry.DN != "uid=alice,"+baseDN {
+		t.Errorf("DN = %q", entry.DN)
+	}
+	if entry.Email != "alice@example.com" || entry.DisplayName != "Alice Example" {
+		t.Errorf("entry = %+v", entry)
+	}
+	if len(entry.Groups) != 1 || entry.Groups[0] != adminsDN {
+		t.Errorf("Groups = %v", entry.Groups)
+	}
+}
+
+func TestAuthenticateRejectsInvalidCredentials(t *testing.T) {
+	server, client := newDirectory(t)
+	server.AddEntry("uid=alice2,"+baseDN, "other-secret", map[string][]string{
+		"objectClass": {"person"},
+		"mail":        {"shared@example.com"},
+	})
+	server.AddEntry("uid=bob,"+baseDN, "bob-secret", map[string][]string{
+		"objectClass": {"person"},
+		"mail":        {"shared@example.com"},
+	})
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+	}{
+		{"wrong password", "alice@example.com", "wrong"},
+		{"empty password", "alice@example.com", ""},
+		{"unknown user", "nobody@example.com", "alice-secret"},
+		{"filter injection", "*", "alice-secret"},
+		{"several matching entries", "shared@example.com", "bob-secret"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := client.Authenticate(tt.email, tt.password)
+			if err != directory.ErrInvalidCredentials {
+				t.Fatalf("Authenticate = %+v, %v; want ErrInvalidCredentials", entry, err)
+			}
+		})
+	}
+}
+
+func TestAuthenticateServiceAccountFailure(t *testing.T) {
+	server, client := newDirectory(t)
+	server.AddEntry(serviceDN, "rotated-secret", nil)
+
+	_, err := client.Authenticate("alice@example.com", "alice-secret")
+	if err == nil || err == directory.ErrInvalidCredentials {
+		t.Fatalf("Authenticate error = %v; want a service account error", err)
+	}
+}
+
+func TestAuthenticateUnreachableServer(t *testing.T) {
+	client, err := directory.NewClient(config.LDAPConfig{
+		URL:        "ldap://127.0.0.1:1",
+		Timeout:    time.Second,
+		BaseDN:     baseDN,
+		UserFilter: "(mail=%s)",
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	_, err = client.Authenticate("alice@example.com", "alice-secret")
+	if err == nil || err == directory.ErrInvalidCredentials {
+		t.Fatalf("Authenticate error = %v; want a connection error", err)
+	}
+}
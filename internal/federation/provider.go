@@ -0,0 +1,268 @@
+package federation
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"ishare-task-api/internal/config"
+)
+
+// ErrUnknownProvider is returned for provider names that are not configured
+var ErrUnknownProvider = errors.New("unknown identity provider")
+
+// Provider is an upstream OpenID Connect provider. Its discovery document
+// is fetched on first use, so the API starts even when the provider is
+// unreachable.
+type Provider struct {
+	Config config.OIDCProviderConfig
+
+	client *http.Client
+
+	mu       sync.Mutex
+	metadata *providerMetadata
+	keys     *keySet
+}
+
+// providerMetadata holds the fields of the discovery document in use
+type providerMetadata struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	CodeChallengeMethods  []string `json:"code_challenge_methods_supported"`
+}
+
+// TokenResponse is the upstream token endpoint response
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+}
+
+// Registry holds the configured providers in configuration order
+type Registry struct {
+	providers []*Provider
+}
+
+// NewRegistry creates providers for the configuration. A nil client uses a
+// client with a 10 second timeout.
+func NewRegistry(cfg config.FederationConfig, client *http.Client) (*Registry, error) {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	registry := &Registry{}
+	seen := make(map[string]bool)
+	for _, providerConfig := range cfg.Providers {
+		if providerConfig.Issuer == "" || providerConfig.ClientID == "" {
+			return nil, fmt.Errorf("identity provider %s needs an issuer and a client ID", providerConfig.Name)
+		}
+		if seen[providerConfig.Name] {
+			return nil, fmt.Errorf("identity provider %s is configured twice", providerConfig.Name)
+		}
+		seen[providerConfig.Name] = true
+
+		registry.providers = append(registry.providers, &Provider{
+			Config: providerConfig,
+			client: client,
+		})
+	}
+
+	return registry, nil
+}
+
+// Providers returns the configured providers
+func (r *Registry) Providers() []*Provider {
+	return r.providers
+}
+
+// Get returns the provider with the given name
+func (r *Registry) Get(name string) (*Provider, error) {
+	for _, provider := range r.providers {
+		if provider.Config.Name == name {
+			return provider, nil
+		}
+	}
+	return nil, ErrUnknownProvider
+}
+
+// AuthCodeURL returns the upstream authorization URL for an authorization
+// code request protected by state, nonce and a PKCE S256 code challenge
+func (p *Provider) AuthCodeURL(ctx context.Context, redirectURI, state, nonce, codeChallenge string) (string, error) {
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.Config.ClientID},
+		"redirect_uri":          {redirectURI},
+		"scope":                 {strings.Join(p.scopes(), " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {codeChallenge},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return metadata.AuthorizationEndpoint + separator + params.Encode(), nil
+}
+
+// Exchange redeems an upstream authorization code together with the PKCE
+// code verifier
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier, redirectURI string) (*TokenResponse, error) {
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {codeVerifier},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.Config.ClientID), url.QueryEscape(p.Config.ClientSecret))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request to %s failed: %w", p.Config.Name, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token request to %s failed with status %d: %s", p.Config.Name, resp.StatusCode, body)
+	}
+
+	var token TokenResponse
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, fmt.Errorf("invalid token response from %s: %w", p.Config.Name, err)
+	}
+	if token.IDToken == "" {
+		return nil, fmt.Errorf("token response from %s has no id_token", p.Config.Name)
+	}
+
+	return &token, nil
+}
+
+// AllowsEmail reports whether the email's domain may sign in with the
+// provider
+func (p *Provider) AllowsEmail(email string) bool {
+	if len(p.Config.AllowedDomains) == 0 {
+		return true
+	}
+
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := email[at+1:]
+	for _, allowed := range p.Config.AllowedDomains {
+		if strings.EqualFold(domain, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// scopes returns the requested scopes, always including openid
+func (p *Provider) scopes() []string {
+	for _, scope := range p.Config.Scopes {
+		if scope == "openid" {
+			return p.Config.Scopes
+		}
+	}
+	return append([]string{"openid"}, p.Config.Scopes...)
+}
+
+// discover fetches and caches the provider's discovery document
+func (p *Provider) discover(ctx context.Context) (*providerMetadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	wellKnown := strings.TrimSuffix(p.Config.Issuer, "/") + "/.well-known/openid-configuration"
+	var metadata providerMetadata
+	if err := p.getJSON(ctx, wellKnown, &metadata); err != nil {
+		return nil, err
+	}
+
+	if metadata.Issuer != p.Config.Issuer {
+		return nil, fmt.Errorf("identity provider %s reports issuer %q, expected %q", p.Config.Name, metadata.Issuer, p.Config.Issuer)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, fmt.Errorf("discovery document of %s lacks required endpoints", p.Config.Name)
+	}
+	if len(metadata.CodeChallengeMethods) > 0 && !containsString(metadata.CodeChallengeMethods, "S256") {
+		return nil, fmt.Errorf("identity provider %s does not support PKCE S256", p.Config.Name)
+	}
+
+	p.metadata = &metadata
+	p.keys = newKeySet(p, metadata.JWKSURI)
+	return p.metadata, nil
+}
+
+// getJSON fetches a JSON document
+func (p *Provider) getJSON(ctx context.Context, target string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request to %s failed: %w", target, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("request to %s failed with status %d", target, resp.StatusCode)
+	}
+
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// CodeChallenge returns the PKCE S256 code challenge for a verifier
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// containsString reports whether a slice contains the value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,172 @@
+package federation_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+	"time"
+
+	"ishare-task-api/internal/config"
+	"ishare-task-api/internal/federation"
+	"ishare-task-api/internal/federation/federationtest"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const callbackURL = "https://api.example.com/oauth/federated/test/callback"
+
+func newProvider(t *testing.T, idp *federationtest.IdP) *federation.Provider {
+	t.Helper()
+
+	registry, err := federation.NewRegistry(config.FederationConfig{
+		Providers: []config.OIDCProviderConfig{idp.Config("test")},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewRegistry: %v", err)
+	}
+	provider, err := registry.Get("test")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	return provider
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	ctx := context.Background()
+	idp := federationtest.NewIdP(t, "api-client", "api-secret")
+	idp.SetUser(jwt.MapClaims{
+		"sub":            "alice",
+		"email":          "alice@example.com",
+		"email_verified": true,
+		"name":           "Alice Example",
+		"amr":            []string{"pwd", "mfa"},
+	})
+	provider := newProvider(t, idp)
+
+	verifier := "verifier-with-enough-entropy-for-the-test"
+	authURL, err := provider.AuthCodeURL(ctx, callbackURL, "state-1", "nonce-1", federation.CodeChallenge(verifier))
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+
+	callback := federationtest.Authorize(t, authURL)
+	if callback.Query().Get("state") != "state-1" {
+		t.Fatalf("callback state = %q; want state-1", callback.Query().Get("state"))
+	}
+	code := callback.Query().Get("code")
+
+	token, err := provider.Exchange(ctx, code, verifier, callbackURL)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	claims, err := provider.VerifyIDToken(ctx, token.IDToken, "nonce-1")
+	if err != nil {
+		t.Fatalf("VerifyIDToken: %v", err)
+	}
+	if claims.Subject != "alice" || claims.Email != "alice@example.com" || !claims.EmailVerified ||
+		claims.Name != "Alice Example" || len(claims.AMR) != 2 {
+		t.Errorf("claims = %+v", claims)
+	}
+
+	// Codes are single use
+	if _, err := provider.Exchange(ctx, code, verifier, callbackURL); err == nil {
+		t.Error("second Exchange of the same code succeeded")
+	}
+}
+
+func TestExchangeRequiresCodeVerifier(t *testing.T) {
+	ctx := context.Background()
+	idp := federationtest.NewIdP(t, "api-client", "api-secret")
+	provider := newProvider(t, idp)
+
+	authURL, err := provider.AuthCodeURL(ctx, callbackURL, "state-1", "nonce-1", federation.CodeChallenge("right-verifier"))
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	code := federationtest.Authorize(t, authURL).Query().Get("code")
+
+	if _, err := provider.Exchange(ctx, code, "wrong-verifier", callbackURL); err == nil {
+		t.Fatal("Exchange with a wrong code verifier succeeded")
+	}
+}
+
+func TestVerifyIDTokenRejects(t *testing.T) {
+	ctx := context.Background()
+	idp := federationtest.NewIdP(t, "api-client", "api-secret")
+	provider := newProvider(t, idp)
+	user := jwt.MapClaims{"sub": "alice"}
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	with := func(name string, value interface{}) string {
+		claims := idp.Claims(user, "nonce-1")
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+		return idp.IDToken(claims)
+	}
+
+	tests := []struct {
+		name    string
+		idToken string
+	}{
+		{"wrong nonce", with("nonce", "nonce-2")},
+		{"missing nonce", with("nonce", nil)},
+		{"wrong audience", with("aud", "other-client")},
+		{"wrong issuer", with("iss", "https://evil.example.com")},
+		{"expired", with("exp", time.Now().Add(-time.Hour).Unix())},
+		{"missing expiry", with("exp", nil)},
+		{"issued in the future", with("iat", time.Now().Add(time.Hour).Unix())},
+		{"missing subject", with("sub", nil)},
+		{"other authorized party", with("azp", "other-client")},
+		{"unknown key", federationtest.SignIDToken(otherKey, "key-2", idp.Claims(user, "nonce-1"))},
+		{"wrong signature", federationtest.SignIDToken(otherKey, "key-1", idp.Claims(user, "nonce-1"))},
+		{"unsigned", unsignedToken(t, idp.Claims(user, "nonce-1"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := provider.VerifyIDToken(ctx, tt.idToken, "nonce-1")
+			if !errors.Is(err, federation.ErrInvalidIDToken) {
+				t.Fatalf("VerifyIDToken = %+v, %v; want ErrInvalidIDToken", claims, err)
+			}
+		})
+	}
+
+	// The same claims unaltered are accepted
+	if _, err := provider.VerifyIDToken(ctx, idp.IDToken(idp.Claims(user, "nonce-1")), "nonce-1"); err != nil {
+		t.Fatalf("VerifyIDToken of a valid token: %v", err)
+	}
+}
+
+func TestDiscoveryRejectsIssuerMismatch(t *testing.T) {
+	idp := federationtest.NewIdP(t, "api-client", "api-secret")
+	cfg := idp.Config("test")
+	cfg.Issuer = idp.Issuer + "/"
+
+	registry, err := federation.NewRegistry(config.FederationConfig{Providers: []config.OIDCProviderConfig{cfg}}, nil)
+	if err != nil {
+		t.Fatalf("NewRegistry: %v", err)
+	}
+	provider, _ := registry.Get("test")
+
+	if _, err := provider.AuthCodeURL(context.Background(), callbackURL, "state", "nonce", "challenge"); err == nil {
+		t.Fatal("AuthCodeURL succeeded with a discovery document for another issuer")
+	}
+}
+
+func unsignedToken(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+	return token
+}
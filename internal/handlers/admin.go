@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"ishare-task-api/internal/auth"
+	"ishare-task-api/internal/config"
+	"ishare-task-api/internal/mail"
+	"ishare-task-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminHandler handles administrative requests
+type AdminHandler struct {
+	oauth  *auth.OAuthManager
+	rbac   *auth.RBAC
+	users  *auth.UserCache
+	cfg    *config.Config
+	mailer mail.Mailer
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(oauth *auth.OAuthManager, rbac *auth.RBAC, users *auth.UserCache, cfg *config.Config, mailer mail.Mailer) *AdminHandler {
+	return &AdminHandler{
+		oauth:  oauth,
+		rbac:   rbac,
+		users:  users,
+		cfg:    cfg,
+		mailer: mailer,
+	}
+}
+
+// ListLockouts lists the active login lockouts
+// @Summary List Lockouts
+// @Description Lists the accounts and IP addresses currently locked out after failed login attempts
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.LockoutsResponse "Active lockouts"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Router /admin/lockouts [get]
+func (h *AdminHandler) ListLockouts(c *gin.Context) {
+	lockouts, err := h.oauth.ListLockouts()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve lockouts",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.LockoutsResponse{
+		Lockouts: lockouts,
+	})
+}
+
+// Unlock lifts a login lockout
+// @Summary Unlock Account or IP Address
+// @Description Lifts the lockout and clears failed login attempts for an email and/or IP address
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.UnlockRequest true "Email and/or IP address to unlock"
+// @Success 200 {object} map[string]interface{} "Lockout lifted"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Router /admin/lockouts/unlock [post]
+func (h *AdminHandler) Unlock(c *gin.Context) {
+	admin, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+		})
+		return
+	}
+
+	var req models.UnlockRequest
+	if err := c.ShouldBindJSON(&req); err != nil || (req.Email == "" && req.IPAddress == "") {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "An email or ip_address is required",
+		})
+		return
+	}
+
+	cleared, err := h.oauth.Unlock(req.Email, req.IPAddress, admin.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to lift lockout",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Lockout lifted",
+		"cleared": cleared,
+	})
+}
+
+// ListAuditEvents lists recent audit events
+// @Summary List Audit Events
+// @Description Lists the most recent security audit events
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param event query string false "Filter by event name" example(account.locked)
+// @Param limit query int false "Maximum number of events" example(50)
+// @Success 200 {object} models.AuditEventsResponse "Audit events"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Router /admin/audit [get]
+func (h *AdminHandler) ListAuditEvents(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 500 {
+		limit = 50
+	}
+
+	events, err := h.oauth.ListAuditEvents(c.Query("event"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve audit events",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.AuditEventsResponse{
+		Events: events,
+	})
+}
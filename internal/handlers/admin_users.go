@@ -0,0 +1,303 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"ishare-task-api/internal/auth"
+	"ishare-task-api/internal/mail"
+	"ishare-task-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ListUsers lists user accounts
+// @Summary List Users
+// @Description Lists user accounts with optional search on email and display name, newest first
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param q query string false "Search term" example(example.com)
+// @Param page query int false "Page number" example(1)
+// @Param limit query int false "Items per page" example(20)
+// @Success 200 {object} models.UsersResponse "Users"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Router /admin/users [get]
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	users, total, err := h.oauth.ListUsers(c.Query("q"), (page-1)*limit, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve users",
+		})
+		return
+	}
+
+	userResponses := make([]models.UserResponse, len(users))
+	for i := range users {
+		userResponses[i] = userResponse(&users[i])
+	}
+
+	c.JSON(http.StatusOK, models.UsersResponse{
+		Users: userResponses,
+		Total: total,
+	})
+}
+
+// GetUser returns a user account
+// @Summary Get User
+// @Description Returns a user account with its roles
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID" format(uuid)
+// @Success 200 {object} models.UserResponse "User"
+// @Failure 400 {object} map[string]interface{} "Invalid user ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "User not found"
+// @Router /admin/users/{id} [get]
+func (h *AdminHandler) GetUser(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.oauth.FindUser(userID)
+	if err != nil {
+		h.respondUserError(c, err, "Failed to retrieve user")
+		return
+	}
+
+	c.JSON(http.StatusOK, userResponse(user))
+}
+
+// DisableUser disables a user account
+// @Summary Disable User
+// @Description Blocks the account from signing in and revokes all of its tokens and sessions immediately
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID" format(uuid)
+// @Success 200 {object} models.UserResponse "User disabled"
+// @Failure 400 {object} map[string]interface{} "Invalid user ID or own account"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "User not found"
+// @Router /admin/users/{id}/disable [post]
+func (h *AdminHandler) DisableUser(c *gin.Context) {
+	admin, userID, ok := h.adminAndUserID(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.oauth.DisableUser(userID, admin.ID, c.ClientIP())
+	if err != nil {
+		h.respondUserError(c, err, "Failed to disable user")
+		return
+	}
+	h.users.Invalidate(userID)
+
+	c.JSON(http.StatusOK, userResponse(user))
+}
+
+// EnableUser enables a disabled user account
+// @Summary Enable User
+// @Description Lets a disabled account sign in again
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID" format(uuid)
+// @Success 200 {object} models.UserResponse "User enabled"
+// @Failure 400 {object} map[string]interface{} "Invalid user ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "User not found"
+// @Router /admin/users/{id}/enable [post]
+func (h *AdminHandler) EnableUser(c *gin.Context) {
+	admin, userID, ok := h.adminAndUserID(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.oauth.EnableUser(userID, admin.ID, c.ClientIP())
+	if err != nil {
+		h.respondUserError(c, err, "Failed to enable user")
+		return
+	}
+	h.users.Invalidate(userID)
+
+	c.JSON(http.StatusOK, userResponse(user))
+}
+
+// ForcePasswordReset forces a user to choose a new password
+// @Summary Force Password Reset
+// @Description Invalidates the user's password, signs the user out everywhere and emails a password reset link
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID" format(uuid)
+// @Success 200 {object} map[string]interface{} "Password reset forced"
+// @Failure 400 {object} map[string]interface{} "Invalid user ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "User not found"
+// @Router /admin/users/{id}/password-reset [post]
+func (h *AdminHandler) ForcePasswordReset(c *gin.Context) {
+	admin, userID, ok := h.adminAndUserID(c)
+	if !ok {
+		return
+	}
+
+	token, user, err := h.oauth.ForcePasswordReset(userID, admin.ID, c.ClientIP())
+	if err != nil {
+		h.respondUserError(c, err, "Failed to reset password")
+		return
+	}
+	h.users.Invalidate(userID)
+
+	link := h.cfg.Server.BaseURL + "/oauth/password/reset?token=" + url.QueryEscape(token)
+	if err := h.mailer.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Choose a new password",
+		Body: fmt.Sprintf("An administrator has reset the password of your account.\n\n"+
+			"Open the following link to choose a new password:\n%s\n\n"+
+			"The link expires in %s and can be used once.\n",
+			link, h.cfg.OAuth.PasswordResetTTL),
+	}); err != nil {
+		log.Printf("Failed to send password reset email: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Password reset, a reset link has been sent to the user",
+	})
+}
+
+// RevokeUserTokens revokes all tokens of a user
+// @Summary Revoke User Tokens
+// @Description Revokes all access tokens, SSO sessions and pending authorization codes of a user
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID" format(uuid)
+// @Success 200 {object} map[string]interface{} "Tokens revoked"
+// @Failure 400 {object} map[string]interface{} "Invalid user ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "User not found"
+// @Router /admin/users/{id}/revoke-tokens [post]
+func (h *AdminHandler) RevokeUserTokens(c *gin.Context) {
+	admin, userID, ok := h.adminAndUserID(c)
+	if !ok {
+		return
+	}
+
+	revoked, err := h.oauth.RevokeUserTokens(userID, admin.ID, c.ClientIP())
+	if err != nil {
+		h.respondUserError(c, err, "Failed to revoke tokens")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tokens revoked",
+		"revoked": revoked,
+	})
+}
+
+// DeleteUser deletes a user account
+// @Summary Delete User
+// @Description Permanently deletes a user account right away, without a grace period
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID" format(uuid)
+// @Success 200 {object} map[string]interface{} "User deleted"
+// @Failure 400 {object} map[string]interface{} "Invalid user ID or own account"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "User not found"
+// @Failure 409 {object} map[string]interface{} "Last admin"
+// @Router /admin/users/{id} [delete]
+func (h *AdminHandler) DeleteUser(c *gin.Context) {
+	admin, userID, ok := h.adminAndUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.oauth.DeleteUser(userID, admin.ID, c.ClientIP()); err != nil {
+		h.respondUserError(c, err, "Failed to delete user")
+		return
+	}
+	h.users.Invalidate(userID)
+	h.rbac.Invalidate(userID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User deleted",
+	})
+}
+
+// adminAndUserID returns the acting admin and the user ID from the path
+func (h *AdminHandler) adminAndUserID(c *gin.Context) (*models.User, uuid.UUID, bool) {
+	admin, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+		})
+		return nil, uuid.Nil, false
+	}
+
+	userID, ok := parseUserID(c)
+	if !ok {
+		return nil, uuid.Nil, false
+	}
+
+	return admin, userID, true
+}
+
+// parseUserID parses the user ID path parameter
+func parseUserID(c *gin.Context) (uuid.UUID, bool) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid user ID format",
+		})
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// respondUserError maps user administration errors to responses
+func (h *AdminHandler) respondUserError(c *gin.Context, err error, message string) {
+	switch err {
+	case auth.ErrUserNotFound:
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "User not found",
+		})
+	case auth.ErrSelfAction:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "This action cannot be applied to your own account",
+		})
+	case auth.ErrLastAdmin:
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "Cannot delete the last admin",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": message,
+		})
+	}
+}
@@ -2,26 +2,37 @@ package handlers
 
 import (
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"ishare-task-api/internal/auth"
 	"ishare-task-api/internal/config"
+	"ishare-task-api/internal/federation"
+	"ishare-task-api/internal/mail"
 	"ishare-task-api/internal/models"
+	"ishare-task-api/internal/password"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 // AuthHandler handles authentication requests
 type AuthHandler struct {
-	oauth *auth.OAuthManager
-	cfg   *config.Config
+	oauth     *auth.OAuthManager
+	cfg       *config.Config
+	mailer    mail.Mailer
+	providers *federation.Registry
 }
 
 // NewAuthHandler creates a new authentication handler
-func NewAuthHandler(oauth *auth.OAuthManager, cfg *config.Config) *AuthHandler {
+func NewAuthHandler(oauth *auth.OAuthManager, cfg *config.Config, mailer mail.Mailer, providers *federation.Registry) *AuthHandler {
 	return &AuthHandler{
-		oauth: oauth,
-		cfg:   cfg,
+		oauth:     oauth,
+		cfg:       cfg,
+		mailer:    mailer,
+		providers: providers,
 	}
 }
 
@@ -36,7 +47,10 @@ func NewAuthHandler(oauth *auth.OAuthManager, cfg *config.Config) *AuthHandler {
 // @Param redirect_uri formData string true "Redirect URI" example(http://localhost:8080/oauth/callback)
 // @Param scope formData string false "Requested scopes" example(tasks:read tasks:write)
 // @Param state formData string false "State parameter for CSRF protection" example(random-state)
+// @Param prompt formData string false "'login' forces re-authentication, 'none' fails instead of showing the login page" example(login)
+// @Param max_age formData int false "Maximum seconds since the user last authenticated" example(3600)
 // @Success 200 {string} string "Authorization page"
+// @Success 302 {string} string "Redirect to callback when an SSO session exists"
 // @Failure 400 {object} map[string]interface{} "Bad request"
 // @Router /oauth/authorize [get]
 func (h *AuthHandler) Authorize(c *gin.Context) {
@@ -72,6 +86,59 @@ func (h *AuthHandler) Authorize(c *gin.Context) {
 		return
 	}
 
+	// Validate prompt and max_age
+	if req.Prompt != "" && req.Prompt != "login" && req.Prompt != "none" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "prompt must be 'login' or 'none'",
+		})
+		return
+	}
+
+	maxAge := -1
+	if req.MaxAge != "" {
+		parsed, err := strconv.Atoi(req.MaxAge)
+		if err != nil || parsed < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "max_age must be a non-negative integer",
+			})
+			return
+		}
+		maxAge = parsed
+	}
+
+	// Skip the login form when the browser already has a fresh SSO session
+	if req.Prompt != "login" {
+		if session := h.currentSSOSession(c); session != nil &&
+			(maxAge < 0 || time.Since(session.AuthTime) <= time.Duration(maxAge)*time.Second) {
+			authCode, err := h.oauth.CreateAuthorizationCode(session.UserID, req.ClientID, req.Scope, auth.SessionInfo{
+				IPAddress: c.ClientIP(),
+				UserAgent: c.GetHeader("User-Agent"),
+				AMR:       strings.Fields(session.AMR),
+			})
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error": "Failed to create authorization code",
+				})
+				return
+			}
+
+			c.Redirect(http.StatusFound, buildRedirectURL(req.RedirectURI, url.Values{
+				"code":  {authCode.Code},
+				"state": {req.State},
+			}))
+			return
+		}
+	}
+
+	// prompt=none must not show any UI
+	if req.Prompt == "none" {
+		c.Redirect(http.StatusFound, buildRedirectURL(req.RedirectURI, url.Values{
+			"error": {"login_required"},
+			"state": {req.State},
+		}))
+		return
+	}
+
 	// For demo purposes, we'll show a simple login form
 	// In a real application, you might redirect to a proper login page
 	c.HTML(http.StatusOK, "authorize.html", gin.H{
@@ -79,6 +146,7 @@ func (h *AuthHandler) Authorize(c *gin.Context) {
 		"redirect_uri": req.RedirectURI,
 		"scope":        req.Scope,
 		"state":        req.State,
+		"providers":    h.providerLinks(req),
 	})
 }
 
@@ -94,9 +162,13 @@ func (h *AuthHandler) Authorize(c *gin.Context) {
 // @Param redirect_uri formData string true "Redirect URI" example(http://localhost:8080/oauth/callback)
 // @Param scope formData string false "Requested scopes" example(tasks:read tasks:write)
 // @Param state formData string false "State parameter" example(random-state)
+// @Success 200 {object} map[string]interface{} "Multi-factor authentication required"
 // @Success 302 {string} string "Redirect to callback with authorization code"
 // @Failure 400 {object} map[string]interface{} "Bad request"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Email address not verified or account disabled"
+// @Failure 423 {object} map[string]interface{} "Account or IP address temporarily locked"
+// @Failure 429 {object} map[string]interface{} "Too many failed attempts, retry later"
 // @Router /oauth/login [post]
 func (h *AuthHandler) Login(c *gin.Context) {
 	email := c.PostForm("email")
@@ -114,16 +186,175 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	}
 
 	// Authenticate user
-	user, err := h.oauth.AuthenticateUser(email, password)
+	user, err := h.oauth.AuthenticateUser(email, password, c.ClientIP())
 	if err != nil {
+		if throttled, ok := err.(*auth.LoginThrottledError); ok {
+			respondLoginThrottled(c, throttled)
+			return
+		}
+		if err == auth.ErrEmailNotVerified {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Email address not verified",
+				"hint":  "Follow the link in the verification email or request a new one at POST /oauth/verify-email/resend",
+			})
+			return
+		}
+		if err == auth.ErrUserDisabled {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Account is disabled",
+			})
+			return
+		}
+		if err == auth.ErrDirectoryLocalUnverified {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": err.Error(),
+				"hint":  "Verify the email address of the existing account before signing in with the directory password",
+			})
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error": "Invalid credentials",
 		})
 		return
 	}
 
+	h.completeFirstFactor(c, user.ID, clientID, redirectURI, scope, state, []string{auth.AMRPassword})
+}
+
+// completeFirstFactor continues a login after the first factor: users with
+// MFA enabled must complete a second step first, everyone else is signed in
+func (h *AuthHandler) completeFirstFactor(c *gin.Context, userID uuid.UUID, clientID, redirectURI, scope, state string, amr []string) {
+	mfaEnabled, err := h.oauth.MFAEnabled(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to check multi-factor authentication",
+		})
+		return
+	}
+	if mfaEnabled {
+		mfaToken, err := h.oauth.CreateMFAChallenge(userID, clientID, redirectURI, scope, state, amr)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to create multi-factor challenge",
+			})
+			return
+		}
+
+		if isAPIClient(c) {
+			c.JSON(http.StatusOK, gin.H{
+				"message":      "Multi-factor authentication required",
+				"mfa_required": true,
+				"mfa_token":    mfaToken,
+				"next_step":    "Submit the authenticator or recovery code with POST /oauth/login/mfa",
+			})
+			return
+		}
+
+		c.HTML(http.StatusOK, "mfa.html", gin.H{
+			"mfa_token": mfaToken,
+		})
+		return
+	}
+
+	h.completeLogin(c, userID, clientID, redirectURI, scope, state, amr)
+}
+
+// respondLoginThrottled reports a lockout (423) or backoff (429) together
+// with the time until the next attempt is allowed
+func respondLoginThrottled(c *gin.Context, throttled *auth.LoginThrottledError) {
+	retryAfter := throttled.RetryAfterSeconds()
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+
+	if throttled.Locked {
+		c.JSON(http.StatusLocked, gin.H{
+			"error":       "Too many failed login attempts, sign-in is temporarily locked",
+			"locked":      true,
+			"retry_after": retryAfter,
+		})
+		return
+	}
+
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       "Too many failed login attempts, please wait before retrying",
+		"locked":      false,
+		"retry_after": retryAfter,
+	})
+}
+
+// LoginMFA handles the second factor of the login step
+// @Summary Multi-Factor Login Step
+// @Description Verifies a TOTP or recovery code for a pending login and creates the authorization code
+// @Tags OAuth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param mfa_token formData string true "Challenge token returned by /oauth/login"
+// @Param code formData string true "Authenticator or recovery code" example(123456)
+// @Success 302 {string} string "Redirect to callback with authorization code"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Invalid code or challenge"
+// @Failure 423 {object} map[string]interface{} "Account or IP address temporarily locked"
+// @Failure 429 {object} map[string]interface{} "Too many failed attempts, retry later"
+// @Router /oauth/login/mfa [post]
+func (h *AuthHandler) LoginMFA(c *gin.Context) {
+	mfaToken := c.PostForm("mfa_token")
+	code := c.PostForm("code")
+
+	if mfaToken == "" || code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "mfa_token and code are required",
+		})
+		return
+	}
+
+	challenge, amr, err := h.oauth.CompleteMFAChallenge(mfaToken, code, c.ClientIP())
+	if err != nil {
+		if throttled, ok := err.(*auth.LoginThrottledError); ok {
+			respondLoginThrottled(c, throttled)
+			return
+		}
+		if err == auth.ErrInvalidMFACode {
+			if isAPIClient(c) {
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"error": "Invalid authentication code",
+				})
+				return
+			}
+			c.HTML(http.StatusUnauthorized, "mfa.html", gin.H{
+				"mfa_token": mfaToken,
+				"error":     "Invalid authentication code",
+			})
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Invalid or expired multi-factor challenge, please sign in again",
+		})
+		return
+	}
+
+	h.completeLogin(c, challenge.UserID, challenge.ClientID, challenge.RedirectURI, challenge.Scope, challenge.State, amr)
+}
+
+// completeLogin establishes the SSO session for an authenticated user and
+// issues the authorization code
+func (h *AuthHandler) completeLogin(c *gin.Context, userID uuid.UUID, clientID, redirectURI, scope, state string, amr []string) {
+	sessionInfo := auth.SessionInfo{
+		IPAddress: c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+		AMR:       amr,
+	}
+
+	// Establish a browser SSO session so later authorize requests skip the form
+	ssoToken, _, err := h.oauth.CreateSSOSession(userID, h.cfg.Session.TTL, sessionInfo)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to create session",
+		})
+		return
+	}
+	h.setSSOCookie(c, ssoToken, int(h.cfg.Session.TTL.Seconds()))
+
 	// Create authorization code
-	authCode, err := h.oauth.CreateAuthorizationCode(user.ID, clientID, scope)
+	authCode, err := h.oauth.CreateAuthorizationCode(userID, clientID, scope, sessionInfo)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -133,9 +364,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	}
 
 	// Check if this is an API call (for testing) or browser redirect
-	// If User-Agent contains curl or similar, return JSON response
-	userAgent := c.GetHeader("User-Agent")
-	if userAgent == "" || contains(userAgent, "curl") || contains(userAgent, "test") {
+	if isAPIClient(c) {
 		// Return JSON response for API calls
 		c.JSON(http.StatusOK, gin.H{
 			"message":      "Login successful",
@@ -147,12 +376,82 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	}
 
 	// Redirect to callback with authorization code for browser requests
-	redirectURL := redirectURI + "?code=" + authCode.Code
-	if state != "" {
-		redirectURL += "&state=" + state
+	c.Redirect(http.StatusFound, buildRedirectURL(redirectURI, url.Values{
+		"code":  {authCode.Code},
+		"state": {state},
+	}))
+}
+
+// isAPIClient reports whether the request comes from a script rather than a
+// browser: if User-Agent contains curl or similar, JSON is returned
+func isAPIClient(c *gin.Context) bool {
+	userAgent := c.GetHeader("User-Agent")
+	return userAgent == "" || contains(userAgent, "curl") || contains(userAgent, "test")
+}
+
+// Logout ends the browser SSO session
+// @Summary Logout
+// @Description Ends the browser SSO session so the next authorization request asks for credentials again
+// @Tags OAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Logged out"
+// @Router /oauth/logout [post]
+func (h *AuthHandler) Logout(c *gin.Context) {
+	if token, err := c.Cookie(h.cfg.Session.CookieName); err == nil && token != "" {
+		if err := h.oauth.DeleteSSOSession(token); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to end session",
+			})
+			return
+		}
 	}
 
-	c.Redirect(http.StatusFound, redirectURL)
+	h.setSSOCookie(c, "", -1)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Logged out successfully",
+	})
+}
+
+// currentSSOSession returns the SSO session referenced by the request cookie
+func (h *AuthHandler) currentSSOSession(c *gin.Context) *models.SSOSession {
+	token, err := c.Cookie(h.cfg.Session.CookieName)
+	if err != nil || token == "" {
+		return nil
+	}
+
+	session, err := h.oauth.GetSSOSession(token)
+	if err != nil {
+		return nil
+	}
+
+	return session
+}
+
+// setSSOCookie writes the HttpOnly SSO session cookie; a negative maxAge
+// removes it
+func (h *AuthHandler) setSSOCookie(c *gin.Context, value string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(h.cfg.Session.CookieName, value, maxAge, "/oauth", "", h.cfg.Session.CookieSecure, true)
+}
+
+// buildRedirectURL appends the non-empty parameters to the redirect URI
+func buildRedirectURL(redirectURI string, params url.Values) string {
+	query := url.Values{}
+	for key, values := range params {
+		for _, value := range values {
+			if value != "" {
+				query.Add(key, value)
+			}
+		}
+	}
+
+	separator := "?"
+	if strings.Contains(redirectURI, "?") {
+		separator = "&"
+	}
+
+	return redirectURI + separator + query.Encode()
 }
 
 // Helper function to check if string contains substring
@@ -205,7 +504,13 @@ func (h *AuthHandler) Token(c *gin.Context) {
 	}
 
 	// Create access token
-	accessToken, err := h.oauth.CreateAccessToken(authCode.UserID, req.ClientID, authCode.Scope)
+	// The session is attributed to the browser that signed in, not to the
+	// client exchanging the code
+	tokenString, accessToken, err := h.oauth.CreateAccessToken(authCode.UserID, uuid.Nil, req.ClientID, authCode.Scope, auth.SessionInfo{
+		IPAddress: authCode.IPAddress,
+		UserAgent: authCode.UserAgent,
+		AMR:       strings.Fields(authCode.AMR),
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to create access token",
@@ -215,13 +520,56 @@ func (h *AuthHandler) Token(c *gin.Context) {
 
 	// Return token response
 	c.JSON(http.StatusOK, auth.TokenResponse{
-		AccessToken: accessToken.Token,
+		AccessToken: tokenString,
 		TokenType:   "Bearer",
 		ExpiresIn:   int64(24 * 60 * 60), // 24 hours in seconds
 		Scope:       accessToken.Scope,
 	})
 }
 
+// Revoke handles OAuth 2.0 token revocation endpoint
+// @Summary OAuth 2.0 Token Revocation
+// @Description Revokes an access token (RFC 7009)
+// @Tags OAuth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param token formData string true "Access token to revoke"
+// @Param token_type_hint formData string false "Token type hint" example(access_token)
+// @Param client_id formData string true "OAuth client ID" example(test-client)
+// @Param client_secret formData string true "OAuth client secret" example(test-secret)
+// @Success 200 {object} map[string]interface{} "Token revoked"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Router /oauth/revoke [post]
+func (h *AuthHandler) Revoke(c *gin.Context) {
+	var req auth.RevocationRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request parameters",
+		})
+		return
+	}
+
+	// Validate client credentials
+	if req.ClientID != h.cfg.OAuth.ClientID || req.ClientSecret != h.cfg.OAuth.ClientSecret {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Invalid client credentials",
+		})
+		return
+	}
+
+	if err := h.oauth.RevokeAccessToken(req.Token); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to revoke token",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Token revoked",
+	})
+}
+
 // Callback handles OAuth callback
 // @Summary OAuth Callback
 // @Description Handles OAuth callback with authorization code
@@ -256,13 +604,13 @@ func (h *AuthHandler) Callback(c *gin.Context) {
 
 // Register handles user registration
 // @Summary User Registration
-// @Description Creates a new user account
+// @Description Creates a new, unverified user account and sends an email verification link
 // @Tags OAuth
 // @Accept json
 // @Produce json
 // @Param user body models.CreateUserRequest true "User registration data"
 // @Success 201 {object} models.UserResponse "User created successfully"
-// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 400 {object} map[string]interface{} "Bad request or password rejected by the policy"
 // @Failure 409 {object} map[string]interface{} "User already exists"
 // @Router /oauth/register [post]
 func (h *AuthHandler) Register(c *gin.Context) {
@@ -277,19 +625,24 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	// Create user
 	user, err := h.oauth.CreateUser(req.Email, req.Password)
 	if err != nil {
+		if policyErr, ok := err.(*password.PolicyError); ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":      "Password does not meet the password policy",
+				"violations": policyErr.Violations,
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to create user",
 		})
 		return
 	}
 
+	// Send the verification link; the account starts unverified
+	h.sendVerificationEmail(user)
+
 	// Return user response (without password)
-	c.JSON(http.StatusCreated, models.UserResponse{
-		ID:        user.ID,
-		Email:     user.Email,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
-	})
+	c.JSON(http.StatusCreated, userResponse(user))
 }
 
 // CleanupTokens handles cleanup of expired tokens
@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"ishare-task-api/internal/auth"
+	"ishare-task-api/internal/federation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// federatedStateCookie binds an upstream sign-in to the browser that
+// started it, so a state captured elsewhere cannot be replayed
+const federatedStateCookie = "ishare_oidc_state"
+
+// FederatedLogin starts a sign-in at an upstream identity provider
+// @Summary Federated Sign-In
+// @Description Redirects to an upstream OpenID Connect provider using the authorization code flow with PKCE. After the provider signs the user in, the local authorization request continues with the same client_id, redirect_uri, scope and state.
+// @Tags OAuth
+// @Produce html
+// @Param provider path string true "Identity provider name" example(corp)
+// @Param client_id query string true "OAuth client ID" example(test-client)
+// @Param redirect_uri query string true "Redirect URI" example(http://localhost:8080/oauth/callback)
+// @Param scope query string false "Requested scopes" example(tasks:read tasks:write)
+// @Param state query string false "State parameter for CSRF protection" example(random-state)
+// @Success 302 {string} string "Redirect to the identity provider"
+// @Failure 400 {object} map[string]interface{} "Invalid client_id or redirect_uri"
+// @Failure 404 {object} map[string]interface{} "Unknown identity provider"
+// @Failure 502 {object} map[string]interface{} "Identity provider unavailable"
+// @Router /oauth/federated/{provider} [get]
+func (h *AuthHandler) FederatedLogin(c *gin.Context) {
+	provider, err := h.providers.Get(c.Param("provider"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Unknown identity provider",
+		})
+		return
+	}
+
+	clientID := c.Query("client_id")
+	redirectURI := c.Query("redirect_uri")
+
+	// Validate client_id and redirect_uri as the authorize endpoint does
+	if clientID != h.cfg.OAuth.ClientID {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid client_id",
+		})
+		return
+	}
+	if redirectURI != h.cfg.OAuth.RedirectURI {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid redirect_uri",
+		})
+		return
+	}
+
+	stateToken, loginState, err := h.oauth.BeginFederatedLogin(provider.Config.Name, clientID, redirectURI,
+		c.Query("scope"), c.Query("state"), h.cfg.Federation.StateTTL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to start sign-in",
+		})
+		return
+	}
+
+	authURL, err := provider.AuthCodeURL(c.Request.Context(), h.federatedCallbackURL(provider),
+		stateToken, loginState.Nonce, federation.CodeChallenge(loginState.CodeVerifier))
+	if err != nil {
+		log.Printf("Identity provider %s unavailable: %v", provider.Config.Name, err)
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error": "Identity provider is unavailable",
+		})
+		return
+	}
+
+	h.setFederatedStateCookie(c, stateToken, int(h.cfg.Federation.StateTTL.Seconds()))
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// FederatedCallback completes a sign-in at an upstream identity provider
+// @Summary Federated Sign-In Callback
+// @Description Receives the upstream authorization code, redeems it with the PKCE code verifier and validates the id_token. The identity is linked to the account with the same verified email or, if the provider allows it, a new account is provisioned. The local authorization request then continues as after a password login: users with MFA enabled complete the local second factor unless the id_token's amr claim contains mfa.
+// @Tags OAuth
+// @Produce json
+// @Param provider path string true "Identity provider name" example(corp)
+// @Param code query string false "Upstream authorization code"
+// @Param state query string true "State issued when the sign-in started"
+// @Param error query string false "Error reported by the identity provider"
+// @Success 200 {object} map[string]interface{} "Login successful (API clients)"
+// @Success 302 {string} string "Redirect to callback with authorization code"
+// @Failure 400 {object} map[string]interface{} "Invalid or expired state, or sign-in cancelled"
+// @Failure 401 {object} map[string]interface{} "Invalid id_token"
+// @Failure 403 {object} map[string]interface{} "Account disabled, not linked or email not allowed"
+// @Failure 404 {object} map[string]interface{} "Unknown identity provider"
+// @Failure 502 {object} map[string]interface{} "Identity provider unavailable"
+// @Router /oauth/federated/{provider}/callback [get]
+func (h *AuthHandler) FederatedCallback(c *gin.Context) {
+	provider, err := h.providers.Get(c.Param("provider"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Unknown identity provider",
+		})
+		return
+	}
+
+	stateToken := c.Query("state")
+	cookie, _ := c.Cookie(federatedStateCookie)
+	h.setFederatedStateCookie(c, "", -1)
+	if stateToken == "" || cookie != stateToken {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": auth.ErrInvalidFederatedState.Error(),
+		})
+		return
+	}
+
+	loginState, err := h.oauth.ConsumeFederatedLoginState(provider.Config.Name, stateToken)
+	if err != nil {
+		if err == auth.ErrInvalidFederatedState {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to complete sign-in",
+		})
+		return
+	}
+
+	// The provider reports denied or failed sign-ins as an error parameter
+	if upstreamError := c.Query("error"); upstreamError != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             "Sign-in at the identity provider failed",
+			"provider_error":    upstreamError,
+			"error_description": c.Query("error_description"),
+		})
+		return
+	}
+
+	code := c.Query("code")
+	if code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Authorization code is required",
+		})
+		return
+	}
+
+	token, err := provider.Exchange(c.Request.Context(), code, loginState.CodeVerifier, h.federatedCallbackURL(provider))
+	if err != nil {
+		log.Printf("Identity provider %s token exchange failed: %v", provider.Config.Name, err)
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error": "Failed to redeem the authorization code at the identity provider",
+		})
+		return
+	}
+
+	claims, err := provider.VerifyIDToken(c.Request.Context(), token.IDToken, loginState.Nonce)
+	if err != nil {
+		if errors.Is(err, federation.ErrInvalidIDToken) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Invalid id_token from the identity provider",
+			})
+			return
+		}
+		log.Printf("Identity provider %s id_token validation failed: %v", provider.Config.Name, err)
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error": "Identity provider is unavailable",
+		})
+		return
+	}
+
+	user, err := h.oauth.FederatedLogin(provider, claims, c.ClientIP())
+	if err != nil {
+		switch err {
+		case auth.ErrUserDisabled:
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Account is disabled",
+			})
+		case auth.ErrFederatedEmailUnverified, auth.ErrFederatedDomainNotAllowed,
+			auth.ErrFederatedAccountNotFound, auth.ErrFederatedLocalUnverified:
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to complete sign-in",
+			})
+		}
+		return
+	}
+
+	// A multi-factor sign-in at the provider stands in for the local second
+	// factor; otherwise users with MFA enabled complete it here
+	if auth.UpstreamMFA(claims) {
+		h.completeLogin(c, user.ID, loginState.ClientID, loginState.RedirectURI, loginState.Scope, loginState.State, auth.FederatedAMR(claims))
+		return
+	}
+	h.completeFirstFactor(c, user.ID, loginState.ClientID, loginState.RedirectURI, loginState.Scope, loginState.State, auth.FederatedAMR(claims))
+}
+
+// providerLinks returns the "Sign in with" links shown on the login page
+func (h *AuthHandler) providerLinks(req auth.AuthorizationRequest) []gin.H {
+	providers := h.providers.Providers()
+	links := make([]gin.H, 0, len(providers))
+	for _, provider := range providers {
+		query := url.Values{
+			"client_id":    {req.ClientID},
+			"redirect_uri": {req.RedirectURI},
+		}
+		if req.Scope != "" {
+			query.Set("scope", req.Scope)
+		}
+		if req.State != "" {
+			query.Set("state", req.State)
+		}
+
+		links = append(links, gin.H{
+			"name":         provider.Config.Name,
+			"display_name": provider.Config.DisplayName,
+			"url":          "/oauth/federated/" + url.PathEscape(provider.Config.Name) + "?" + query.Encode(),
+		})
+	}
+	return links
+}
+
+// federatedCallbackURL is the redirect URI registered at the provider
+func (h *AuthHandler) federatedCallbackURL(provider *federation.Provider) string {
+	return strings.TrimSuffix(h.cfg.Server.BaseURL, "/") + "/oauth/federated/" + url.PathEscape(provider.Config.Name) + "/callback"
+}
+
+// setFederatedStateCookie writes the HttpOnly upstream sign-in state cookie;
+// a negative maxAge removes it
+func (h *AuthHandler) setFederatedStateCookie(c *gin.Context, value string, maxAge int) {
+	// Lax lets the cookie accompany the top-level redirect back from the provider
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(federatedStateCookie, value, maxAge, "/oauth/federated", "", h.cfg.Session.CookieSecure, true)
+}
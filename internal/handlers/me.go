@@ -0,0 +1,328 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"ishare-task-api/internal/auth"
+	"ishare-task-api/internal/config"
+	"ishare-task-api/internal/mail"
+	"ishare-task-api/internal/models"
+	"ishare-task-api/internal/password"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MeHandler handles self-service requests for the current user's account
+type MeHandler struct {
+	oauth  *auth.OAuthManager
+	cfg    *config.Config
+	mailer mail.Mailer
+	users  *auth.UserCache
+}
+
+// NewMeHandler creates a new account self-service handler
+func NewMeHandler(oauth *auth.OAuthManager, cfg *config.Config, mailer mail.Mailer, users *auth.UserCache) *MeHandler {
+	return &MeHandler{
+		oauth:  oauth,
+		cfg:    cfg,
+		mailer: mailer,
+		users:  users,
+	}
+}
+
+// GetMe returns the current user's account
+// @Summary Get Current User
+// @Description Returns the account of the authenticated user
+// @Tags Me
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.UserResponse "Current user"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Request made with a personal access token"
+// @Router /me [get]
+func (h *MeHandler) GetMe(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, userResponse(user))
+}
+
+// UpdateMe updates the current user's profile
+// @Summary Update Profile
+// @Description Updates display name, locale (BCP 47 tag) and timezone (IANA name) of the authenticated user
+// @Tags Me
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.UpdateProfileRequest true "Profile fields to change"
+// @Success 200 {object} models.UserResponse "Profile updated"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Request made with a personal access token"
+// @Router /me [patch]
+func (h *MeHandler) UpdateMe(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request body",
+		})
+		return
+	}
+
+	if err := h.oauth.UpdateProfile(user, req); err != nil {
+		switch err {
+		case auth.ErrInvalidLocale:
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid locale, use a BCP 47 language tag such as en-US",
+			})
+		case auth.ErrInvalidTimezone:
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid timezone, use an IANA name such as Europe/Amsterdam",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to update profile",
+			})
+		}
+		return
+	}
+	h.users.Invalidate(user.ID)
+
+	c.JSON(http.StatusOK, userResponse(user))
+}
+
+// ChangePassword changes the current user's password
+// @Summary Change Password
+// @Description Sets a new password after checking the current one and signs out all other sessions
+// @Tags Me
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.ChangePasswordRequest true "Current and new password"
+// @Success 200 {object} map[string]interface{} "Password changed"
+// @Failure 400 {object} map[string]interface{} "Bad request or password rejected by the policy"
+// @Failure 401 {object} map[string]interface{} "Unauthorized or incorrect current password"
+// @Failure 403 {object} map[string]interface{} "Request made with a personal access token"
+// @Failure 423 {object} map[string]interface{} "Too many failed attempts"
+// @Router /me/password [post]
+func (h *MeHandler) ChangePassword(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req models.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request body",
+		})
+		return
+	}
+
+	tokenID, _ := auth.GetTokenIDFromContext(c)
+	revoked, err := h.oauth.ChangePassword(user, req.CurrentPassword, req.NewPassword, tokenID, c.ClientIP())
+	if err != nil {
+		if policyErr, ok := err.(*password.PolicyError); ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":      "Password does not meet the password policy",
+				"violations": policyErr.Violations,
+			})
+			return
+		}
+		h.respondReauthError(c, err, "Failed to change password")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":          "Password changed",
+		"revoked_sessions": revoked,
+	})
+}
+
+// ChangeEmail starts a change of the current user's email address
+// @Summary Change Email
+// @Description Sends a verification link to the new address; the email changes once the link is opened. The current address is notified.
+// @Tags Me
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.ChangeEmailRequest true "New email and current password"
+// @Success 202 {object} map[string]interface{} "Verification email sent"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized or incorrect current password"
+// @Failure 403 {object} map[string]interface{} "Request made with a personal access token"
+// @Failure 409 {object} map[string]interface{} "Email already in use"
+// @Router /me/email [post]
+func (h *MeHandler) ChangeEmail(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req models.ChangeEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "A valid new_email and the current_password are required",
+		})
+		return
+	}
+
+	token, err := h.oauth.RequestEmailChange(user, req.NewEmail, req.CurrentPassword, c.ClientIP())
+	if err != nil {
+		switch err {
+		case auth.ErrSameEmail:
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "The new email address is the current one",
+			})
+		case auth.ErrEmailTaken:
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "Email address is already in use",
+			})
+		default:
+			h.respondReauthError(c, err, "Failed to change email address")
+		}
+		return
+	}
+
+	newEmail := strings.TrimSpace(req.NewEmail)
+	link := h.cfg.Server.BaseURL + "/oauth/verify-email?token=" + url.QueryEscape(token)
+	h.send(mail.Message{
+		To:      newEmail,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf("Please confirm your new email address by opening the following link:\n%s\n\n"+
+			"The link expires in %s. Until then you keep signing in with your current address.\n",
+			link, h.cfg.OAuth.EmailVerificationTTL),
+	})
+	h.send(mail.Message{
+		To:      user.Email,
+		Subject: "Email address change requested",
+		Body: fmt.Sprintf("A change of your account email address to %s was requested.\n\n"+
+			"If this was not you, change your password right away.\n", newEmail),
+	})
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "A verification link has been sent to the new email address",
+	})
+}
+
+// DeleteMe schedules deletion of the current user's account
+// @Summary Delete Account
+// @Description Schedules the account for deletion after a grace period and signs out everywhere. Signing in before the deletion date restores the account.
+// @Tags Me
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.DeleteAccountRequest true "Current password"
+// @Success 202 {object} map[string]interface{} "Deletion scheduled"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized or incorrect current password"
+// @Failure 403 {object} map[string]interface{} "Request made with a personal access token"
+// @Router /me [delete]
+func (h *MeHandler) DeleteMe(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req models.DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "The current_password is required",
+		})
+		return
+	}
+
+	deleteAt, err := h.oauth.ScheduleAccountDeletion(user, req.CurrentPassword, c.ClientIP())
+	if err != nil {
+		h.respondReauthError(c, err, "Failed to delete account")
+		return
+	}
+	h.users.Invalidate(user.ID)
+
+	h.send(mail.Message{
+		To:      user.Email,
+		Subject: "Your account will be deleted",
+		Body: fmt.Sprintf("Your account is scheduled for deletion on %s.\n\n"+
+			"Sign in before then to keep your account.\n", deleteAt.UTC().Format(time.RFC1123)),
+	})
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":               "Account scheduled for deletion, sign in before the deletion date to restore it",
+		"deletion_scheduled_at": deleteAt,
+	})
+}
+
+// currentUser loads the authenticated user fresh from the database, as
+// cached users may carry an outdated password hash
+func (h *MeHandler) currentUser(c *gin.Context) (*models.User, bool) {
+	contextUser, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+		})
+		return nil, false
+	}
+
+	user, err := h.oauth.GetUserByID(contextUser.ID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not found",
+		})
+		return nil, false
+	}
+
+	return user, true
+}
+
+// respondReauthError maps errors of operations that re-check the password
+func (h *MeHandler) respondReauthError(c *gin.Context, err error, message string) {
+	if throttled, ok := err.(*auth.LoginThrottledError); ok {
+		respondLoginThrottled(c, throttled)
+		return
+	}
+	if err == auth.ErrIncorrectPassword {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Current password is incorrect",
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error": message,
+	})
+}
+
+// send delivers an account notification; failures are logged only
+func (h *MeHandler) send(msg mail.Message) {
+	if err := h.mailer.Send(msg); err != nil {
+		log.Printf("Failed to send account email: %v", err)
+	}
+}
+
+// userResponse converts a user to its API representation
+func userResponse(user *models.User) models.UserResponse {
+	return models.UserResponse{
+		ID:                  user.ID,
+		Email:               user.Email,
+		EmailVerified:       user.EmailVerified(),
+		DisplayName:         user.DisplayName,
+		Locale:              user.Locale,
+		Timezone:            user.Timezone,
+		DeletionScheduledAt: user.DeletionScheduledAt,
+		DisabledAt:          user.DisabledAt,
+		Roles:               user.RoleNames(),
+		CreatedAt:           user.CreatedAt,
+		UpdatedAt:           user.UpdatedAt,
+	}
+}
@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"net/http"
+
+	"ishare-task-api/internal/auth"
+	"ishare-task-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MFAHandler handles multi-factor enrollment of the current user
+type MFAHandler struct {
+	oauth *auth.OAuthManager
+}
+
+// NewMFAHandler creates a new MFA handler
+func NewMFAHandler(oauth *auth.OAuthManager) *MFAHandler {
+	return &MFAHandler{
+		oauth: oauth,
+	}
+}
+
+// GetStatus returns the MFA status of the current user
+// @Summary MFA Status
+// @Description Reports whether TOTP is enabled and how many recovery codes remain
+// @Tags MFA
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.MFAStatusResponse "MFA status"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Request made with a personal access token"
+// @Router /me/mfa [get]
+func (h *MFAHandler) GetStatus(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+		})
+		return
+	}
+
+	credential, remaining, err := h.oauth.GetMFAStatus(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve MFA status",
+		})
+		return
+	}
+
+	response := models.MFAStatusResponse{
+		Enabled:                credential != nil,
+		RecoveryCodesRemaining: remaining,
+	}
+	if credential != nil {
+		response.ConfirmedAt = credential.ConfirmedAt
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// BeginTOTP starts TOTP enrollment
+// @Summary Start TOTP Enrollment
+// @Description Generates a TOTP secret and otpauth URI. The authenticator becomes active once confirmed.
+// @Tags MFA
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.MFAEnrollmentResponse "TOTP secret generated"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Request made with a personal access token"
+// @Failure 409 {object} map[string]interface{} "MFA already enabled"
+// @Router /me/mfa/totp [post]
+func (h *MFAHandler) BeginTOTP(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+		})
+		return
+	}
+
+	secret, uri, err := h.oauth.BeginTOTPEnrollment(user)
+	if err != nil {
+		if err == auth.ErrMFAAlreadyEnabled {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "Multi-factor authentication is already enabled",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to start TOTP enrollment",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.MFAEnrollmentResponse{
+		Secret:     secret,
+		OTPAuthURI: uri,
+	})
+}
+
+// ConfirmTOTP confirms TOTP enrollment
+// @Summary Confirm TOTP Enrollment
+// @Description Activates the pending authenticator and returns recovery codes, which are shown only once
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.MFACodeRequest true "Code from the authenticator app"
+// @Success 200 {object} models.RecoveryCodesResponse "TOTP enabled"
+// @Failure 400 {object} map[string]interface{} "Bad request or invalid code"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Request made with a personal access token"
+// @Router /me/mfa/totp/confirm [post]
+func (h *MFAHandler) ConfirmTOTP(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+		})
+		return
+	}
+
+	var req models.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request body",
+		})
+		return
+	}
+
+	codes, err := h.oauth.ConfirmTOTPEnrollment(user.ID, req.Code)
+	if err != nil {
+		h.respondMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.RecoveryCodesResponse{
+		RecoveryCodes: codes,
+	})
+}
+
+// DisableTOTP disables TOTP
+// @Summary Disable TOTP
+// @Description Removes the authenticator and recovery codes after checking a current code
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.MFACodeRequest true "Authenticator or recovery code"
+// @Success 200 {object} map[string]interface{} "TOTP disabled"
+// @Failure 400 {object} map[string]interface{} "Bad request or invalid code"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Request made with a personal access token"
+// @Router /me/mfa/totp [delete]
+func (h *MFAHandler) DisableTOTP(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+		})
+		return
+	}
+
+	var req models.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request body",
+		})
+		return
+	}
+
+	if err := h.oauth.DisableTOTP(user.ID, req.Code); err != nil {
+		h.respondMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Multi-factor authentication disabled",
+	})
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes
+// @Summary Regenerate Recovery Codes
+// @Description Invalidates the existing recovery codes and returns a new set, shown only once
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.MFACodeRequest true "Authenticator or recovery code"
+// @Success 200 {object} models.RecoveryCodesResponse "Recovery codes regenerated"
+// @Failure 400 {object} map[string]interface{} "Bad request or invalid code"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Request made with a personal access token"
+// @Router /me/mfa/recovery-codes [post]
+func (h *MFAHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+		})
+		return
+	}
+
+	var req models.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request body",
+		})
+		return
+	}
+
+	codes, err := h.oauth.RegenerateRecoveryCodes(user.ID, req.Code)
+	if err != nil {
+		h.respondMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.RecoveryCodesResponse{
+		RecoveryCodes: codes,
+	})
+}
+
+// respondMFAError maps MFA errors to responses
+func (h *MFAHandler) respondMFAError(c *gin.Context, err error) {
+	switch err {
+	case auth.ErrInvalidMFACode:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid authentication code",
+		})
+	case auth.ErrMFANotEnabled, auth.ErrMFAEnrollmentNeeded:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+	case auth.ErrMFAAlreadyEnabled:
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "Multi-factor authentication is already enabled",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to process multi-factor request",
+		})
+	}
+}
@@ -0,0 +1,443 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"ishare-task-api/internal/auth"
+	"ishare-task-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// OrganizationHandler handles the organizations of the current user and
+// the administration of the active organization
+type OrganizationHandler struct {
+	oauth *auth.OAuthManager
+	rbac  *auth.RBAC
+}
+
+// NewOrganizationHandler creates a new organization handler
+func NewOrganizationHandler(oauth *auth.OAuthManager, rbac *auth.RBAC) *OrganizationHandler {
+	return &OrganizationHandler{
+		oauth: oauth,
+		rbac:  rbac,
+	}
+}
+
+// ListMyOrganizations lists the organizations of the current user
+// @Summary List My Organizations
+// @Description Lists the organizations the authenticated user belongs to with the user's role in each; the organization of the current token is marked active
+// @Tags Organizations
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.OrganizationsResponse "Organizations"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Request made with a personal access token"
+// @Router /me/orgs [get]
+func (h *OrganizationHandler) ListMyOrganizations(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+		})
+		return
+	}
+
+	memberships, err := h.rbac.UserOrganizations(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve organizations",
+		})
+		return
+	}
+
+	activeOrgID, _ := auth.GetOrganizationIDFromContext(c)
+	orgResponses := make([]models.OrganizationResponse, len(memberships))
+	for i, membership := range memberships {
+		orgResponses[i] = organizationResponse(&membership.Organization)
+		orgResponses[i].Role = membership.Role.Name
+		orgResponses[i].Active = membership.OrgID == activeOrgID
+	}
+
+	c.JSON(http.StatusOK, models.OrganizationsResponse{
+		Organizations: orgResponses,
+		Total:         int64(len(orgResponses)),
+	})
+}
+
+// SwitchOrganization issues a token for another organization
+// @Summary Switch Organization
+// @Description Exchanges the current access token for one acting in another organization the user belongs to. The new token keeps the client, scope and sign-in methods; the current token is revoked. Personal access tokens are bound to their organization and cannot switch.
+// @Tags Organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.SwitchOrganizationRequest true "Organization to switch to"
+// @Success 200 {object} auth.TokenResponse "Token for the organization"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Not a member of the organization, or personal access token"
+// @Router /me/orgs/switch [post]
+func (h *OrganizationHandler) SwitchOrganization(c *gin.Context) {
+	claims, exists := auth.GetClaimsFromContext(c)
+	tokenID, hasTokenID := auth.GetTokenIDFromContext(c)
+	if !exists || !hasTokenID {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+		})
+		return
+	}
+
+	var req models.SwitchOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "An organization ID is required",
+		})
+		return
+	}
+
+	tokenString, accessToken, err := h.oauth.SwitchOrganization(tokenID, claims.AMR, req.OrgID, c.ClientIP())
+	if err != nil {
+		switch err {
+		case auth.ErrNotMember:
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Not a member of the organization",
+			})
+		case auth.ErrSessionNotFound:
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Token not found or expired",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to switch organization",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, auth.TokenResponse{
+		AccessToken: tokenString,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(24 * 60 * 60), // 24 hours in seconds
+		Scope:       accessToken.Scope,
+	})
+}
+
+// GetOrganization returns the active organization
+// @Summary Get Organization
+// @Description Returns the organization of the current token with the user's role in it
+// @Tags Organizations
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.OrganizationResponse "Organization"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "No active organization or insufficient permissions"
+// @Router /org [get]
+func (h *OrganizationHandler) GetOrganization(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	orgID, hasOrg := auth.GetOrganizationIDFromContext(c)
+	if !exists || !hasOrg {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+		})
+		return
+	}
+
+	memberships, err := h.rbac.UserOrganizations(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve organization",
+		})
+		return
+	}
+
+	for _, membership := range memberships {
+		if membership.OrgID == orgID {
+			response := organizationResponse(&membership.Organization)
+			response.Role = membership.Role.Name
+			response.Active = true
+			c.JSON(http.StatusOK, response)
+			return
+		}
+	}
+
+	c.JSON(http.StatusNotFound, gin.H{
+		"error": "Organization not found",
+	})
+}
+
+// ListMembers lists the members of the active organization
+// @Summary List Organization Members
+// @Description Lists the members of the organization of the current token with their roles
+// @Tags Organizations
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.OrganizationMembersResponse "Members"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "No active organization or insufficient permissions"
+// @Router /org/members [get]
+func (h *OrganizationHandler) ListMembers(c *gin.Context) {
+	orgID, ok := activeOrganization(c)
+	if !ok {
+		return
+	}
+
+	members, err := h.rbac.OrganizationMembers(orgID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve members",
+		})
+		return
+	}
+
+	memberResponses := make([]models.OrganizationMemberResponse, len(members))
+	for i := range members {
+		memberResponses[i] = organizationMemberResponse(&members[i])
+	}
+
+	c.JSON(http.StatusOK, models.OrganizationMembersResponse{
+		Members: memberResponses,
+		Total:   int64(len(memberResponses)),
+	})
+}
+
+// AddMember adds a user to the active organization
+// @Summary Add Organization Member
+// @Description Adds an existing user, identified by email, to the organization of the current token with the given role (e.g. admin, member or viewer)
+// @Tags Organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.AddOrganizationMemberRequest true "User email and role"
+// @Success 201 {object} map[string]interface{} "Member added"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "No active organization or insufficient permissions"
+// @Failure 404 {object} map[string]interface{} "User or role not found"
+// @Failure 409 {object} map[string]interface{} "Already a member"
+// @Router /org/members [post]
+func (h *OrganizationHandler) AddMember(c *gin.Context) {
+	orgID, ok := activeOrganization(c)
+	if !ok {
+		return
+	}
+
+	addOrganizationMember(c, h.oauth, h.rbac, orgID)
+}
+
+// UpdateMember changes the role of a member of the active organization
+// @Summary Update Organization Member
+// @Description Changes the role of a member of the organization of the current token. The last admin of an organization cannot be demoted.
+// @Tags Organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param request body models.UpdateOrganizationMemberRequest true "New role"
+// @Success 200 {object} map[string]interface{} "Member updated"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "No active organization or insufficient permissions"
+// @Failure 404 {object} map[string]interface{} "Member or role not found"
+// @Failure 409 {object} map[string]interface{} "Last admin of the organization"
+// @Router /org/members/{id} [patch]
+func (h *OrganizationHandler) UpdateMember(c *gin.Context) {
+	actor, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+		})
+		return
+	}
+
+	orgID, ok := activeOrganization(c)
+	if !ok {
+		return
+	}
+
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateOrganizationMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "A role is required",
+		})
+		return
+	}
+
+	if err := h.rbac.SetOrganizationRole(orgID, userID, req.Role); err != nil {
+		respondOrganizationError(c, err, "Failed to update member")
+		return
+	}
+
+	h.oauth.RecordAuditEvent(models.AuditEvent{
+		Event:     auth.AuditOrganizationMemberUpdated,
+		UserID:    &userID,
+		ActorID:   &actor.ID,
+		IPAddress: c.ClientIP(),
+		Details:   fmt.Sprintf("org: %s, role: %s", orgID, req.Role),
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Member updated",
+	})
+}
+
+// RemoveMember removes a member from the active organization
+// @Summary Remove Organization Member
+// @Description Removes a user from the organization of the current token; the account itself is kept. The last admin of an organization cannot be removed.
+// @Tags Organizations
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} map[string]interface{} "Member removed"
+// @Failure 400 {object} map[string]interface{} "Invalid user ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "No active organization or insufficient permissions"
+// @Failure 404 {object} map[string]interface{} "Member not found"
+// @Failure 409 {object} map[string]interface{} "Last admin of the organization"
+// @Router /org/members/{id} [delete]
+func (h *OrganizationHandler) RemoveMember(c *gin.Context) {
+	actor, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+		})
+		return
+	}
+
+	orgID, ok := activeOrganization(c)
+	if !ok {
+		return
+	}
+
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.rbac.RemoveOrganizationMember(orgID, userID); err != nil {
+		respondOrganizationError(c, err, "Failed to remove member")
+		return
+	}
+
+	h.oauth.RecordAuditEvent(models.AuditEvent{
+		Event:     auth.AuditOrganizationMemberRemoved,
+		UserID:    &userID,
+		ActorID:   &actor.ID,
+		IPAddress: c.ClientIP(),
+		Details:   fmt.Sprintf("org: %s", orgID),
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Member removed",
+	})
+}
+
+// addOrganizationMember adds the user named in the request body to an
+// organization; shared by organization and platform administration
+func addOrganizationMember(c *gin.Context, oauth *auth.OAuthManager, rbac *auth.RBAC, orgID uuid.UUID) {
+	actor, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+		})
+		return
+	}
+
+	var req models.AddOrganizationMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "An email address and a role are required",
+		})
+		return
+	}
+
+	user, err := rbac.AddOrganizationMember(orgID, req.Email, req.Role)
+	if err != nil {
+		respondOrganizationError(c, err, "Failed to add member")
+		return
+	}
+
+	oauth.RecordAuditEvent(models.AuditEvent{
+		Event:     auth.AuditOrganizationMemberAdded,
+		UserID:    &user.ID,
+		ActorID:   &actor.ID,
+		Email:     user.Email,
+		IPAddress: c.ClientIP(),
+		Details:   fmt.Sprintf("org: %s, role: %s", orgID, req.Role),
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Member added",
+		"user_id": user.ID,
+	})
+}
+
+// respondOrganizationError maps organization errors to responses
+func respondOrganizationError(c *gin.Context, err error, message string) {
+	switch err {
+	case auth.ErrOrganizationNotFound:
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Organization not found",
+		})
+	case auth.ErrUserNotFound:
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "User not found",
+		})
+	case auth.ErrRoleNotFound:
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Role not found",
+		})
+	case auth.ErrNotMember:
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Member not found",
+		})
+	case auth.ErrAlreadyMember:
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "User is already a member of the organization",
+		})
+	case auth.ErrOrganizationExists:
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "Organization slug already in use",
+		})
+	case auth.ErrInvalidSlug:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+	case auth.ErrLastOrgAdmin:
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "Cannot remove or demote the last admin of the organization",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": message,
+		})
+	}
+}
+
+// organizationResponse converts an organization to its response
+func organizationResponse(org *models.Organization) models.OrganizationResponse {
+	return models.OrganizationResponse{
+		ID:        org.ID,
+		Name:      org.Name,
+		Slug:      org.Slug,
+		CreatedAt: org.CreatedAt,
+	}
+}
+
+// organizationMemberResponse converts a membership to its response
+func organizationMemberResponse(member *models.OrganizationMember) models.OrganizationMemberResponse {
+	return models.OrganizationMemberResponse{
+		UserID:      member.UserID,
+		Email:       member.User.Email,
+		DisplayName: member.User.DisplayName,
+		Role:        member.Role.Name,
+		CreatedAt:   member.CreatedAt,
+	}
+}
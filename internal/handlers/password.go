@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"ishare-task-api/internal/auth"
+	"ishare-task-api/internal/mail"
+	"ishare-task-api/internal/models"
+	"ishare-task-api/internal/password"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ForgotPasswordPage renders the forgot password form
+// @Summary Forgot Password Page
+// @Description Renders the form to request a password reset email
+// @Tags Password
+// @Produce html
+// @Success 200 {string} string "Forgot password page"
+// @Router /oauth/password/forgot [get]
+func (h *AuthHandler) ForgotPasswordPage(c *gin.Context) {
+	c.HTML(http.StatusOK, "forgot_password.html", gin.H{})
+}
+
+// ForgotPassword sends a password reset email
+// @Summary Forgot Password
+// @Description Sends a single-use password reset link. The response does not reveal whether the email is registered.
+// @Tags Password
+// @Accept json,x-www-form-urlencoded
+// @Produce json,html
+// @Param request body models.ForgotPasswordRequest true "Account email"
+// @Success 200 {object} map[string]interface{} "Reset email sent if the account exists"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Router /oauth/password/forgot [post]
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req models.ForgotPasswordRequest
+	if err := c.ShouldBind(&req); err != nil {
+		h.respondPassword(c, http.StatusBadRequest, "forgot_password.html", gin.H{
+			"error": "A valid email address is required",
+		})
+		return
+	}
+
+	// The lookup, the token and the email happen after the response so its
+	// timing does not reveal whether the email is registered
+	go h.sendPasswordReset(req.Email)
+
+	h.respondPassword(c, http.StatusOK, "forgot_password.html", gin.H{
+		"message": "If an account exists for this email, a password reset link has been sent",
+	})
+}
+
+// sendPasswordReset emails a password reset link if the account exists;
+// failures are only logged
+func (h *AuthHandler) sendPasswordReset(email string) {
+	token, user, err := h.oauth.CreatePasswordResetToken(email)
+	if err != nil {
+		if err != auth.ErrUserNotFound {
+			log.Printf("Failed to create password reset token: %v", err)
+		}
+		return
+	}
+
+	link := h.cfg.Server.BaseURL + "/oauth/password/reset?token=" + url.QueryEscape(token)
+	if err := h.mailer.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("A password reset was requested for your account.\n\n"+
+			"Open the following link to choose a new password:\n%s\n\n"+
+			"The link expires in %s and can be used once. "+
+			"If you did not request a reset, you can ignore this email.\n",
+			link, h.cfg.OAuth.PasswordResetTTL),
+	}); err != nil {
+		log.Printf("Failed to send password reset email: %v", err)
+	}
+}
+
+// ResetPasswordPage renders the reset password form
+// @Summary Reset Password Page
+// @Description Renders the form to choose a new password
+// @Tags Password
+// @Produce html
+// @Param token query string true "Password reset token"
+// @Success 200 {string} string "Reset password page"
+// @Router /oauth/password/reset [get]
+func (h *AuthHandler) ResetPasswordPage(c *gin.Context) {
+	c.HTML(http.StatusOK, "reset_password.html", gin.H{
+		"token": c.Query("token"),
+	})
+}
+
+// ResetPassword sets a new password using a reset token
+// @Summary Reset Password
+// @Description Consumes a password reset token, sets the new password and signs the user out everywhere
+// @Tags Password
+// @Accept json,x-www-form-urlencoded
+// @Produce json,html
+// @Param request body models.ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} map[string]interface{} "Password reset successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request, invalid token or password rejected by the policy"
+// @Router /oauth/password/reset [post]
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := c.ShouldBind(&req); err != nil {
+		h.respondPassword(c, http.StatusBadRequest, "reset_password.html", gin.H{
+			"error": "Token and password are required",
+			"token": req.Token,
+		})
+		return
+	}
+
+	if _, err := h.oauth.ResetPassword(req.Token, req.Password); err != nil {
+		if err == auth.ErrInvalidResetToken {
+			h.respondPassword(c, http.StatusBadRequest, "reset_password.html", gin.H{
+				"error": "Invalid or expired password reset token",
+			})
+			return
+		}
+		if policyErr, ok := err.(*password.PolicyError); ok {
+			h.respondPassword(c, http.StatusBadRequest, "reset_password.html", gin.H{
+				"error":      "Password " + strings.Join(policyErr.Violations, ", "),
+				"violations": policyErr.Violations,
+				"token":      req.Token,
+			})
+			return
+		}
+		h.respondPassword(c, http.StatusInternalServerError, "reset_password.html", gin.H{
+			"error": "Failed to reset password",
+			"token": req.Token,
+		})
+		return
+	}
+
+	h.respondPassword(c, http.StatusOK, "reset_password.html", gin.H{
+		"message": "Password reset successfully. You can now sign in with your new password.",
+		"done":    true,
+	})
+}
+
+// respondPassword renders the page for form submissions and JSON otherwise
+func (h *AuthHandler) respondPassword(c *gin.Context, status int, page string, data gin.H) {
+	if c.ContentType() == "application/x-www-form-urlencoded" {
+		c.HTML(status, page, data)
+		return
+	}
+
+	response := gin.H{}
+	for _, key := range []string{"error", "message", "violations"} {
+		if value, ok := data[key]; ok {
+			response[key] = value
+		}
+	}
+	c.JSON(status, response)
+}
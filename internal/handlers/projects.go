@@ -0,0 +1,519 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"ishare-task-api/internal/auth"
+	"ishare-task-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ProjectHandler handles project-related requests
+type ProjectHandler struct {
+	authz *auth.TaskAuthorizer
+}
+
+// NewProjectHandler creates a new project handler
+func NewProjectHandler(authz *auth.TaskAuthorizer) *ProjectHandler {
+	return &ProjectHandler{
+		authz: authz,
+	}
+}
+
+// projectWithRole is a project with the current user's role on it
+type projectWithRole struct {
+	models.Project
+	Role string
+}
+
+// ListProjects lists the projects of the current user
+// @Summary List Projects
+// @Description Lists the projects in the active organization the user is a member of, with the user's role on each. Archived projects are only included with archived=true.
+// @Tags Projects
+// @Produce json
+// @Security BearerAuth
+// @Param archived query bool false "Include archived projects" example(true)
+// @Success 200 {object} models.ProjectsResponse "Projects"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Insufficient permissions"
+// @Router /projects [get]
+func (h *ProjectHandler) ListProjects(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+		})
+		return
+	}
+
+	orgID, ok := activeOrganization(c)
+	if !ok {
+		return
+	}
+
+	query := h.authz.DB(c).Model(&models.Project{}).
+		Select("projects.*, project_members.role AS role").
+		Joins("JOIN project_members ON project_members.project_id = projects.id AND project_members.user_id = ?", user.ID).
+		Where("projects.org_id = ?", orgID)
+	if c.Query("archived") != "true" {
+		query = query.Where("projects.archived = ?", false)
+	}
+
+	var projects []projectWithRole
+	if err := query.Order("projects.name").Scan(&projects).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve projects",
+		})
+		return
+	}
+
+	projectResponses := make([]models.ProjectResponse, len(projects))
+	for i := range projects {
+		projectResponses[i] = projectResponse(&projects[i].Project, projects[i].Role)
+	}
+
+	c.JSON(http.StatusOK, models.ProjectsResponse{
+		Projects: projectResponses,
+		Total:    int64(len(projectResponses)),
+	})
+}
+
+// CreateProject creates a project
+// @Summary Create Project
+// @Description Creates a project in the active organization; the creator becomes its owner
+// @Tags Projects
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.CreateProjectRequest true "Project"
+// @Success 201 {object} models.ProjectResponse "Project created"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Insufficient permissions"
+// @Router /projects [post]
+func (h *ProjectHandler) CreateProject(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+		})
+		return
+	}
+
+	orgID, ok := activeOrganization(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request body",
+		})
+		return
+	}
+
+	project := &models.Project{
+		OrgID:       orgID,
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+		CreatedBy:   &user.ID,
+	}
+
+	db := h.authz.DB(c)
+	if err := db.Create(project).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to create project",
+		})
+		return
+	}
+	if err := saveProjectMember(db, project, user.ID, models.TaskRoleOwner); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to create project",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, projectResponse(project, models.TaskRoleOwner))
+}
+
+// GetProject retrieves a project
+// @Summary Get Project
+// @Description Retrieves a project by ID; requires at least the viewer role on the project
+// @Tags Projects
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Project ID" format(uuid)
+// @Success 200 {object} models.ProjectResponse "Project"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Insufficient permissions"
+// @Failure 404 {object} map[string]interface{} "Project not found"
+// @Router /projects/{id} [get]
+func (h *ProjectHandler) GetProject(c *gin.Context) {
+	project, role, ok := h.authz.AuthorizeProject(c, models.TaskRoleViewer)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, projectResponse(project, role))
+}
+
+// UpdateProject updates a project
+// @Summary Update Project
+// @Description Updates the name and description of a project, which requires at least the editor role on it, or archives and restores it, which requires the owner role. Omitted fields are left unchanged.
+// @Tags Projects
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Project ID" format(uuid)
+// @Param request body models.UpdateProjectRequest true "Project changes"
+// @Success 200 {object} models.ProjectResponse "Project updated"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Insufficient permissions or project role"
+// @Failure 404 {object} map[string]interface{} "Project not found"
+// @Router /projects/{id} [patch]
+func (h *ProjectHandler) UpdateProject(c *gin.Context) {
+	project, role, ok := h.authz.AuthorizeProject(c, models.TaskRoleEditor)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request body",
+		})
+		return
+	}
+
+	if req.Name != nil {
+		project.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Description != nil {
+		project.Description = *req.Description
+	}
+	if req.Archived != nil && *req.Archived != project.Archived {
+		if role != models.TaskRoleOwner {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":         "Insufficient access to the project",
+				"role":          role,
+				"required_role": models.TaskRoleOwner,
+			})
+			return
+		}
+		project.Archived = *req.Archived
+	}
+
+	if err := h.authz.DB(c).Save(project).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to update project",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, projectResponse(project, role))
+}
+
+// DeleteProject deletes a project
+// @Summary Delete Project
+// @Description Deletes a project; requires the owner role on it. Its tasks are kept without a project and remain accessible to their owners and the users they are shared with.
+// @Tags Projects
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Project ID" format(uuid)
+// @Success 200 {object} map[string]interface{} "Project deleted"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Insufficient permissions or project role"
+// @Failure 404 {object} map[string]interface{} "Project not found"
+// @Router /projects/{id} [delete]
+func (h *ProjectHandler) DeleteProject(c *gin.Context) {
+	project, _, ok := h.authz.AuthorizeProject(c, models.TaskRoleOwner)
+	if !ok {
+		return
+	}
+
+	if err := h.authz.DB(c).Delete(project).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to delete project",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Project deleted",
+	})
+}
+
+// ListProjectTasks lists the tasks of a project
+// @Summary List Project Tasks
+// @Description Retrieves the tasks of a project with the same filtering and pagination as GET /tasks; requires at least the viewer role on the project
+// @Tags Projects
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Project ID" format(uuid)
+// @Param status query string false "Filter by status" example(pending)
+// @Param due_before query string false "Only tasks due before this time; plain dates and times without an offset are in the user's timezone" example(2026-11-01)
+// @Param due_after query string false "Only tasks due at or after this time" example(2026-10-01T00:00:00Z)
+// @Param overdue query bool false "Only tasks past their due date that are not completed" example(true)
+// @Param due_today query bool false "Only tasks due today in the user's timezone" example(true)
+// @Param page query int false "Page number" example(1)
+// @Param limit query int false "Items per page" example(10)
+// @Success 200 {object} models.TasksResponse "Tasks retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Insufficient permissions"
+// @Failure 404 {object} map[string]interface{} "Project not found"
+// @Router /projects/{id}/tasks [get]
+func (h *ProjectHandler) ListProjectTasks(c *gin.Context) {
+	project, _, ok := h.authz.AuthorizeProject(c, models.TaskRoleViewer)
+	if !ok {
+		return
+	}
+
+	// Project roles cascade to all tasks of the project
+	listTasks(c, h.authz.DB(c).Model(&models.Task{}).Where("org_id = ? AND project_id = ?", project.OrgID, project.ID))
+}
+
+// ListMembers lists the members of a project
+// @Summary List Project Members
+// @Description Lists the members of a project and their roles; requires at least the viewer role on the project
+// @Tags Projects
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Project ID" format(uuid)
+// @Success 200 {object} models.ProjectMembersResponse "Members"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Insufficient permissions"
+// @Failure 404 {object} map[string]interface{} "Project not found"
+// @Router /projects/{id}/members [get]
+func (h *ProjectHandler) ListMembers(c *gin.Context) {
+	project, _, ok := h.authz.AuthorizeProject(c, models.TaskRoleViewer)
+	if !ok {
+		return
+	}
+
+	var members []models.ProjectMember
+	if err := h.authz.DB(c).Preload("User").
+		Where("project_id = ?", project.ID).
+		Order("created_at").
+		Find(&members).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve members",
+		})
+		return
+	}
+
+	memberResponses := make([]models.ProjectMemberResponse, len(members))
+	for i := range members {
+		memberResponses[i] = projectMemberResponse(&members[i], members[i].User)
+	}
+
+	c.JSON(http.StatusOK, models.ProjectMembersResponse{
+		Members: memberResponses,
+		Total:   int64(len(memberResponses)),
+	})
+}
+
+// AddMember adds a member of the organization to a project
+// @Summary Add Project Member
+// @Description Grants a member of the organization the viewer, editor or owner role on the project and all of its tasks, replacing any role they had; requires the owner role on the project
+// @Tags Projects
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Project ID" format(uuid)
+// @Param request body models.AddProjectMemberRequest true "Member email and role"
+// @Success 200 {object} models.ProjectMemberResponse "Member added"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Insufficient permissions or project role"
+// @Failure 404 {object} map[string]interface{} "Project or member not found"
+// @Failure 409 {object} map[string]interface{} "Last owner of the project"
+// @Router /projects/{id}/members [post]
+func (h *ProjectHandler) AddMember(c *gin.Context) {
+	project, _, ok := h.authz.AuthorizeProject(c, models.TaskRoleOwner)
+	if !ok {
+		return
+	}
+
+	var req models.AddProjectMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "An email address and a role of viewer, editor or owner are required",
+		})
+		return
+	}
+
+	db := h.authz.DB(c)
+
+	var target models.User
+	if err := db.Joins("JOIN organization_members ON organization_members.user_id = users.id").
+		Where("organization_members.org_id = ? AND LOWER(users.email) = LOWER(?)", project.OrgID, strings.TrimSpace(req.Email)).
+		First(&target).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "User is not a member of the organization",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to add member",
+		})
+		return
+	}
+
+	if req.Role != models.TaskRoleOwner && !ensureProjectOwnerRemains(c, db, project.ID, target.ID) {
+		return
+	}
+
+	if err := saveProjectMember(db, project, target.ID, req.Role); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to add member",
+		})
+		return
+	}
+
+	var member models.ProjectMember
+	if err := db.Where("project_id = ? AND user_id = ?", project.ID, target.ID).First(&member).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to add member",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, projectMemberResponse(&member, &target))
+}
+
+// RemoveMember removes a member from a project
+// @Summary Remove Project Member
+// @Description Removes a member from a project. Owners can remove any member; other members can remove themselves. The last owner of a project cannot be removed.
+// @Tags Projects
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Project ID" format(uuid)
+// @Param user_id path string true "User ID" format(uuid)
+// @Success 200 {object} map[string]interface{} "Member removed"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Insufficient permissions or project role"
+// @Failure 404 {object} map[string]interface{} "Project or member not found"
+// @Failure 409 {object} map[string]interface{} "Last owner of the project"
+// @Router /projects/{id}/members/{user_id} [delete]
+func (h *ProjectHandler) RemoveMember(c *gin.Context) {
+	project, role, ok := h.authz.AuthorizeProject(c, models.TaskRoleViewer)
+	if !ok {
+		return
+	}
+
+	user, _ := auth.GetUserFromContext(c)
+
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid user ID format",
+		})
+		return
+	}
+
+	if role != models.TaskRoleOwner && userID != user.ID {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":         "Insufficient access to the project",
+			"role":          role,
+			"required_role": models.TaskRoleOwner,
+		})
+		return
+	}
+
+	db := h.authz.DB(c)
+	if !ensureProjectOwnerRemains(c, db, project.ID, userID) {
+		return
+	}
+
+	result := db.Where("project_id = ? AND user_id = ?", project.ID, userID).Delete(&models.ProjectMember{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to remove member",
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Member not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Member removed",
+	})
+}
+
+// saveProjectMember grants a user a role on a project, replacing any
+// previous role
+func saveProjectMember(db *gorm.DB, project *models.Project, userID uuid.UUID, role string) error {
+	now := time.Now()
+	return db.Exec(`INSERT INTO project_members (project_id, user_id, org_id, role, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (project_id, user_id) DO UPDATE SET role = EXCLUDED.role, updated_at = EXCLUDED.updated_at`,
+		project.ID, userID, project.OrgID, role, now, now).Error
+}
+
+// ensureProjectOwnerRemains writes a conflict and returns false if the user
+// is the only owner of the project
+func ensureProjectOwnerRemains(c *gin.Context, db *gorm.DB, projectID, userID uuid.UUID) bool {
+	var owners []uuid.UUID
+	if err := db.Model(&models.ProjectMember{}).
+		Where("project_id = ? AND role = ?", projectID, models.TaskRoleOwner).
+		Pluck("user_id", &owners).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to check project owners",
+		})
+		return false
+	}
+
+	if len(owners) == 1 && owners[0] == userID {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "Cannot remove or demote the last owner of the project",
+		})
+		return false
+	}
+	return true
+}
+
+// projectResponse converts a project to its response
+func projectResponse(project *models.Project, role string) models.ProjectResponse {
+	return models.ProjectResponse{
+		ID:          project.ID,
+		OrgID:       project.OrgID,
+		Name:        project.Name,
+		Description: project.Description,
+		Archived:    project.Archived,
+		Role:        role,
+		CreatedBy:   project.CreatedBy,
+		CreatedAt:   project.CreatedAt,
+		UpdatedAt:   project.UpdatedAt,
+	}
+}
+
+// projectMemberResponse converts a project member to its response
+func projectMemberResponse(member *models.ProjectMember, user *models.User) models.ProjectMemberResponse {
+	response := models.ProjectMemberResponse{
+		UserID:    member.UserID,
+		Role:      member.Role,
+		CreatedAt: member.CreatedAt,
+	}
+	if user != nil {
+		response.Email = user.Email
+		response.DisplayName = user.DisplayName
+	}
+	return response
+}
@@ -0,0 +1,361 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"ishare-task-api/internal/auth"
+	"ishare-task-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListRoles lists all roles
+// @Summary List Roles
+// @Description Lists the built-in and custom roles with their permissions
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.RolesResponse "Roles"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Router /admin/roles [get]
+func (h *AdminHandler) ListRoles(c *gin.Context) {
+	roles, err := h.rbac.ListRoles()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve roles",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.RolesResponse{
+		Roles: roles,
+	})
+}
+
+// ListPermissions lists all permissions
+// @Summary List Permissions
+// @Description Lists the permissions that can be granted to roles
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.PermissionsResponse "Permissions"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Router /admin/permissions [get]
+func (h *AdminHandler) ListPermissions(c *gin.Context) {
+	permissions, err := h.rbac.ListPermissions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve permissions",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PermissionsResponse{
+		Permissions: permissions,
+	})
+}
+
+// CreateRole creates a custom role
+// @Summary Create Role
+// @Description Creates a custom role with the given permissions
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.CreateRoleRequest true "Role"
+// @Success 201 {object} models.Role "Role created"
+// @Failure 400 {object} map[string]interface{} "Bad request or unknown permission"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 409 {object} map[string]interface{} "Role already exists"
+// @Router /admin/roles [post]
+func (h *AdminHandler) CreateRole(c *gin.Context) {
+	admin, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+		})
+		return
+	}
+
+	var req models.CreateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request body",
+		})
+		return
+	}
+
+	role, err := h.rbac.CreateRole(req.Name, req.Description, req.Permissions)
+	if err != nil {
+		h.respondRoleError(c, err, "Failed to create role")
+		return
+	}
+
+	h.oauth.RecordAuditEvent(models.AuditEvent{
+		Event:     auth.AuditRoleCreated,
+		ActorID:   &admin.ID,
+		IPAddress: c.ClientIP(),
+		Details:   "role: " + role.Name + ", permissions: " + strings.Join(role.PermissionNames(), ","),
+	})
+
+	c.JSON(http.StatusCreated, role)
+}
+
+// UpdateRole updates a custom role
+// @Summary Update Role
+// @Description Changes the description and/or replaces the permissions of a custom role. Built-in roles cannot be changed.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "Role name"
+// @Param request body models.UpdateRoleRequest true "Fields to change"
+// @Success 200 {object} models.Role "Role updated"
+// @Failure 400 {object} map[string]interface{} "Bad request or unknown permission"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden or built-in role"
+// @Failure 404 {object} map[string]interface{} "Role not found"
+// @Router /admin/roles/{name} [patch]
+func (h *AdminHandler) UpdateRole(c *gin.Context) {
+	admin, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+		})
+		return
+	}
+
+	var req models.UpdateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request body",
+		})
+		return
+	}
+
+	role, err := h.rbac.UpdateRole(c.Param("name"), req.Description, req.Permissions)
+	if err != nil {
+		h.respondRoleError(c, err, "Failed to update role")
+		return
+	}
+
+	h.oauth.RecordAuditEvent(models.AuditEvent{
+		Event:     auth.AuditRoleUpdated,
+		ActorID:   &admin.ID,
+		IPAddress: c.ClientIP(),
+		Details:   "role: " + role.Name + ", permissions: " + strings.Join(role.PermissionNames(), ","),
+	})
+
+	c.JSON(http.StatusOK, role)
+}
+
+// DeleteRole deletes a custom role
+// @Summary Delete Role
+// @Description Deletes a custom role and removes it from all users. Built-in roles cannot be deleted.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "Role name"
+// @Success 200 {object} map[string]interface{} "Role deleted"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden or built-in role"
+// @Failure 404 {object} map[string]interface{} "Role not found"
+// @Router /admin/roles/{name} [delete]
+func (h *AdminHandler) DeleteRole(c *gin.Context) {
+	admin, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+		})
+		return
+	}
+
+	name := c.Param("name")
+	if err := h.rbac.DeleteRole(name); err != nil {
+		h.respondRoleError(c, err, "Failed to delete role")
+		return
+	}
+
+	h.oauth.RecordAuditEvent(models.AuditEvent{
+		Event:     auth.AuditRoleDeleted,
+		ActorID:   &admin.ID,
+		IPAddress: c.ClientIP(),
+		Details:   "role: " + name,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Role deleted",
+	})
+}
+
+// ListUserRoles lists the roles of a user
+// @Summary List User Roles
+// @Description Lists the roles assigned to a user
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} models.RolesResponse "User roles"
+// @Failure 400 {object} map[string]interface{} "Invalid user ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "User not found"
+// @Router /admin/users/{id}/roles [get]
+func (h *AdminHandler) ListUserRoles(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	roles, err := h.rbac.UserRoles(userID)
+	if err != nil {
+		h.respondRoleError(c, err, "Failed to retrieve user roles")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.RolesResponse{
+		Roles: roles,
+	})
+}
+
+// AssignRole assigns a role to a user
+// @Summary Assign Role
+// @Description Grants a role to a user. The change applies to the user's next request.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param request body models.AssignRoleRequest true "Role to assign"
+// @Success 200 {object} map[string]interface{} "Role assigned"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "User or role not found"
+// @Router /admin/users/{id}/roles [post]
+func (h *AdminHandler) AssignRole(c *gin.Context) {
+	admin, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+		})
+		return
+	}
+
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.AssignRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "A role is required",
+		})
+		return
+	}
+
+	if err := h.rbac.AssignRole(userID, req.Role); err != nil {
+		h.respondRoleError(c, err, "Failed to assign role")
+		return
+	}
+
+	h.oauth.RecordAuditEvent(models.AuditEvent{
+		Event:     auth.AuditRoleAssigned,
+		UserID:    &userID,
+		ActorID:   &admin.ID,
+		IPAddress: c.ClientIP(),
+		Details:   "role: " + req.Role,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Role assigned",
+	})
+}
+
+// RemoveRole removes a role from a user
+// @Summary Remove Role
+// @Description Takes a role away from a user. The admin role cannot be removed from the last admin.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param role path string true "Role name"
+// @Success 200 {object} map[string]interface{} "Role removed"
+// @Failure 400 {object} map[string]interface{} "Invalid user ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Role not found"
+// @Failure 409 {object} map[string]interface{} "Last admin"
+// @Router /admin/users/{id}/roles/{role} [delete]
+func (h *AdminHandler) RemoveRole(c *gin.Context) {
+	admin, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+		})
+		return
+	}
+
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	roleName := c.Param("role")
+	if err := h.rbac.RemoveRole(userID, roleName); err != nil {
+		h.respondRoleError(c, err, "Failed to remove role")
+		return
+	}
+
+	h.oauth.RecordAuditEvent(models.AuditEvent{
+		Event:     auth.AuditRoleRemoved,
+		UserID:    &userID,
+		ActorID:   &admin.ID,
+		IPAddress: c.ClientIP(),
+		Details:   "role: " + roleName,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Role removed",
+	})
+}
+
+// respondRoleError maps role management errors to responses
+func (h *AdminHandler) respondRoleError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, auth.ErrUnknownPermission):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+	case err == auth.ErrRoleNotFound:
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Role not found",
+		})
+	case err == auth.ErrUserNotFound:
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "User not found",
+		})
+	case err == auth.ErrRoleExists:
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "Role already exists",
+		})
+	case err == auth.ErrBuiltinRole:
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Built-in roles cannot be changed",
+		})
+	case err == auth.ErrLastAdmin:
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "Cannot remove the admin role from the last admin",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": message,
+		})
+	}
+}
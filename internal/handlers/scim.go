@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"strings"
+
+	"ishare-task-api/internal/auth"
+	"ishare-task-api/internal/config"
+	"ishare-task-api/internal/password"
+	"ishare-task-api/internal/scim"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SCIMHandler handles SCIM 2.0 provisioning requests
+type SCIMHandler struct {
+	oauth *auth.OAuthManager
+	users *auth.UserCache
+	db    *gorm.DB
+	cfg   *config.Config
+}
+
+// NewSCIMHandler creates a new SCIM handler
+func NewSCIMHandler(oauth *auth.OAuthManager, users *auth.UserCache, db *gorm.DB, cfg *config.Config) *SCIMHandler {
+	return &SCIMHandler{
+		oauth: oauth,
+		users: users,
+		db:    db,
+		cfg:   cfg,
+	}
+}
+
+// Authenticate requires the SCIM bearer token. It is separate from user
+// tokens, so SCIM access is not tied to any account.
+func (h *SCIMHandler) Authenticate() gin.HandlerFunc {
+	expected := sha256.Sum256([]byte(h.cfg.SCIM.BearerToken))
+
+	return func(c *gin.Context) {
+		token, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		provided := sha256.Sum256([]byte(token))
+		if h.cfg.SCIM.BearerToken == "" || !found ||
+			subtle.ConstantTimeCompare(provided[:], expected[:]) != 1 {
+			c.Header("WWW-Authenticate", `Bearer realm="scim"`)
+			scim.WriteError(c, scim.NewError(http.StatusUnauthorized, "", "A valid SCIM bearer token is required"))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// ServiceProviderConfig describes the supported SCIM features
+// @Summary SCIM Service Provider Configuration
+// @Description Describes the SCIM features supported by the API: PATCH, filtering and ETags, but not bulk operations or sorting
+// @Tags SCIM
+// @Produce json
+// @Security SCIMAuth
+// @Success 200 {object} map[string]interface{} "Service provider configuration"
+// @Failure 401 {object} scim.ErrorResponse "Missing or invalid SCIM bearer token"
+// @Router /scim/v2/ServiceProviderConfig [get]
+func (h *SCIMHandler) ServiceProviderConfig(c *gin.Context) {
+	scim.Write(c, http.StatusOK, gin.H{
+		"schemas":          []string{scim.SchemaServiceProviderConfig},
+		"documentationUri": h.location("/swagger/index.html"),
+		"patch":            gin.H{"supported": true},
+		"bulk":             gin.H{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":           gin.H{"supported": true, "maxResults": scim.MaxResults},
+		"changePassword":   gin.H{"supported": true},
+		"sort":             gin.H{"supported": false},
+		"etag":             gin.H{"supported": true},
+		"authenticationSchemes": []gin.H{{
+			"type":        "oauthbearertoken",
+			"name":        "Bearer Token",
+			"description": "The token configured in SCIM_BEARER_TOKEN",
+			"primary":     true,
+		}},
+		"meta": gin.H{
+			"resourceType": "ServiceProviderConfig",
+			"location":     h.location("/scim/v2/ServiceProviderConfig"),
+		},
+	})
+}
+
+// ResourceTypes lists the SCIM resource types
+// @Summary SCIM Resource Types
+// @Description Lists the provisioned resource types, User and Group
+// @Tags SCIM
+// @Produce json
+// @Security SCIMAuth
+// @Success 200 {object} scim.ListResponse "Resource types"
+// @Failure 401 {object} scim.ErrorResponse "Missing or invalid SCIM bearer token"
+// @Router /scim/v2/ResourceTypes [get]
+func (h *SCIMHandler) ResourceTypes(c *gin.Context) {
+	resourceTypes := []interface{}{
+		gin.H{
+			"schemas":  []string{scim.SchemaResourceType},
+			"id":       "User",
+			"name":     "User",
+			"endpoint": "/Users",
+			"schema":   scim.SchemaUser,
+			"meta": gin.H{
+				"resourceType": "ResourceType",
+				"location":     h.location("/scim/v2/ResourceTypes/User"),
+			},
+		},
+		gin.H{
+			"schemas":  []string{scim.SchemaResourceType},
+			"id":       "Group",
+			"name":     "Group",
+			"endpoint": "/Groups",
+			"schema":   scim.SchemaGroup,
+			"meta": gin.H{
+				"resourceType": "ResourceType",
+				"location":     h.location("/scim/v2/ResourceTypes/Group"),
+			},
+		},
+	}
+
+	scim.Write(c, http.StatusOK, scim.NewListResponse(resourceTypes, int64(len(resourceTypes)), 1))
+}
+
+// location returns the absolute URL of an API path
+func (h *SCIMHandler) location(path string) string {
+	return strings.TrimSuffix(h.cfg.Server.BaseURL, "/") + path
+}
+
+// filterCondition translates the filter query parameter, if any, into an
+// SQL condition
+func filterCondition(c *gin.Context, attributes scim.Attributes) (string, []interface{}, *scim.Error) {
+	filter := c.Query("filter")
+	if filter == "" {
+		return "", nil, nil
+	}
+
+	expr, err := scim.ParseFilter(filter)
+	if err == nil {
+		var condition string
+		var args []interface{}
+		condition, args, err = attributes.Where(expr)
+		if err == nil {
+			return condition, args, nil
+		}
+	}
+
+	if scimErr, ok := err.(*scim.Error); ok {
+		return "", nil, scimErr
+	}
+	return "", nil, scim.BadRequest(scim.ErrorInvalidFilter, err.Error())
+}
+
+// respondSCIMError maps errors of provisioning operations onto SCIM errors
+func respondSCIMError(c *gin.Context, err error, message string) {
+	if scimErr, ok := err.(*scim.Error); ok {
+		scim.WriteError(c, scimErr)
+		return
+	}
+	if policyErr, ok := err.(*password.PolicyError); ok {
+		scim.WriteError(c, scim.BadRequest(scim.ErrorInvalidValue, policyErr.Error()))
+		return
+	}
+
+	switch err {
+	case auth.ErrEmailTaken:
+		scim.WriteError(c, scim.NewError(http.StatusConflict, scim.ErrorUniqueness, "userName is already in use"))
+	case auth.ErrInvalidLocale, auth.ErrInvalidTimezone:
+		scim.WriteError(c, scim.BadRequest(scim.ErrorInvalidValue, err.Error()))
+	case auth.ErrLastAdmin:
+		scim.WriteError(c, scim.NewError(http.StatusConflict, "", "The last admin cannot be deleted"))
+	default:
+		log.Printf("SCIM request failed: %v", err)
+		scim.WriteError(c, scim.NewError(http.StatusInternalServerError, "", message))
+	}
+}
@@ -0,0 +1,647 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"ishare-task-api/internal/models"
+	"ishare-task-api/internal/scim"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// scimGroupAttributes maps the filterable Group attributes onto the groups
+// table; members are matched through the group_members join table
+var scimGroupAttributes = scim.Attributes{
+	"id":                {Column: "CAST(id AS TEXT)", Kind: scim.KindCaseExactString},
+	"externalid":        {Column: "external_id", Kind: scim.KindCaseExactString},
+	"displayname":       {Column: "display_name", Kind: scim.KindString},
+	"members":           {Column: "CAST(user_id AS TEXT)", Kind: scim.KindCaseExactString, Within: "id IN (SELECT group_id FROM group_members WHERE %s)"},
+	"members.value":     {Column: "CAST(user_id AS TEXT)", Kind: scim.KindCaseExactString, Within: "id IN (SELECT group_id FROM group_members WHERE %s)"},
+	"meta.created":      {Column: "created_at", Kind: scim.KindDateTime},
+	"meta.lastmodified": {Column: "updated_at", Kind: scim.KindDateTime},
+}
+
+// groupState is the stored state of a group: its attributes and the IDs of
+// its members
+type groupState struct {
+	DisplayName string
+	ExternalID  string
+	Members     []uuid.UUID
+}
+
+// ListGroups lists groups matching a SCIM filter
+// @Summary List SCIM Groups
+// @Description Lists groups, optionally filtered (e.g. displayName eq "Engineering") and paginated with the 1-based startIndex and count (at most 200)
+// @Tags SCIM
+// @Produce json
+// @Security SCIMAuth
+// @Param filter query string false "SCIM filter" example(displayName eq "Engineering")
+// @Param startIndex query int false "1-based index of the first result" default(1)
+// @Param count query int false "Page size" default(100)
+// @Param excludedAttributes query string false "Set to members to omit the members"
+// @Success 200 {object} scim.ListResponse "Groups"
+// @Failure 400 {object} scim.ErrorResponse "Invalid filter"
+// @Failure 401 {object} scim.ErrorResponse "Missing or invalid SCIM bearer token"
+// @Router /scim/v2/Groups [get]
+func (h *SCIMHandler) ListGroups(c *gin.Context) {
+	startIndex, count, scimErr := scim.Pagination(c)
+	if scimErr != nil {
+		scim.WriteError(c, scimErr)
+		return
+	}
+
+	query := h.db.Model(&models.Group{})
+	condition, args, scimErr := filterCondition(c, scimGroupAttributes)
+	if scimErr != nil {
+		scim.WriteError(c, scimErr)
+		return
+	}
+	if condition != "" {
+		query = query.Where(condition, args...)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		respondSCIMError(c, err, "Failed to list groups")
+		return
+	}
+
+	var groups []models.Group
+	if count > 0 {
+		if err := query.Order("created_at, id").Offset(startIndex - 1).Limit(count).Find(&groups).Error; err != nil {
+			respondSCIMError(c, err, "Failed to list groups")
+			return
+		}
+	}
+
+	var members map[uuid.UUID][]scim.Member
+	if !scim.Excluded(c, "members") {
+		groupIDs := make([]uuid.UUID, len(groups))
+		for i := range groups {
+			groupIDs[i] = groups[i].ID
+		}
+		var err error
+		if members, err = h.groupMembers(groupIDs); err != nil {
+			respondSCIMError(c, err, "Failed to list groups")
+			return
+		}
+	}
+
+	resources := make([]interface{}, len(groups))
+	for i := range groups {
+		resources[i] = h.groupResource(&groups[i], members[groups[i].ID])
+	}
+
+	scim.Write(c, http.StatusOK, scim.NewListResponse(resources, total, startIndex))
+}
+
+// GetGroup returns a group
+// @Summary Get SCIM Group
+// @Description Returns a group with its members. Responds 304 when If-None-Match matches the current version.
+// @Tags SCIM
+// @Produce json
+// @Security SCIMAuth
+// @Param id path string true "Group ID" format(uuid)
+// @Param excludedAttributes query string false "Set to members to omit the members"
+// @Success 200 {object} scim.Group "Group"
+// @Success 304 {string} string "Not modified"
+// @Failure 401 {object} scim.ErrorResponse "Missing or invalid SCIM bearer token"
+// @Failure 404 {object} scim.ErrorResponse "Group not found"
+// @Router /scim/v2/Groups/{id} [get]
+func (h *SCIMHandler) GetGroup(c *gin.Context) {
+	group, ok := h.findGroup(c)
+	if !ok {
+		return
+	}
+
+	if scim.NotModified(c, scim.ETag(group.UpdatedAt)) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	h.writeGroup(c, http.StatusOK, group)
+}
+
+// CreateGroup creates a group
+// @Summary Create SCIM Group
+// @Description Creates a group. Display names are unique, ignoring case, and members must be existing users.
+// @Tags SCIM
+// @Accept json
+// @Produce json
+// @Security SCIMAuth
+// @Param group body scim.Group true "Group"
+// @Success 201 {object} scim.Group "Group created"
+// @Failure 400 {object} scim.ErrorResponse "Invalid group"
+// @Failure 401 {object} scim.ErrorResponse "Missing or invalid SCIM bearer token"
+// @Failure 409 {object} scim.ErrorResponse "displayName already in use"
+// @Router /scim/v2/Groups [post]
+func (h *SCIMHandler) CreateGroup(c *gin.Context) {
+	var resource scim.Group
+	if err := c.ShouldBindJSON(&resource); err != nil {
+		scim.WriteError(c, scim.BadRequest(scim.ErrorInvalidSyntax, "Invalid request body"))
+		return
+	}
+
+	state, scimErr := groupStateOf(&resource)
+	if scimErr != nil {
+		scim.WriteError(c, scimErr)
+		return
+	}
+
+	group := &models.Group{}
+	if err := h.saveGroup(group, state); err != nil {
+		respondSCIMError(c, err, "Failed to create group")
+		return
+	}
+
+	c.Header("Location", h.location("/scim/v2/Groups/"+group.ID.String()))
+	h.writeGroup(c, http.StatusCreated, group)
+}
+
+// ReplaceGroup replaces the attributes and members of a group
+// @Summary Replace SCIM Group
+// @Description Replaces the display name, external ID and members of a group. Honors If-Match.
+// @Tags SCIM
+// @Accept json
+// @Produce json
+// @Security SCIMAuth
+// @Param id path string true "Group ID" format(uuid)
+// @Param group body scim.Group true "Group"
+// @Success 200 {object} scim.Group "Group replaced"
+// @Failure 400 {object} scim.ErrorResponse "Invalid group"
+// @Failure 401 {object} scim.ErrorResponse "Missing or invalid SCIM bearer token"
+// @Failure 404 {object} scim.ErrorResponse "Group not found"
+// @Failure 409 {object} scim.ErrorResponse "displayName already in use"
+// @Failure 412 {object} scim.ErrorResponse "Version mismatch"
+// @Router /scim/v2/Groups/{id} [put]
+func (h *SCIMHandler) ReplaceGroup(c *gin.Context) {
+	group, ok := h.findGroup(c)
+	if !ok {
+		return
+	}
+	if scimErr := scim.CheckPreconditions(c, scim.ETag(group.UpdatedAt)); scimErr != nil {
+		scim.WriteError(c, scimErr)
+		return
+	}
+
+	var resource scim.Group
+	if err := c.ShouldBindJSON(&resource); err != nil {
+		scim.WriteError(c, scim.BadRequest(scim.ErrorInvalidSyntax, "Invalid request body"))
+		return
+	}
+	if resource.ID != "" && resource.ID != group.ID.String() {
+		scim.WriteError(c, scim.BadRequest(scim.ErrorMutability, "id cannot be changed"))
+		return
+	}
+
+	state, scimErr := groupStateOf(&resource)
+	if scimErr != nil {
+		scim.WriteError(c, scimErr)
+		return
+	}
+
+	if err := h.saveGroup(group, state); err != nil {
+		respondSCIMError(c, err, "Failed to update group")
+		return
+	}
+
+	h.writeGroup(c, http.StatusOK, group)
+}
+
+// PatchGroup changes attributes and members of a group
+// @Summary Patch SCIM Group
+// @Description Applies add, replace and remove operations, e.g. {"op": "add", "path": "members", "value": [{"value": "<user id>"}]} or {"op": "remove", "path": "members[value eq \"<user id>\"]"}. Honors If-Match.
+// @Tags SCIM
+// @Accept json
+// @Produce json
+// @Security SCIMAuth
+// @Param id path string true "Group ID" format(uuid)
+// @Param request body scim.PatchRequest true "Patch operations"
+// @Success 200 {object} scim.Group "Group updated"
+// @Failure 400 {object} scim.ErrorResponse "Invalid operation"
+// @Failure 401 {object} scim.ErrorResponse "Missing or invalid SCIM bearer token"
+// @Failure 404 {object} scim.ErrorResponse "Group not found"
+// @Failure 409 {object} scim.ErrorResponse "displayName already in use"
+// @Failure 412 {object} scim.ErrorResponse "Version mismatch"
+// @Router /scim/v2/Groups/{id} [patch]
+func (h *SCIMHandler) PatchGroup(c *gin.Context) {
+	group, ok := h.findGroup(c)
+	if !ok {
+		return
+	}
+	if scimErr := scim.CheckPreconditions(c, scim.ETag(group.UpdatedAt)); scimErr != nil {
+		scim.WriteError(c, scimErr)
+		return
+	}
+
+	var req scim.PatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		scim.WriteError(c, scim.BadRequest(scim.ErrorInvalidSyntax, "Invalid request body"))
+		return
+	}
+	if scimErr := req.Validate(); scimErr != nil {
+		scim.WriteError(c, scimErr)
+		return
+	}
+
+	var memberIDs []uuid.UUID
+	if err := h.db.Table("group_members").Where("group_id = ?", group.ID).Pluck("user_id", &memberIDs).Error; err != nil {
+		respondSCIMError(c, err, "Failed to update group")
+		return
+	}
+
+	state := &groupState{
+		DisplayName: group.DisplayName,
+		ExternalID:  group.ExternalID,
+		Members:     memberIDs,
+	}
+	for _, operation := range req.Operations {
+		if scimErr := applyGroupPatch(state, operation); scimErr != nil {
+			scim.WriteError(c, scimErr)
+			return
+		}
+	}
+
+	if err := h.saveGroup(group, state); err != nil {
+		respondSCIMError(c, err, "Failed to update group")
+		return
+	}
+
+	h.writeGroup(c, http.StatusOK, group)
+}
+
+// DeleteGroup deletes a group
+// @Summary Delete SCIM Group
+// @Description Deletes a group and its memberships; the member accounts are kept. Honors If-Match.
+// @Tags SCIM
+// @Security SCIMAuth
+// @Param id path string true "Group ID" format(uuid)
+// @Success 204 {string} string "Group deleted"
+// @Failure 401 {object} scim.ErrorResponse "Missing or invalid SCIM bearer token"
+// @Failure 404 {object} scim.ErrorResponse "Group not found"
+// @Failure 412 {object} scim.ErrorResponse "Version mismatch"
+// @Router /scim/v2/Groups/{id} [delete]
+func (h *SCIMHandler) DeleteGroup(c *gin.Context) {
+	group, ok := h.findGroup(c)
+	if !ok {
+		return
+	}
+	if scimErr := scim.CheckPreconditions(c, scim.ETag(group.UpdatedAt)); scimErr != nil {
+		scim.WriteError(c, scimErr)
+		return
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM group_members WHERE group_id = ?", group.ID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(group).Error
+	})
+	if err != nil {
+		respondSCIMError(c, err, "Failed to delete group")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// findGroup loads the group named by the id parameter, responding 404 if
+// there is none
+func (h *SCIMHandler) findGroup(c *gin.Context) (*models.Group, bool) {
+	notFound := scim.NewError(http.StatusNotFound, "", "Group not found")
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		scim.WriteError(c, notFound)
+		return nil, false
+	}
+
+	var group models.Group
+	if err := h.db.Where("id = ?", groupID).First(&group).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			scim.WriteError(c, notFound)
+		} else {
+			respondSCIMError(c, err, "Failed to retrieve group")
+		}
+		return nil, false
+	}
+
+	return &group, true
+}
+
+// saveGroup creates or updates a group with the given state, replacing its
+// members. The group is reloaded afterwards.
+func (h *SCIMHandler) saveGroup(group *models.Group, state *groupState) error {
+	return h.db.Transaction(func(tx *gorm.DB) error {
+		var taken int64
+		query := tx.Model(&models.Group{}).Where("LOWER(display_name) = LOWER(?)", state.DisplayName)
+		if group.ID != uuid.Nil {
+			query = query.Where("id <> ?", group.ID)
+		}
+		if err := query.Count(&taken).Error; err != nil {
+			return err
+		}
+		if taken > 0 {
+			return scim.NewError(http.StatusConflict, scim.ErrorUniqueness, "displayName is already in use")
+		}
+
+		if len(state.Members) > 0 {
+			var found int64
+			if err := tx.Model(&models.User{}).Where("id IN ?", state.Members).Count(&found).Error; err != nil {
+				return err
+			}
+			if found != int64(len(state.Members)) {
+				return scim.BadRequest(scim.ErrorInvalidValue, "members must reference existing users")
+			}
+		}
+
+		if group.ID == uuid.Nil {
+			group.DisplayName = state.DisplayName
+			group.ExternalID = state.ExternalID
+			if err := tx.Create(group).Error; err != nil {
+				return err
+			}
+		} else if err := tx.Model(group).Updates(map[string]interface{}{
+			"display_name": state.DisplayName,
+			"external_id":  state.ExternalID,
+		}).Error; err != nil {
+			return err
+		}
+
+		if len(state.Members) == 0 {
+			if err := tx.Exec("DELETE FROM group_members WHERE group_id = ?", group.ID).Error; err != nil {
+				return err
+			}
+		} else {
+			if err := tx.Exec("DELETE FROM group_members WHERE group_id = ? AND user_id NOT IN ?", group.ID, state.Members).Error; err != nil {
+				return err
+			}
+			for _, userID := range state.Members {
+				if err := tx.Exec("INSERT INTO group_members (group_id, user_id) VALUES (?, ?) ON CONFLICT DO NOTHING", group.ID, userID).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		return tx.Where("id = ?", group.ID).First(group).Error
+	})
+}
+
+// writeGroup sends a group with its members and version
+func (h *SCIMHandler) writeGroup(c *gin.Context, status int, group *models.Group) {
+	var members []scim.Member
+	if !scim.Excluded(c, "members") {
+		groupMembers, err := h.groupMembers([]uuid.UUID{group.ID})
+		if err != nil {
+			respondSCIMError(c, err, "Failed to retrieve group")
+			return
+		}
+		members = groupMembers[group.ID]
+	}
+
+	c.Header("ETag", scim.ETag(group.UpdatedAt))
+	scim.Write(c, status, h.groupResource(group, members))
+}
+
+// groupMembers returns references to the members of the given groups
+func (h *SCIMHandler) groupMembers(groupIDs []uuid.UUID) (map[uuid.UUID][]scim.Member, error) {
+	members := make(map[uuid.UUID][]scim.Member)
+	if len(groupIDs) == 0 {
+		return members, nil
+	}
+
+	var memberships []struct {
+		GroupID     uuid.UUID
+		UserID      uuid.UUID
+		Email       string
+		DisplayName string
+	}
+	if err := h.db.Table("group_members").
+		Select("group_members.group_id, users.id AS user_id, users.email, users.display_name").
+		Joins("JOIN users ON users.id = group_members.user_id").
+		Where("group_members.group_id IN ?", groupIDs).
+		Order("users.email").
+		Scan(&memberships).Error; err != nil {
+		return nil, err
+	}
+
+	for _, membership := range memberships {
+		display := membership.DisplayName
+		if display == "" {
+			display = membership.Email
+		}
+		members[membership.GroupID] = append(members[membership.GroupID], scim.Member{
+			Value:   membership.UserID.String(),
+			Display: display,
+			Ref:     h.location("/scim/v2/Users/" + membership.UserID.String()),
+			Type:    "User",
+		})
+	}
+	return members, nil
+}
+
+// groupResource converts a group to its SCIM representation
+func (h *SCIMHandler) groupResource(group *models.Group, members []scim.Member) scim.Group {
+	return scim.Group{
+		Schemas:     []string{scim.SchemaGroup},
+		ID:          group.ID.String(),
+		ExternalID:  group.ExternalID,
+		DisplayName: group.DisplayName,
+		Members:     members,
+		Meta: &scim.Meta{
+			ResourceType: "Group",
+			Created:      group.CreatedAt,
+			LastModified: group.UpdatedAt,
+			Location:     h.location("/scim/v2/Groups/" + group.ID.String()),
+			Version:      scim.ETag(group.UpdatedAt),
+		},
+	}
+}
+
+// groupStateOf validates a SCIM group and returns the state to store
+func groupStateOf(resource *scim.Group) (*groupState, *scim.Error) {
+	state := &groupState{
+		DisplayName: strings.TrimSpace(resource.DisplayName),
+		ExternalID:  strings.TrimSpace(resource.ExternalID),
+	}
+	if state.DisplayName == "" {
+		return nil, scim.BadRequest(scim.ErrorInvalidValue, "displayName is required")
+	}
+	if len(state.DisplayName) > 255 {
+		return nil, scim.BadRequest(scim.ErrorInvalidValue, "displayName must be at most 255 characters")
+	}
+
+	userIDs, scimErr := memberIDs(resource.Members)
+	if scimErr != nil {
+		return nil, scimErr
+	}
+	state.Members = addMembers(nil, userIDs)
+	return state, nil
+}
+
+// applyGroupPatch applies a PATCH operation to the state of a group
+func applyGroupPatch(state *groupState, operation scim.PatchOperation) *scim.Error {
+	op := operation.Operation()
+
+	// Without a path the value holds the attributes to add or replace
+	if operation.Path == "" {
+		var values map[string]json.RawMessage
+		if err := json.Unmarshal(operation.Value, &values); err != nil {
+			return scim.BadRequest(scim.ErrorInvalidSyntax, "the value of an operation without path must be an object")
+		}
+		for name, value := range values {
+			if scim.IsExtensionAttribute(name, scim.SchemaGroup) {
+				continue
+			}
+			path, scimErr := scim.ParsePath(name)
+			if scimErr != nil {
+				return scimErr
+			}
+			if scimErr := setGroupAttribute(state, op, path, value); scimErr != nil {
+				return scimErr
+			}
+		}
+		return nil
+	}
+
+	if scim.IsExtensionAttribute(operation.Path, scim.SchemaGroup) {
+		return nil
+	}
+	path, scimErr := scim.ParsePath(operation.Path)
+	if scimErr != nil {
+		return scimErr
+	}
+	if op == "remove" {
+		return removeGroupAttribute(state, path, operation.Value)
+	}
+	return setGroupAttribute(state, op, path, operation.Value)
+}
+
+// setGroupAttribute adds or replaces an attribute of a group. Adding
+// members keeps the existing ones; replacing them does not.
+func setGroupAttribute(state *groupState, op string, path *scim.Path, value json.RawMessage) *scim.Error {
+	var scimErr *scim.Error
+	switch path.Attribute {
+	case "displayname":
+		var displayName string
+		if displayName, scimErr = scim.StringValue(value); scimErr != nil {
+			return scimErr
+		}
+		displayName = strings.TrimSpace(displayName)
+		if displayName == "" || len(displayName) > 255 {
+			return scim.BadRequest(scim.ErrorInvalidValue, "displayName must be 1 to 255 characters")
+		}
+		state.DisplayName = displayName
+	case "externalid":
+		state.ExternalID, scimErr = scim.StringValue(value)
+		state.ExternalID = strings.TrimSpace(state.ExternalID)
+	case "members":
+		if path.Filter != nil || path.SubAttribute != "" {
+			return scim.BadRequest(scim.ErrorInvalidPath, "members can only be added or replaced as a whole")
+		}
+		var members []scim.Member
+		if err := json.Unmarshal(value, &members); err != nil {
+			return scim.BadRequest(scim.ErrorInvalidValue, "members must be an array of {\"value\": \"<user id>\"}")
+		}
+		userIDs, scimErr := memberIDs(members)
+		if scimErr != nil {
+			return scimErr
+		}
+		if op == "replace" {
+			state.Members = nil
+		}
+		state.Members = addMembers(state.Members, userIDs)
+	case "id", "meta", "schemas":
+		return scim.BadRequest(scim.ErrorMutability, path.Attribute+" cannot be changed")
+	default:
+		return scim.BadRequest(scim.ErrorInvalidPath, "unsupported attribute "+path.Attribute)
+	}
+	return scimErr
+}
+
+// removeGroupAttribute removes an attribute of a group. Members are
+// removed all at once, by a filter such as members[value eq "<user id>"],
+// or by a value listing them.
+func removeGroupAttribute(state *groupState, path *scim.Path, value json.RawMessage) *scim.Error {
+	switch path.Attribute {
+	case "externalid":
+		state.ExternalID = ""
+	case "members":
+		var values []string
+		switch {
+		case path.Filter != nil:
+			filtered, ok := scim.EqualValues(path.Filter, "value")
+			if !ok || path.SubAttribute != "" {
+				return scim.BadRequest(scim.ErrorInvalidFilter, "members can only be selected by value eq \"<user id>\"")
+			}
+			values = filtered
+		case len(value) > 0:
+			var members []scim.Member
+			if err := json.Unmarshal(value, &members); err != nil {
+				return scim.BadRequest(scim.ErrorInvalidValue, "members must be an array of {\"value\": \"<user id>\"}")
+			}
+			for _, member := range members {
+				values = append(values, member.Value)
+			}
+		default:
+			state.Members = nil
+			return nil
+		}
+
+		removed := make(map[uuid.UUID]bool, len(values))
+		for _, value := range values {
+			// Unknown IDs cannot be members, so there is nothing to remove
+			if userID, err := uuid.Parse(value); err == nil {
+				removed[userID] = true
+			}
+		}
+		kept := state.Members[:0]
+		for _, userID := range state.Members {
+			if !removed[userID] {
+				kept = append(kept, userID)
+			}
+		}
+		state.Members = kept
+	case "displayname", "id", "meta", "schemas":
+		return scim.BadRequest(scim.ErrorMutability, path.Attribute+" cannot be removed")
+	default:
+		return scim.BadRequest(scim.ErrorInvalidPath, "unsupported attribute "+path.Attribute)
+	}
+	return nil
+}
+
+// memberIDs parses the user IDs of group members
+func memberIDs(members []scim.Member) ([]uuid.UUID, *scim.Error) {
+	userIDs := make([]uuid.UUID, 0, len(members))
+	for _, member := range members {
+		if member.Type != "" && !strings.EqualFold(member.Type, "User") {
+			return nil, scim.BadRequest(scim.ErrorInvalidValue, "only users can be group members")
+		}
+		userID, err := uuid.Parse(member.Value)
+		if err != nil {
+			return nil, scim.BadRequest(scim.ErrorInvalidValue, "member "+member.Value+" is not a user ID")
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, nil
+}
+
+// addMembers appends the user IDs that are not members yet
+func addMembers(members []uuid.UUID, userIDs []uuid.UUID) []uuid.UUID {
+	present := make(map[uuid.UUID]bool, len(members))
+	for _, userID := range members {
+		present[userID] = true
+	}
+	for _, userID := range userIDs {
+		if !present[userID] {
+			present[userID] = true
+			members = append(members, userID)
+		}
+	}
+	return members
+}
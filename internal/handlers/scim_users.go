@@ -0,0 +1,544 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/mail"
+	"strings"
+
+	"ishare-task-api/internal/auth"
+	"ishare-task-api/internal/models"
+	"ishare-task-api/internal/scim"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// scimUserAttributes maps the filterable User attributes onto the users
+// table. The account email is the userName and the only (work) email.
+var scimUserAttributes = scim.Attributes{
+	"id":                {Column: "CAST(id AS TEXT)", Kind: scim.KindCaseExactString},
+	"externalid":        {Column: "external_id", Kind: scim.KindCaseExactString},
+	"username":          {Column: "email", Kind: scim.KindString},
+	"emails":            {Column: "email", Kind: scim.KindString},
+	"emails.value":      {Column: "email", Kind: scim.KindString},
+	"emails.type":       {Column: "'work'", Kind: scim.KindString},
+	"emails.primary":    {Column: "TRUE", Kind: scim.KindBoolean},
+	"displayname":       {Column: "display_name", Kind: scim.KindString},
+	"name.formatted":    {Column: "display_name", Kind: scim.KindString},
+	"active":            {Column: "disabled_at IS NULL", Kind: scim.KindBoolean},
+	"locale":            {Column: "locale", Kind: scim.KindString},
+	"timezone":          {Column: "timezone", Kind: scim.KindString},
+	"meta.created":      {Column: "created_at", Kind: scim.KindDateTime},
+	"meta.lastmodified": {Column: "updated_at", Kind: scim.KindDateTime},
+}
+
+// ListUsers lists users matching a SCIM filter
+// @Summary List SCIM Users
+// @Description Lists users, optionally filtered (e.g. userName eq "jane@example.com") and paginated with the 1-based startIndex and count (at most 200)
+// @Tags SCIM
+// @Produce json
+// @Security SCIMAuth
+// @Param filter query string false "SCIM filter" example(userName eq "jane@example.com")
+// @Param startIndex query int false "1-based index of the first result" default(1)
+// @Param count query int false "Page size" default(100)
+// @Param excludedAttributes query string false "Set to groups to omit group memberships"
+// @Success 200 {object} scim.ListResponse "Users"
+// @Failure 400 {object} scim.ErrorResponse "Invalid filter"
+// @Failure 401 {object} scim.ErrorResponse "Missing or invalid SCIM bearer token"
+// @Router /scim/v2/Users [get]
+func (h *SCIMHandler) ListUsers(c *gin.Context) {
+	startIndex, count, scimErr := scim.Pagination(c)
+	if scimErr != nil {
+		scim.WriteError(c, scimErr)
+		return
+	}
+
+	query := h.db.Model(&models.User{})
+	condition, args, scimErr := filterCondition(c, scimUserAttributes)
+	if scimErr != nil {
+		scim.WriteError(c, scimErr)
+		return
+	}
+	if condition != "" {
+		query = query.Where(condition, args...)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		respondSCIMError(c, err, "Failed to list users")
+		return
+	}
+
+	var users []models.User
+	if count > 0 {
+		if err := query.Order("created_at, id").Offset(startIndex - 1).Limit(count).Find(&users).Error; err != nil {
+			respondSCIMError(c, err, "Failed to list users")
+			return
+		}
+	}
+
+	var groups map[uuid.UUID][]scim.GroupRef
+	if !scim.Excluded(c, "groups") {
+		userIDs := make([]uuid.UUID, len(users))
+		for i := range users {
+			userIDs[i] = users[i].ID
+		}
+		var err error
+		if groups, err = h.userGroups(userIDs); err != nil {
+			respondSCIMError(c, err, "Failed to list users")
+			return
+		}
+	}
+
+	resources := make([]interface{}, len(users))
+	for i := range users {
+		resources[i] = h.userResource(&users[i], groups[users[i].ID])
+	}
+
+	scim.Write(c, http.StatusOK, scim.NewListResponse(resources, total, startIndex))
+}
+
+// GetUser returns a user
+// @Summary Get SCIM User
+// @Description Returns a user. Responds 304 when If-None-Match matches the current version.
+// @Tags SCIM
+// @Produce json
+// @Security SCIMAuth
+// @Param id path string true "User ID" format(uuid)
+// @Success 200 {object} scim.User "User"
+// @Success 304 {string} string "Not modified"
+// @Failure 401 {object} scim.ErrorResponse "Missing or invalid SCIM bearer token"
+// @Failure 404 {object} scim.ErrorResponse "User not found"
+// @Router /scim/v2/Users/{id} [get]
+func (h *SCIMHandler) GetUser(c *gin.Context) {
+	user, ok := h.findUser(c)
+	if !ok {
+		return
+	}
+
+	etag := scim.ETag(user.UpdatedAt)
+	if scim.NotModified(c, etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	h.writeUser(c, http.StatusOK, user)
+}
+
+// CreateUser provisions a user
+// @Summary Create SCIM User
+// @Description Creates a verified account with the userName as email address and the member role. Without a password the user sets one through the password reset flow.
+// @Tags SCIM
+// @Accept json
+// @Produce json
+// @Security SCIMAuth
+// @Param user body scim.User true "User"
+// @Success 201 {object} scim.User "User created"
+// @Failure 400 {object} scim.ErrorResponse "Invalid user"
+// @Failure 401 {object} scim.ErrorResponse "Missing or invalid SCIM bearer token"
+// @Failure 409 {object} scim.ErrorResponse "userName already in use"
+// @Router /scim/v2/Users [post]
+func (h *SCIMHandler) CreateUser(c *gin.Context) {
+	var resource scim.User
+	if err := c.ShouldBindJSON(&resource); err != nil {
+		scim.WriteError(c, scim.BadRequest(scim.ErrorInvalidSyntax, "Invalid request body"))
+		return
+	}
+
+	attrs, scimErr := userAttributes(&resource)
+	if scimErr != nil {
+		scim.WriteError(c, scimErr)
+		return
+	}
+
+	user, err := h.oauth.ProvisionUser(attrs, c.ClientIP())
+	if err != nil {
+		respondSCIMError(c, err, "Failed to create user")
+		return
+	}
+
+	c.Header("Location", h.location("/scim/v2/Users/"+user.ID.String()))
+	h.writeUser(c, http.StatusCreated, user)
+}
+
+// ReplaceUser replaces the attributes of a user
+// @Summary Replace SCIM User
+// @Description Replaces all attributes of a user. Setting active to false disables the account and revokes its tokens and sessions. Honors If-Match.
+// @Tags SCIM
+// @Accept json
+// @Produce json
+// @Security SCIMAuth
+// @Param id path string true "User ID" format(uuid)
+// @Param user body scim.User true "User"
+// @Success 200 {object} scim.User "User replaced"
+// @Failure 400 {object} scim.ErrorResponse "Invalid user"
+// @Failure 401 {object} scim.ErrorResponse "Missing or invalid SCIM bearer token"
+// @Failure 404 {object} scim.ErrorResponse "User not found"
+// @Failure 409 {object} scim.ErrorResponse "userName already in use"
+// @Failure 412 {object} scim.ErrorResponse "Version mismatch"
+// @Router /scim/v2/Users/{id} [put]
+func (h *SCIMHandler) ReplaceUser(c *gin.Context) {
+	user, ok := h.findUser(c)
+	if !ok {
+		return
+	}
+	if scimErr := scim.CheckPreconditions(c, scim.ETag(user.UpdatedAt)); scimErr != nil {
+		scim.WriteError(c, scimErr)
+		return
+	}
+
+	var resource scim.User
+	if err := c.ShouldBindJSON(&resource); err != nil {
+		scim.WriteError(c, scim.BadRequest(scim.ErrorInvalidSyntax, "Invalid request body"))
+		return
+	}
+	if resource.ID != "" && resource.ID != user.ID.String() {
+		scim.WriteError(c, scim.BadRequest(scim.ErrorMutability, "id cannot be changed"))
+		return
+	}
+
+	h.updateUser(c, user, &resource)
+}
+
+// PatchUser changes attributes of a user
+// @Summary Patch SCIM User
+// @Description Applies add, replace and remove operations, e.g. {"op": "replace", "path": "active", "value": false}. Honors If-Match.
+// @Tags SCIM
+// @Accept json
+// @Produce json
+// @Security SCIMAuth
+// @Param id path string true "User ID" format(uuid)
+// @Param request body scim.PatchRequest true "Patch operations"
+// @Success 200 {object} scim.User "User updated"
+// @Failure 400 {object} scim.ErrorResponse "Invalid operation"
+// @Failure 401 {object} scim.ErrorResponse "Missing or invalid SCIM bearer token"
+// @Failure 404 {object} scim.ErrorResponse "User not found"
+// @Failure 409 {object} scim.ErrorResponse "userName already in use"
+// @Failure 412 {object} scim.ErrorResponse "Version mismatch"
+// @Router /scim/v2/Users/{id} [patch]
+func (h *SCIMHandler) PatchUser(c *gin.Context) {
+	user, ok := h.findUser(c)
+	if !ok {
+		return
+	}
+	if scimErr := scim.CheckPreconditions(c, scim.ETag(user.UpdatedAt)); scimErr != nil {
+		scim.WriteError(c, scimErr)
+		return
+	}
+
+	var req scim.PatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		scim.WriteError(c, scim.BadRequest(scim.ErrorInvalidSyntax, "Invalid request body"))
+		return
+	}
+	if scimErr := req.Validate(); scimErr != nil {
+		scim.WriteError(c, scimErr)
+		return
+	}
+
+	resource := h.userResource(user, nil)
+	for _, operation := range req.Operations {
+		if scimErr := applyUserPatch(&resource, operation); scimErr != nil {
+			scim.WriteError(c, scimErr)
+			return
+		}
+	}
+
+	h.updateUser(c, user, &resource)
+}
+
+// DeleteUser deletes a user
+// @Summary Delete SCIM User
+// @Description Permanently deletes the account with its tokens, sessions and group memberships. The last admin cannot be deleted. Honors If-Match.
+// @Tags SCIM
+// @Security SCIMAuth
+// @Param id path string true "User ID" format(uuid)
+// @Success 204 {string} string "User deleted"
+// @Failure 401 {object} scim.ErrorResponse "Missing or invalid SCIM bearer token"
+// @Failure 404 {object} scim.ErrorResponse "User not found"
+// @Failure 409 {object} scim.ErrorResponse "Last admin"
+// @Failure 412 {object} scim.ErrorResponse "Version mismatch"
+// @Router /scim/v2/Users/{id} [delete]
+func (h *SCIMHandler) DeleteUser(c *gin.Context) {
+	user, ok := h.findUser(c)
+	if !ok {
+		return
+	}
+	if scimErr := scim.CheckPreconditions(c, scim.ETag(user.UpdatedAt)); scimErr != nil {
+		scim.WriteError(c, scimErr)
+		return
+	}
+
+	if err := h.oauth.DeprovisionUser(user, c.ClientIP()); err != nil {
+		respondSCIMError(c, err, "Failed to delete user")
+		return
+	}
+	h.users.Invalidate(user.ID)
+
+	c.Status(http.StatusNoContent)
+}
+
+// updateUser stores the attributes of a replaced or patched resource
+func (h *SCIMHandler) updateUser(c *gin.Context, user *models.User, resource *scim.User) {
+	attrs, scimErr := userAttributes(resource)
+	if scimErr != nil {
+		scim.WriteError(c, scimErr)
+		return
+	}
+
+	if err := h.oauth.UpdateProvisionedUser(user, attrs, c.ClientIP()); err != nil {
+		respondSCIMError(c, err, "Failed to update user")
+		return
+	}
+	h.users.Invalidate(user.ID)
+
+	h.writeUser(c, http.StatusOK, user)
+}
+
+// findUser loads the user named by the id parameter, responding 404 if
+// there is none
+func (h *SCIMHandler) findUser(c *gin.Context) (*models.User, bool) {
+	notFound := scim.NewError(http.StatusNotFound, "", "User not found")
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		scim.WriteError(c, notFound)
+		return nil, false
+	}
+
+	user, err := h.oauth.FindUser(userID)
+	if err != nil {
+		if err == auth.ErrUserNotFound {
+			scim.WriteError(c, notFound)
+		} else {
+			respondSCIMError(c, err, "Failed to retrieve user")
+		}
+		return nil, false
+	}
+
+	return user, true
+}
+
+// writeUser sends a user with its groups and version
+func (h *SCIMHandler) writeUser(c *gin.Context, status int, user *models.User) {
+	groups, err := h.userGroups([]uuid.UUID{user.ID})
+	if err != nil {
+		respondSCIMError(c, err, "Failed to retrieve user")
+		return
+	}
+
+	c.Header("ETag", scim.ETag(user.UpdatedAt))
+	scim.Write(c, status, h.userResource(user, groups[user.ID]))
+}
+
+// userGroups returns references to the groups of the given users
+func (h *SCIMHandler) userGroups(userIDs []uuid.UUID) (map[uuid.UUID][]scim.GroupRef, error) {
+	groups := make(map[uuid.UUID][]scim.GroupRef)
+	if len(userIDs) == 0 {
+		return groups, nil
+	}
+
+	var memberships []struct {
+		UserID      uuid.UUID
+		GroupID     uuid.UUID
+		DisplayName string
+	}
+	if err := h.db.Table("group_members").
+		Select("group_members.user_id, groups.id AS group_id, groups.display_name").
+		Joins("JOIN groups ON groups.id = group_members.group_id").
+		Where("group_members.user_id IN ?", userIDs).
+		Order("groups.display_name").
+		Scan(&memberships).Error; err != nil {
+		return nil, err
+	}
+
+	for _, membership := range memberships {
+		groups[membership.UserID] = append(groups[membership.UserID], scim.GroupRef{
+			Value:   membership.GroupID.String(),
+			Display: membership.DisplayName,
+			Ref:     h.location("/scim/v2/Groups/" + membership.GroupID.String()),
+		})
+	}
+	return groups, nil
+}
+
+// userResource converts a user to its SCIM representation
+func (h *SCIMHandler) userResource(user *models.User, groups []scim.GroupRef) scim.User {
+	active := !user.Disabled()
+
+	resource := scim.User{
+		Schemas:     []string{scim.SchemaUser},
+		ID:          user.ID.String(),
+		ExternalID:  user.ExternalID,
+		UserName:    user.Email,
+		DisplayName: user.DisplayName,
+		Emails:      []scim.Email{{Value: user.Email, Type: "work", Primary: true}},
+		Active:      &active,
+		Locale:      user.Locale,
+		Timezone:    user.Timezone,
+		Groups:      groups,
+		Meta: &scim.Meta{
+			ResourceType: "User",
+			Created:      user.CreatedAt,
+			LastModified: user.UpdatedAt,
+			Location:     h.location("/scim/v2/Users/" + user.ID.String()),
+			Version:      scim.ETag(user.UpdatedAt),
+		},
+	}
+	if user.DisplayName != "" {
+		resource.Name = &scim.Name{Formatted: user.DisplayName}
+	}
+	return resource
+}
+
+// userAttributes validates a SCIM user and returns the attributes to store
+func userAttributes(resource *scim.User) (auth.UserAttributes, *scim.Error) {
+	userName := strings.TrimSpace(resource.UserName)
+	if userName == "" {
+		userName = strings.TrimSpace(scim.PrimaryEmail(resource.Emails))
+	}
+	if userName == "" {
+		return auth.UserAttributes{}, scim.BadRequest(scim.ErrorInvalidValue, "userName is required")
+	}
+	if address, err := mail.ParseAddress(userName); err != nil || address.Address != userName {
+		return auth.UserAttributes{}, scim.BadRequest(scim.ErrorInvalidValue, "userName must be an email address")
+	}
+
+	displayName := resource.DisplayName
+	if displayName == "" {
+		displayName = resource.Name.FullName()
+	}
+
+	active := true
+	if resource.Active != nil {
+		active = *resource.Active
+	}
+
+	return auth.UserAttributes{
+		Email:       userName,
+		DisplayName: displayName,
+		Locale:      resource.Locale,
+		Timezone:    resource.Timezone,
+		ExternalID:  resource.ExternalID,
+		Active:      active,
+		Password:    resource.Password,
+	}, nil
+}
+
+// applyUserPatch applies a PATCH operation to a user resource
+func applyUserPatch(resource *scim.User, operation scim.PatchOperation) *scim.Error {
+	// Without a path the value holds the attributes to add or replace
+	if operation.Path == "" {
+		var values map[string]json.RawMessage
+		if err := json.Unmarshal(operation.Value, &values); err != nil {
+			return scim.BadRequest(scim.ErrorInvalidSyntax, "the value of an operation without path must be an object")
+		}
+		for name, value := range values {
+			if scim.IsExtensionAttribute(name, scim.SchemaUser) {
+				continue
+			}
+			path, scimErr := scim.ParsePath(name)
+			if scimErr != nil {
+				return scimErr
+			}
+			if scimErr := setUserAttribute(resource, path, value); scimErr != nil {
+				return scimErr
+			}
+		}
+		return nil
+	}
+
+	if scim.IsExtensionAttribute(operation.Path, scim.SchemaUser) {
+		return nil
+	}
+	path, scimErr := scim.ParsePath(operation.Path)
+	if scimErr != nil {
+		return scimErr
+	}
+	if operation.Operation() == "remove" {
+		return removeUserAttribute(resource, path)
+	}
+	return setUserAttribute(resource, path, operation.Value)
+}
+
+// setUserAttribute adds or replaces an attribute of a user resource
+func setUserAttribute(resource *scim.User, path *scim.Path, value json.RawMessage) *scim.Error {
+	var scimErr *scim.Error
+	switch path.Attribute {
+	case "username":
+		resource.UserName, scimErr = scim.StringValue(value)
+	case "displayname":
+		resource.DisplayName, scimErr = scim.StringValue(value)
+	case "name":
+		previous := resource.Name.FullName()
+		if resource.Name == nil {
+			resource.Name = &scim.Name{}
+		}
+		switch path.SubAttribute {
+		case "":
+			resource.Name = &scim.Name{}
+			if err := json.Unmarshal(value, resource.Name); err != nil {
+				return scim.BadRequest(scim.ErrorInvalidValue, "name must be an object")
+			}
+		case "formatted":
+			resource.Name.Formatted, scimErr = scim.StringValue(value)
+		case "givenname":
+			resource.Name.GivenName, scimErr = scim.StringValue(value)
+			resource.Name.Formatted = ""
+		case "familyname":
+			resource.Name.FamilyName, scimErr = scim.StringValue(value)
+			resource.Name.Formatted = ""
+		default:
+			return scim.BadRequest(scim.ErrorInvalidPath, "unsupported attribute name."+path.SubAttribute)
+		}
+		// The display name follows the name unless it was set separately
+		if resource.DisplayName == previous {
+			resource.DisplayName = ""
+		}
+	case "emails":
+		// The account email is the userName
+	case "active":
+		var active bool
+		active, scimErr = scim.BoolValue(value)
+		resource.Active = &active
+	case "externalid":
+		resource.ExternalID, scimErr = scim.StringValue(value)
+	case "locale":
+		resource.Locale, scimErr = scim.StringValue(value)
+	case "timezone":
+		resource.Timezone, scimErr = scim.StringValue(value)
+	case "password":
+		resource.Password, scimErr = scim.StringValue(value)
+	case "id", "meta", "groups", "schemas":
+		return scim.BadRequest(scim.ErrorMutability, path.Attribute+" cannot be changed")
+	default:
+		return scim.BadRequest(scim.ErrorInvalidPath, "unsupported attribute "+path.Attribute)
+	}
+	return scimErr
+}
+
+// removeUserAttribute clears an optional attribute of a user resource
+func removeUserAttribute(resource *scim.User, path *scim.Path) *scim.Error {
+	switch path.Attribute {
+	case "displayname":
+		resource.DisplayName = ""
+		resource.Name = nil
+	case "name":
+		resource.Name = nil
+		resource.DisplayName = ""
+	case "emails":
+		// The account email is the userName
+	case "externalid":
+		resource.ExternalID = ""
+	case "locale":
+		resource.Locale = ""
+	case "timezone":
+		resource.Timezone = ""
+	case "username", "active", "id", "meta", "groups", "schemas", "password":
+		return scim.BadRequest(scim.ErrorMutability, path.Attribute+" cannot be removed")
+	default:
+		return scim.BadRequest(scim.ErrorInvalidPath, "unsupported attribute "+path.Attribute)
+	}
+	return nil
+}
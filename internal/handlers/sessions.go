@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"net/http"
+
+	"ishare-task-api/internal/auth"
+	"ishare-task-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// SessionHandler handles session management requests of the current user
+type SessionHandler struct {
+	oauth *auth.OAuthManager
+}
+
+// NewSessionHandler creates a new session handler
+func NewSessionHandler(oauth *auth.OAuthManager) *SessionHandler {
+	return &SessionHandler{
+		oauth: oauth,
+	}
+}
+
+// ListSessions lists the active sessions of the current user
+// @Summary List Sessions
+// @Description Lists the active sessions (access tokens) of the authenticated user
+// @Tags Sessions
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.SessionsResponse "Sessions retrieved successfully"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Request made with a personal access token"
+// @Router /me/sessions [get]
+func (h *SessionHandler) ListSessions(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+		})
+		return
+	}
+
+	sessions, err := h.oauth.ListSessions(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve sessions",
+		})
+		return
+	}
+
+	currentID, _ := auth.GetTokenIDFromContext(c)
+
+	// Convert to response format
+	sessionResponses := make([]models.SessionResponse, len(sessions))
+	for i, session := range sessions {
+		sessionResponses[i] = models.SessionResponse{
+			ID:         session.ID,
+			ClientID:   session.ClientID,
+			IPAddress:  session.IPAddress,
+			UserAgent:  session.UserAgent,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    session.JTI == currentID,
+		}
+	}
+
+	c.JSON(http.StatusOK, models.SessionsResponse{
+		Sessions: sessionResponses,
+		Total:    int64(len(sessionResponses)),
+	})
+}
+
+// RevokeSession revokes a single session of the current user
+// @Summary Revoke Session
+// @Description Signs the authenticated user out of a single session
+// @Tags Sessions
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Session ID" format(uuid)
+// @Success 200 {object} map[string]interface{} "Session revoked successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Request made with a personal access token"
+// @Failure 404 {object} map[string]interface{} "Session not found"
+// @Router /me/sessions/{id} [delete]
+func (h *SessionHandler) RevokeSession(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+		})
+		return
+	}
+
+	// Parse UUID
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid session ID format",
+		})
+		return
+	}
+
+	if err := h.oauth.RevokeSession(user.ID, sessionID); err != nil {
+		if err == auth.ErrSessionNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Session not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to revoke session",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Session revoked successfully",
+	})
+}
+
+// RevokeAllSessions signs the current user out everywhere
+// @Summary Sign Out Everywhere
+// @Description Revokes all sessions of the authenticated user, optionally keeping the current one
+// @Tags Sessions
+// @Produce json
+// @Security BearerAuth
+// @Param keep_current query bool false "Keep the session making this request" example(true)
+// @Success 200 {object} map[string]interface{} "Sessions revoked successfully"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Request made with a personal access token"
+// @Router /me/sessions [delete]
+func (h *SessionHandler) RevokeAllSessions(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+		})
+		return
+	}
+
+	exceptID := ""
+	if c.Query("keep_current") == "true" {
+		exceptID, _ = auth.GetTokenIDFromContext(c)
+	}
+
+	revoked, err := h.oauth.RevokeAllSessions(user.ID, exceptID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to revoke sessions",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Sessions revoked successfully",
+		"revoked": revoked,
+	})
+}
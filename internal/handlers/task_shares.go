@@ -0,0 +1,457 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"ishare-task-api/internal/auth"
+	"ishare-task-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ListShares lists the users a task is shared with
+// @Summary List Task Shares
+// @Description Lists the users a task is shared with and their roles; requires at least the viewer role on the task
+// @Tags Tasks
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Task ID" format(uuid)
+// @Success 200 {object} models.TaskSharesResponse "Shares"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Insufficient permissions or task role"
+// @Failure 404 {object} map[string]interface{} "Task not found"
+// @Router /tasks/{id}/shares [get]
+func (h *TaskHandler) ListShares(c *gin.Context) {
+	task, _, ok := h.authz.Authorize(c, models.TaskRoleViewer)
+	if !ok {
+		return
+	}
+
+	var shares []models.TaskShare
+	if err := h.authz.DB(c).Preload("User").
+		Where("task_id = ?", task.ID).
+		Order("created_at").
+		Find(&shares).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve shares",
+		})
+		return
+	}
+
+	shareResponses := make([]models.TaskShareResponse, len(shares))
+	for i := range shares {
+		shareResponses[i] = taskShareResponse(&shares[i], shares[i].User)
+	}
+
+	c.JSON(http.StatusOK, models.TaskSharesResponse{
+		Shares: shareResponses,
+		Total:  int64(len(shareResponses)),
+	})
+}
+
+// ShareTask shares a task with a member of its organization
+// @Summary Share Task
+// @Description Grants a member of the task's organization the viewer, editor or owner role on the task, replacing any role they had; requires the owner role on the task
+// @Tags Tasks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Task ID" format(uuid)
+// @Param request body models.ShareTaskRequest true "Member email and role"
+// @Success 200 {object} models.TaskShareResponse "Task shared"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Insufficient permissions or task role"
+// @Failure 404 {object} map[string]interface{} "Task or member not found"
+// @Failure 409 {object} map[string]interface{} "User owns the task"
+// @Router /tasks/{id}/shares [post]
+func (h *TaskHandler) ShareTask(c *gin.Context) {
+	task, _, ok := h.authz.Authorize(c, models.TaskRoleOwner)
+	if !ok {
+		return
+	}
+
+	user, _ := auth.GetUserFromContext(c)
+
+	var req models.ShareTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "An email address and a role of viewer, editor or owner are required",
+		})
+		return
+	}
+
+	db := h.authz.DB(c)
+
+	var target models.User
+	if err := db.Joins("JOIN organization_members ON organization_members.user_id = users.id").
+		Where("organization_members.org_id = ? AND LOWER(users.email) = LOWER(?)", task.OrgID, strings.TrimSpace(req.Email)).
+		First(&target).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "User is not a member of the organization",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to share task",
+		})
+		return
+	}
+
+	if target.ID == task.OwnerID {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "The user owns the task",
+		})
+		return
+	}
+
+	share, err := saveTaskShare(db, task, target.ID, req.Role, &user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to share task",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, taskShareResponse(share, &target))
+}
+
+// UnshareTask removes a user's access to a task
+// @Summary Unshare Task
+// @Description Removes the share of a user. Owners can remove any share; other users can remove their own.
+// @Tags Tasks
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Task ID" format(uuid)
+// @Param user_id path string true "User ID" format(uuid)
+// @Success 200 {object} map[string]interface{} "Share removed"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Insufficient permissions or task role"
+// @Failure 404 {object} map[string]interface{} "Task or share not found"
+// @Router /tasks/{id}/shares/{user_id} [delete]
+func (h *TaskHandler) UnshareTask(c *gin.Context) {
+	task, role, ok := h.authz.Authorize(c, models.TaskRoleViewer)
+	if !ok {
+		return
+	}
+
+	user, _ := auth.GetUserFromContext(c)
+
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid user ID format",
+		})
+		return
+	}
+
+	if role != models.TaskRoleOwner && userID != user.ID {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":         "Insufficient access to the task",
+			"role":          role,
+			"required_role": models.TaskRoleOwner,
+		})
+		return
+	}
+
+	result := h.authz.DB(c).Where("task_id = ? AND user_id = ?", task.ID, userID).Delete(&models.TaskShare{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to remove share",
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Share not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Share removed",
+	})
+}
+
+// ListLinks lists the active share-by-link tokens of a task
+// @Summary List Task Links
+// @Description Lists the share-by-link tokens of a task that have not expired or been revoked; requires the owner role on the task
+// @Tags Tasks
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Task ID" format(uuid)
+// @Success 200 {object} models.TaskLinksResponse "Links"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Insufficient permissions or task role"
+// @Failure 404 {object} map[string]interface{} "Task not found"
+// @Router /tasks/{id}/links [get]
+func (h *TaskHandler) ListLinks(c *gin.Context) {
+	task, _, ok := h.authz.Authorize(c, models.TaskRoleOwner)
+	if !ok {
+		return
+	}
+
+	var links []models.TaskLink
+	if err := h.authz.DB(c).
+		Where("task_id = ? AND expires_at > ? AND revoked_at IS NULL", task.ID, time.Now()).
+		Order("created_at DESC").
+		Find(&links).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve links",
+		})
+		return
+	}
+
+	linkResponses := make([]models.TaskLinkResponse, len(links))
+	for i := range links {
+		linkResponses[i] = taskLinkResponse(&links[i])
+	}
+
+	c.JSON(http.StatusOK, models.TaskLinksResponse{
+		Links: linkResponses,
+		Total: int64(len(linkResponses)),
+	})
+}
+
+// CreateLink creates a share-by-link token for a task
+// @Summary Create Task Link
+// @Description Creates a link token that grants the viewer or editor role on the task to any member of its organization who accepts it before it expires. The token is returned only once. Requires the owner role on the task.
+// @Tags Tasks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Task ID" format(uuid)
+// @Param request body models.CreateTaskLinkRequest true "Role and lifetime"
+// @Success 201 {object} models.CreatedTaskLinkResponse "Link created"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Insufficient permissions or task role"
+// @Failure 404 {object} map[string]interface{} "Task not found"
+// @Router /tasks/{id}/links [post]
+func (h *TaskHandler) CreateLink(c *gin.Context) {
+	task, _, ok := h.authz.Authorize(c, models.TaskRoleOwner)
+	if !ok {
+		return
+	}
+
+	user, _ := auth.GetUserFromContext(c)
+
+	var req models.CreateTaskLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "A role of viewer or editor and expires_in_hours between 1 and 720 are required",
+		})
+		return
+	}
+
+	token, hash, prefix, err := auth.NewTaskLink()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to create link",
+		})
+		return
+	}
+
+	link := &models.TaskLink{
+		TaskID:    task.ID,
+		OrgID:     task.OrgID,
+		TokenHash: hash,
+		Prefix:    prefix,
+		Role:      req.Role,
+		ExpiresAt: time.Now().Add(time.Duration(req.ExpiresInHours) * time.Hour),
+		CreatedBy: &user.ID,
+	}
+	if err := h.authz.DB(c).Create(link).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to create link",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.CreatedTaskLinkResponse{
+		TaskLinkResponse: taskLinkResponse(link),
+		Token:            token,
+	})
+}
+
+// RevokeLink revokes a share-by-link token
+// @Summary Revoke Task Link
+// @Description Revokes a link token so it can no longer be accepted; roles already granted through it are kept. Requires the owner role on the task.
+// @Tags Tasks
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Task ID" format(uuid)
+// @Param link_id path string true "Link ID" format(uuid)
+// @Success 200 {object} map[string]interface{} "Link revoked"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Insufficient permissions or task role"
+// @Failure 404 {object} map[string]interface{} "Task or link not found"
+// @Router /tasks/{id}/links/{link_id} [delete]
+func (h *TaskHandler) RevokeLink(c *gin.Context) {
+	task, _, ok := h.authz.Authorize(c, models.TaskRoleOwner)
+	if !ok {
+		return
+	}
+
+	linkID, err := uuid.Parse(c.Param("link_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid link ID format",
+		})
+		return
+	}
+
+	result := h.authz.DB(c).Model(&models.TaskLink{}).
+		Where("id = ? AND task_id = ? AND revoked_at IS NULL", linkID, task.ID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to revoke link",
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Link not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Link revoked",
+	})
+}
+
+// AcceptLink grants the current user the role of a share-by-link token
+// @Summary Accept Task Link
+// @Description Accepts a link token of a task in the active organization and returns the task. The user is granted the link's role unless they already hold an equal or higher one.
+// @Tags Tasks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.AcceptTaskLinkRequest true "Link token"
+// @Success 200 {object} models.TaskResponse "Task"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Insufficient permissions"
+// @Failure 404 {object} map[string]interface{} "Invalid, expired or revoked link"
+// @Router /tasks/links/accept [post]
+func (h *TaskHandler) AcceptLink(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+		})
+		return
+	}
+
+	orgID, ok := activeOrganization(c)
+	if !ok {
+		return
+	}
+
+	var req models.AcceptTaskLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil || !auth.IsTaskLink(req.Token) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "A link token is required",
+		})
+		return
+	}
+
+	db := h.authz.DB(c)
+
+	var link models.TaskLink
+	if err := db.Where("token_hash = ? AND org_id = ? AND expires_at > ? AND revoked_at IS NULL",
+		auth.HashToken(req.Token), orgID, time.Now()).First(&link).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Invalid, expired or revoked link",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to accept link",
+		})
+		return
+	}
+
+	var task models.Task
+	if err := db.Where("id = ?", link.TaskID).First(&task).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to accept link",
+		})
+		return
+	}
+
+	role, err := h.authz.Role(db, &task, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to accept link",
+		})
+		return
+	}
+
+	if models.TaskRoleRank[role] < models.TaskRoleRank[link.Role] {
+		if _, err := saveTaskShare(db, &task, user.ID, link.Role, link.CreatedBy); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to accept link",
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, taskResponse(&task))
+}
+
+// saveTaskShare grants a user a role on a task, replacing any previous share
+func saveTaskShare(db *gorm.DB, task *models.Task, userID uuid.UUID, role string, createdBy *uuid.UUID) (*models.TaskShare, error) {
+	now := time.Now()
+	if err := db.Exec(`INSERT INTO task_shares (task_id, user_id, org_id, role, created_by, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (task_id, user_id) DO UPDATE SET role = EXCLUDED.role, updated_at = EXCLUDED.updated_at`,
+		task.ID, userID, task.OrgID, role, createdBy, now, now).Error; err != nil {
+		return nil, err
+	}
+
+	var share models.TaskShare
+	if err := db.Where("task_id = ? AND user_id = ?", task.ID, userID).First(&share).Error; err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
+
+// taskShareResponse converts a share to its response
+func taskShareResponse(share *models.TaskShare, user *models.User) models.TaskShareResponse {
+	response := models.TaskShareResponse{
+		UserID:    share.UserID,
+		Role:      share.Role,
+		CreatedBy: share.CreatedBy,
+		CreatedAt: share.CreatedAt,
+	}
+	if user != nil {
+		response.Email = user.Email
+		response.DisplayName = user.DisplayName
+	}
+	return response
+}
+
+// taskLinkResponse converts a link to its response
+func taskLinkResponse(link *models.TaskLink) models.TaskLinkResponse {
+	return models.TaskLinkResponse{
+		ID:        link.ID,
+		Prefix:    link.Prefix,
+		Role:      link.Role,
+		ExpiresAt: link.ExpiresAt,
+		CreatedBy: link.CreatedBy,
+		CreatedAt: link.CreatedAt,
+	}
+}
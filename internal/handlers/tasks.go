@@ -3,7 +3,9 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"time"
 
+	"ishare-task-api/internal/auth"
 	"ishare-task-api/internal/models"
 
 	"github.com/gin-gonic/gin"
@@ -13,19 +15,19 @@ import (
 
 // TaskHandler handles task-related requests
 type TaskHandler struct {
-	db *gorm.DB
+	authz *auth.TaskAuthorizer
 }
 
 // NewTaskHandler creates a new task handler
-func NewTaskHandler(db *gorm.DB) *TaskHandler {
+func NewTaskHandler(authz *auth.TaskAuthorizer) *TaskHandler {
 	return &TaskHandler{
-		db: db,
+		authz: authz,
 	}
 }
 
 // CreateTask creates a new task
 // @Summary Create Task
-// @Description Creates a new task
+// @Description Creates a new task in the active organization, owned by the authenticated user. Adding it to a project requires at least the editor role on the project. The status must belong to the workflow of the project or organization and defaults to its initial status.
 // @Tags Tasks
 // @Accept json
 // @Produce json
@@ -34,8 +36,24 @@ func NewTaskHandler(db *gorm.DB) *TaskHandler {
 // @Success 201 {object} models.TaskResponse "Task created successfully"
 // @Failure 400 {object} map[string]interface{} "Bad request"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Insufficient permissions or project role"
+// @Failure 404 {object} map[string]interface{} "Project not found"
+// @Failure 409 {object} map[string]interface{} "Project is archived"
 // @Router /tasks [post]
 func (h *TaskHandler) CreateTask(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+		})
+		return
+	}
+
+	orgID, ok := activeOrganization(c)
+	if !ok {
+		return
+	}
+
 	var req models.CreateTaskRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -44,19 +62,58 @@ func (h *TaskHandler) CreateTask(c *gin.Context) {
 		return
 	}
 
+	startAt, ok := taskTime(c, "start_at", req.StartAt, user.Location(), false)
+	if !ok {
+		return
+	}
+	dueAt, ok := taskTime(c, "due_at", req.DueAt, user.Location(), true)
+	if !ok {
+		return
+	}
+
+	if req.ProjectID != nil && !h.checkProject(c, *req.ProjectID) {
+		return
+	}
+
+	workflow, err := workflowFor(h.authz.DB(c), orgID, req.ProjectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve workflow",
+		})
+		return
+	}
+
 	// Set default status if not provided
 	if req.Status == "" {
-		req.Status = "pending"
+		req.Status = workflow.InitialStatus
+	}
+	if !workflow.HasStatus(req.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":    "Unknown status",
+			"status":   req.Status,
+			"statuses": workflow.StatusNames(),
+		})
+		return
 	}
 
 	// Create task
 	task := &models.Task{
+		OrgID:       orgID,
+		OwnerID:     user.ID,
+		CreatedBy:   &user.ID,
+		ProjectID:   req.ProjectID,
 		Title:       req.Title,
 		Description: req.Description,
-		Status:      req.Status,
+		StartAt:     startAt,
+		DueAt:       dueAt,
 	}
+	applyStatus(task, workflow, req.Status)
 
-	if err := h.db.Create(task).Error; err != nil {
+	if !checkTaskDates(c, task) {
+		return
+	}
+
+	if err := h.authz.DB(c).Create(task).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to create task",
 		})
@@ -64,19 +121,12 @@ func (h *TaskHandler) CreateTask(c *gin.Context) {
 	}
 
 	// Return task response
-	c.JSON(http.StatusCreated, models.TaskResponse{
-		ID:          task.ID,
-		Title:       task.Title,
-		Description: task.Description,
-		Status:      task.Status,
-		CreatedAt:   task.CreatedAt,
-		UpdatedAt:   task.UpdatedAt,
-	})
+	c.JSON(http.StatusCreated, taskResponse(task))
 }
 
 // GetTask retrieves a specific task
 // @Summary Get Task
-// @Description Retrieves a specific task by ID
+// @Description Retrieves a specific task by ID; requires at least the viewer role on the task
 // @Tags Tasks
 // @Produce json
 // @Security BearerAuth
@@ -84,55 +134,22 @@ func (h *TaskHandler) CreateTask(c *gin.Context) {
 // @Success 200 {object} models.TaskResponse "Task retrieved successfully"
 // @Failure 400 {object} map[string]interface{} "Bad request"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Insufficient permissions or task role"
 // @Failure 404 {object} map[string]interface{} "Task not found"
 // @Router /tasks/{id} [get]
 func (h *TaskHandler) GetTask(c *gin.Context) {
-	taskID := c.Param("id")
-	if taskID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Task ID is required",
-		})
-		return
-	}
-
-	// Parse UUID
-	taskUUID, err := uuid.Parse(taskID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid task ID format",
-		})
-		return
-	}
-
-	// Get task
-	var task models.Task
-	if err := h.db.Where("id = ?", taskUUID).First(&task).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error": "Task not found",
-			})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to retrieve task",
-		})
+	task, _, ok := h.authz.Authorize(c, models.TaskRoleViewer)
+	if !ok {
 		return
 	}
 
 	// Return task response
-	c.JSON(http.StatusOK, models.TaskResponse{
-		ID:          task.ID,
-		Title:       task.Title,
-		Description: task.Description,
-		Status:      task.Status,
-		CreatedAt:   task.CreatedAt,
-		UpdatedAt:   task.UpdatedAt,
-	})
+	c.JSON(http.StatusOK, taskResponse(task))
 }
 
 // UpdateTask updates a specific task
 // @Summary Update Task
-// @Description Updates a specific task by ID
+// @Description Updates a specific task by ID; requires at least the editor role on the task. Moving it to another project, or out of its project with a null project_id, requires the owner role on the task and at least the editor role on the project. Status changes must be transitions allowed by the workflow of the task's project or organization; reaching a terminal status sets completed_at. A moved task keeps its status, with completed_at set as the new workflow's terminal statuses say; if that workflow lacks the status, the task must be given one of its statuses.
 // @Tags Tasks
 // @Accept json
 // @Produce json
@@ -142,23 +159,13 @@ func (h *TaskHandler) GetTask(c *gin.Context) {
 // @Success 200 {object} models.TaskResponse "Task updated successfully"
 // @Failure 400 {object} map[string]interface{} "Bad request"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
-// @Failure 404 {object} map[string]interface{} "Task not found"
+// @Failure 403 {object} map[string]interface{} "Insufficient permissions or task role"
+// @Failure 404 {object} map[string]interface{} "Task or project not found"
+// @Failure 409 {object} map[string]interface{} "Project is archived or status transition not allowed"
 // @Router /tasks/{id} [put]
 func (h *TaskHandler) UpdateTask(c *gin.Context) {
-	taskID := c.Param("id")
-	if taskID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Task ID is required",
-		})
-		return
-	}
-
-	// Parse UUID
-	taskUUID, err := uuid.Parse(taskID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid task ID format",
-		})
+	task, role, ok := h.authz.Authorize(c, models.TaskRoleEditor)
+	if !ok {
 		return
 	}
 
@@ -170,18 +177,18 @@ func (h *TaskHandler) UpdateTask(c *gin.Context) {
 		return
 	}
 
-	// Get existing task
-	var task models.Task
-	if err := h.db.Where("id = ?", taskUUID).First(&task).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error": "Task not found",
-			})
+	user, _ := auth.GetUserFromContext(c)
+	if req.StartAt != nil {
+		if task.StartAt, ok = taskTime(c, "start_at", *req.StartAt, user.Location(), false); !ok {
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to retrieve task",
-		})
+	}
+	if req.DueAt != nil {
+		if task.DueAt, ok = taskTime(c, "due_at", *req.DueAt, user.Location(), true); !ok {
+			return
+		}
+	}
+	if !checkTaskDates(c, task) {
 		return
 	}
 
@@ -192,12 +199,66 @@ func (h *TaskHandler) UpdateTask(c *gin.Context) {
 	if req.Description != "" {
 		task.Description = req.Description
 	}
-	if req.Status != "" {
-		task.Status = req.Status
+	moved := req.ProjectID.Changes(task.ProjectID)
+	if moved {
+		// Moving a task changes who can access it
+		if role != models.TaskRoleOwner {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":         "Insufficient access to the task",
+				"role":          role,
+				"required_role": models.TaskRoleOwner,
+			})
+			return
+		}
+		if req.ProjectID.Value != nil && !h.checkProject(c, *req.ProjectID.Value) {
+			return
+		}
+		task.ProjectID = req.ProjectID.Value
+		task.Project = nil
+	}
+
+	// Status changes follow the workflow of the task's (new) project
+	workflow, err := workflowFor(h.authz.DB(c), task.OrgID, task.ProjectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve workflow",
+		})
+		return
+	}
+	if req.Status != "" && req.Status != task.Status {
+		if !workflow.HasStatus(req.Status) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":    "Unknown status",
+				"status":   req.Status,
+				"statuses": workflow.StatusNames(),
+			})
+			return
+		}
+		if !workflow.CanTransition(task.Status, req.Status) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":         "Status transition not allowed",
+				"from":          task.Status,
+				"to":            req.Status,
+				"next_statuses": workflow.NextStatuses(task.Status),
+			})
+			return
+		}
+		applyStatus(task, workflow, req.Status)
+	}
+
+	// A moved task needs a status of its new project's workflow, which also
+	// decides whether that status completes the task
+	if moved && !settleMovedStatus(task, workflow) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":    "Status is not part of the project's workflow, set one of its statuses",
+			"status":   task.Status,
+			"statuses": workflow.StatusNames(),
+		})
+		return
 	}
 
 	// Save updated task
-	if err := h.db.Save(&task).Error; err != nil {
+	if err := h.authz.DB(c).Save(task).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to update task",
 		})
@@ -205,19 +266,12 @@ func (h *TaskHandler) UpdateTask(c *gin.Context) {
 	}
 
 	// Return updated task response
-	c.JSON(http.StatusOK, models.TaskResponse{
-		ID:          task.ID,
-		Title:       task.Title,
-		Description: task.Description,
-		Status:      task.Status,
-		CreatedAt:   task.CreatedAt,
-		UpdatedAt:   task.UpdatedAt,
-	})
+	c.JSON(http.StatusOK, taskResponse(task))
 }
 
 // DeleteTask deletes a specific task
 // @Summary Delete Task
-// @Description Deletes a specific task by ID
+// @Description Deletes a specific task by ID; requires the owner role on the task
 // @Tags Tasks
 // @Produce json
 // @Security BearerAuth
@@ -225,43 +279,17 @@ func (h *TaskHandler) UpdateTask(c *gin.Context) {
 // @Success 200 {object} map[string]interface{} "Task deleted successfully"
 // @Failure 400 {object} map[string]interface{} "Bad request"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Insufficient permissions or task role"
 // @Failure 404 {object} map[string]interface{} "Task not found"
 // @Router /tasks/{id} [delete]
 func (h *TaskHandler) DeleteTask(c *gin.Context) {
-	taskID := c.Param("id")
-	if taskID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Task ID is required",
-		})
-		return
-	}
-
-	// Parse UUID
-	taskUUID, err := uuid.Parse(taskID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid task ID format",
-		})
-		return
-	}
-
-	// Check if task exists
-	var task models.Task
-	if err := h.db.Where("id = ?", taskUUID).First(&task).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error": "Task not found",
-			})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to retrieve task",
-		})
+	task, _, ok := h.authz.Authorize(c, models.TaskRoleOwner)
+	if !ok {
 		return
 	}
 
 	// Delete task
-	if err := h.db.Delete(&task).Error; err != nil {
+	if err := h.authz.DB(c).Delete(task).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to delete task",
 		})
@@ -275,18 +303,42 @@ func (h *TaskHandler) DeleteTask(c *gin.Context) {
 
 // ListTasks retrieves all tasks with optional filtering and pagination
 // @Summary List Tasks
-// @Description Retrieves all tasks with optional filtering and pagination
+// @Description Retrieves the tasks in the active organization the user owns, that are shared with them or that belong to their projects, with optional filtering and pagination
 // @Tags Tasks
 // @Produce json
 // @Security BearerAuth
 // @Param status query string false "Filter by status" example(pending)
+// @Param due_before query string false "Only tasks due before this time; plain dates and times without an offset are in the user's timezone" example(2026-11-01)
+// @Param due_after query string false "Only tasks due at or after this time" example(2026-10-01T00:00:00Z)
+// @Param overdue query bool false "Only tasks past their due date that are not completed" example(true)
+// @Param due_today query bool false "Only tasks due today in the user's timezone" example(true)
 // @Param page query int false "Page number" example(1)
 // @Param limit query int false "Items per page" example(10)
 // @Success 200 {object} models.TasksResponse "Tasks retrieved successfully"
 // @Failure 400 {object} map[string]interface{} "Bad request"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Insufficient permissions"
 // @Router /tasks [get]
 func (h *TaskHandler) ListTasks(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+		})
+		return
+	}
+
+	orgID, ok := activeOrganization(c)
+	if !ok {
+		return
+	}
+
+	listTasks(c, h.authz.Visible(h.authz.DB(c).Model(&models.Task{}).Where("org_id = ?", orgID), user.ID))
+}
+
+// listTasks responds with a page of the tasks the query selects, applying
+// the filtering and pagination parameters of the request
+func listTasks(c *gin.Context, query *gorm.DB) {
 	// Get query parameters
 	status := c.Query("status")
 	pageStr := c.DefaultQuery("page", "1")
@@ -305,12 +357,43 @@ func (h *TaskHandler) ListTasks(c *gin.Context) {
 
 	offset := (page - 1) * limit
 
-	// Build query
-	query := h.db.Model(&models.Task{})
+	// Apply filters
 	if status != "" {
 		query = query.Where("status = ?", status)
 	}
 
+	// Date filters are in the user's timezone
+	user, _ := auth.GetUserFromContext(c)
+	location := user.Location()
+	if dueBefore := c.Query("due_before"); dueBefore != "" {
+		t, err := parseTaskTime(dueBefore, location, false)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid due_before, expected an RFC 3339 timestamp or a date",
+			})
+			return
+		}
+		query = query.Where("due_at < ?", t)
+	}
+	if dueAfter := c.Query("due_after"); dueAfter != "" {
+		t, err := parseTaskTime(dueAfter, location, false)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid due_after, expected an RFC 3339 timestamp or a date",
+			})
+			return
+		}
+		query = query.Where("due_at >= ?", t)
+	}
+	if c.Query("overdue") == "true" {
+		query = query.Where("due_at < ? AND completed_at IS NULL", time.Now())
+	}
+	if c.Query("due_today") == "true" {
+		now := time.Now().In(location)
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location)
+		query = query.Where("due_at >= ? AND due_at < ?", today, today.AddDate(0, 0, 1))
+	}
+
 	// Get total count
 	var total int64
 	if err := query.Count(&total).Error; err != nil {
@@ -331,15 +414,8 @@ func (h *TaskHandler) ListTasks(c *gin.Context) {
 
 	// Convert to response format
 	taskResponses := make([]models.TaskResponse, len(tasks))
-	for i, task := range tasks {
-		taskResponses[i] = models.TaskResponse{
-			ID:          task.ID,
-			Title:       task.Title,
-			Description: task.Description,
-			Status:      task.Status,
-			CreatedAt:   task.CreatedAt,
-			UpdatedAt:   task.UpdatedAt,
-		}
+	for i := range tasks {
+		taskResponses[i] = taskResponse(&tasks[i])
 	}
 
 	// Return response
@@ -348,3 +424,141 @@ func (h *TaskHandler) ListTasks(c *gin.Context) {
 		Total: total,
 	})
 }
+
+// checkProject checks that a task can be added to the project: it must be
+// an active project of the organization on which the user holds at least
+// the editor role
+func (h *TaskHandler) checkProject(c *gin.Context, projectID uuid.UUID) bool {
+	user, _ := auth.GetUserFromContext(c)
+	orgID, _ := auth.GetOrganizationIDFromContext(c)
+	db := h.authz.DB(c)
+
+	var project models.Project
+	if err := db.Where("id = ? AND org_id = ?", projectID, orgID).First(&project).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Project not found",
+			})
+			return false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve project",
+		})
+		return false
+	}
+
+	role, err := h.authz.ProjectRole(db, project.ID, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to check project access",
+		})
+		return false
+	}
+	if role == "" {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Project not found",
+		})
+		return false
+	}
+	if models.TaskRoleRank[role] < models.TaskRoleRank[models.TaskRoleEditor] {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":         "Insufficient access to the project",
+			"role":          role,
+			"required_role": models.TaskRoleEditor,
+		})
+		return false
+	}
+
+	if project.Archived {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "Project is archived",
+		})
+		return false
+	}
+
+	return true
+}
+
+// taskResponse converts a task to its response
+func taskResponse(task *models.Task) models.TaskResponse {
+	return models.TaskResponse{
+		ID:          task.ID,
+		OrgID:       task.OrgID,
+		OwnerID:     task.OwnerID,
+		CreatedBy:   task.CreatedBy,
+		ProjectID:   task.ProjectID,
+		Title:       task.Title,
+		Description: task.Description,
+		Status:      task.Status,
+		StartAt:     task.StartAt,
+		DueAt:       task.DueAt,
+		CompletedAt: task.CompletedAt,
+		CreatedAt:   task.CreatedAt,
+		UpdatedAt:   task.UpdatedAt,
+	}
+}
+
+// taskTimeLayouts are the accepted formats of task dates without an offset
+var taskTimeLayouts = []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"}
+
+// parseTaskTime parses a task date. RFC 3339 timestamps keep their offset,
+// other formats are in the location. A plain date means the start of the
+// day, or its last second with endOfDay.
+func parseTaskTime(value string, location *time.Location, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+
+	var err error
+	for _, layout := range taskTimeLayouts {
+		var t time.Time
+		if t, err = time.ParseInLocation(layout, value, location); err == nil {
+			if endOfDay && len(value) == len("2006-01-02") {
+				t = t.AddDate(0, 0, 1).Add(-time.Second)
+			}
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// taskTime parses an optional task date from a request field, writing a bad
+// request response when it is invalid. An empty value is no date.
+func taskTime(c *gin.Context, field, value string, location *time.Location, endOfDay bool) (*time.Time, bool) {
+	if value == "" {
+		return nil, true
+	}
+	t, err := parseTaskTime(value, location, endOfDay)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid " + field + ", expected an RFC 3339 timestamp or a date",
+		})
+		return nil, false
+	}
+	return &t, true
+}
+
+// checkTaskDates writes a bad request response if the task starts after it
+// is due
+func checkTaskDates(c *gin.Context, task *models.Task) bool {
+	if task.StartAt != nil && task.DueAt != nil && task.StartAt.After(*task.DueAt) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "start_at must not be after due_at",
+		})
+		return false
+	}
+	return true
+}
+
+// activeOrganization returns the organization the request acts in; tasks
+// are only visible within it
+func activeOrganization(c *gin.Context) (uuid.UUID, bool) {
+	orgID, ok := auth.GetOrganizationIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "No active organization",
+		})
+		return uuid.Nil, false
+	}
+	return orgID, true
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"ishare-task-api/internal/auth"
+	"ishare-task-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// TokenHandler handles personal access token requests of the current user
+type TokenHandler struct {
+	oauth *auth.OAuthManager
+}
+
+// NewTokenHandler creates a new personal access token handler
+func NewTokenHandler(oauth *auth.OAuthManager) *TokenHandler {
+	return &TokenHandler{
+		oauth: oauth,
+	}
+}
+
+// ListTokens lists the personal access tokens of the current user
+// @Summary List Personal Access Tokens
+// @Description Lists the active personal access tokens of the authenticated user. Token secrets are never returned.
+// @Tags Tokens
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.PersonalAccessTokensResponse "Personal access tokens"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Request made with a personal access token"
+// @Router /me/tokens [get]
+func (h *TokenHandler) ListTokens(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+		})
+		return
+	}
+
+	tokens, err := h.oauth.ListPersonalAccessTokens(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve personal access tokens",
+		})
+		return
+	}
+
+	tokenResponses := make([]models.PersonalAccessTokenResponse, len(tokens))
+	for i := range tokens {
+		tokenResponses[i] = personalAccessTokenResponse(&tokens[i])
+	}
+
+	c.JSON(http.StatusOK, models.PersonalAccessTokensResponse{
+		Tokens: tokenResponses,
+		Total:  int64(len(tokenResponses)),
+	})
+}
+
+// CreateToken creates a personal access token for the current user
+// @Summary Create Personal Access Token
+// @Description Creates a named token limited to the given scopes (permission names). The token is shown only in this response. The token acts in the active organization. Personal access tokens cannot create further tokens.
+// @Tags Tokens
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.CreatePersonalAccessTokenRequest true "Token name, scopes and expiry"
+// @Success 201 {object} models.CreatedPersonalAccessTokenResponse "Token created"
+// @Failure 400 {object} map[string]interface{} "Bad request, unknown scope or invalid expiry"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Request made with a personal access token"
+// @Router /me/tokens [post]
+func (h *TokenHandler) CreateToken(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+		})
+		return
+	}
+
+	var req models.CreatePersonalAccessTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "A name and at least one scope are required",
+		})
+		return
+	}
+
+	// The token acts in the organization the request was made in
+	orgID, _ := auth.GetOrganizationIDFromContext(c)
+
+	lifetime := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	token, pat, err := h.oauth.CreatePersonalAccessToken(user, orgID, req.Name, req.Scopes, lifetime, c.ClientIP())
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidScope):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+		case err == auth.ErrInvalidTokenLifetime:
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "expires_in_days exceeds the maximum token lifetime",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to create personal access token",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.CreatedPersonalAccessTokenResponse{
+		PersonalAccessTokenResponse: personalAccessTokenResponse(pat),
+		Token:                       token,
+	})
+}
+
+// RevokeToken revokes a personal access token of the current user
+// @Summary Revoke Personal Access Token
+// @Description Revokes one of the authenticated user's personal access tokens; it stops working immediately
+// @Tags Tokens
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Token ID" format(uuid)
+// @Success 200 {object} map[string]interface{} "Token revoked"
+// @Failure 400 {object} map[string]interface{} "Invalid token ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Request made with a personal access token"
+// @Failure 404 {object} map[string]interface{} "Token not found"
+// @Router /me/tokens/{id} [delete]
+func (h *TokenHandler) RevokeToken(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+		})
+		return
+	}
+
+	tokenID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid token ID format",
+		})
+		return
+	}
+
+	if err := h.oauth.RevokePersonalAccessToken(user, tokenID, c.ClientIP()); err != nil {
+		if err == auth.ErrPersonalAccessTokenNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Personal access token not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to revoke personal access token",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Personal access token revoked",
+	})
+}
+
+// personalAccessTokenResponse converts a token to its API representation
+func personalAccessTokenResponse(pat *models.PersonalAccessToken) models.PersonalAccessTokenResponse {
+	return models.PersonalAccessTokenResponse{
+		ID:         pat.ID,
+		Name:       pat.Name,
+		Prefix:     pat.Prefix,
+		Scopes:     pat.Scopes(),
+		OrgID:      pat.OrgID,
+		ExpiresAt:  pat.ExpiresAt,
+		LastUsedAt: pat.LastUsedAt,
+		LastUsedIP: pat.LastUsedIP,
+		CreatedAt:  pat.CreatedAt,
+	}
+}
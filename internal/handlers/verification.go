@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+
+	"ishare-task-api/internal/auth"
+	"ishare-task-api/internal/mail"
+	"ishare-task-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VerifyEmail verifies an email address from a signed link
+// @Summary Verify Email
+// @Description Verifies the email address bound to a signed verification link, completing an email change if the link was sent for one
+// @Tags OAuth
+// @Produce html,json
+// @Param token query string true "Signed verification token"
+// @Success 200 {object} map[string]interface{} "Email verified"
+// @Failure 400 {object} map[string]interface{} "Invalid or expired link"
+// @Failure 409 {object} map[string]interface{} "New email address already in use"
+// @Router /oauth/verify-email [get]
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		h.respondVerification(c, http.StatusBadRequest, gin.H{
+			"error": "Verification token is required",
+		})
+		return
+	}
+
+	user, err := h.oauth.VerifyEmail(token)
+	if err != nil {
+		switch err {
+		case auth.ErrInvalidVerificationToken, auth.ErrVerificationEmailMismatch:
+			h.respondVerification(c, http.StatusBadRequest, gin.H{
+				"error": "Invalid or expired verification link",
+			})
+		case auth.ErrEmailTaken:
+			h.respondVerification(c, http.StatusConflict, gin.H{
+				"error": "Email address is already in use",
+			})
+		default:
+			h.respondVerification(c, http.StatusInternalServerError, gin.H{
+				"error": "Failed to verify email address",
+			})
+		}
+		return
+	}
+
+	h.respondVerification(c, http.StatusOK, gin.H{
+		"message": "Email address verified successfully",
+		"email":   user.Email,
+	})
+}
+
+// ResendVerification re-sends the verification email
+// @Summary Resend Verification Email
+// @Description Sends a new verification link to an unverified account. The response does not reveal whether the email is registered.
+// @Tags OAuth
+// @Accept json,x-www-form-urlencoded
+// @Produce json
+// @Param request body models.ResendVerificationRequest true "Account email"
+// @Success 200 {object} map[string]interface{} "Verification email sent if needed"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Router /oauth/verify-email/resend [post]
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	var req models.ResendVerificationRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "A valid email address is required",
+		})
+		return
+	}
+
+	if user, err := h.oauth.FindUnverifiedUser(req.Email); err == nil {
+		h.sendVerificationEmail(user)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "If the account exists and is not verified yet, a new verification link has been sent",
+	})
+}
+
+// sendVerificationEmail emails a signed verification link for the user's
+// current address; failures are logged since the user can request a new link
+func (h *AuthHandler) sendVerificationEmail(user *models.User) {
+	token, err := h.oauth.CreateEmailVerificationToken(user)
+	if err != nil {
+		log.Printf("Failed to create email verification token: %v", err)
+		return
+	}
+
+	link := h.cfg.Server.BaseURL + "/oauth/verify-email?token=" + url.QueryEscape(token)
+	if err := h.mailer.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Please confirm your email address by opening the following link:\n%s\n\n"+
+			"The link expires in %s.\n", link, h.cfg.OAuth.EmailVerificationTTL),
+	}); err != nil {
+		log.Printf("Failed to send verification email: %v", err)
+	}
+}
+
+// respondVerification renders the verification page for browsers and JSON otherwise
+func (h *AuthHandler) respondVerification(c *gin.Context, status int, data gin.H) {
+	if c.NegotiateFormat(gin.MIMEJSON, gin.MIMEHTML) == gin.MIMEHTML {
+		c.HTML(status, "verify_email.html", data)
+		return
+	}
+	c.JSON(status, data)
+}
@@ -0,0 +1,353 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"ishare-task-api/internal/auth"
+	"ishare-task-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// WorkflowHandler handles task status workflow requests
+type WorkflowHandler struct {
+	authz *auth.TaskAuthorizer
+}
+
+// NewWorkflowHandler creates a new workflow handler
+func NewWorkflowHandler(authz *auth.TaskAuthorizer) *WorkflowHandler {
+	return &WorkflowHandler{
+		authz: authz,
+	}
+}
+
+// GetOrganizationWorkflow returns the workflow of the active organization
+// @Summary Get Organization Workflow
+// @Description Returns the task status workflow of the active organization, or the default workflow if it has not defined one. Projects can define their own.
+// @Tags Workflows
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.WorkflowResponse "Workflow"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Insufficient permissions"
+// @Router /org/workflow [get]
+func (h *WorkflowHandler) GetOrganizationWorkflow(c *gin.Context) {
+	orgID, ok := activeOrganization(c)
+	if !ok {
+		return
+	}
+
+	workflow, err := workflowFor(h.authz.DB(c), orgID, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve workflow",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, workflowResponse(workflow))
+}
+
+// SetOrganizationWorkflow defines the workflow of the active organization
+// @Summary Set Organization Workflow
+// @Description Defines the task status workflow of the active organization, replacing the previous one. It applies to all tasks outside of projects with their own workflow. Tasks keep their status; tasks whose status is no longer part of the workflow can move to any status of it.
+// @Tags Workflows
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.WorkflowRequest true "Workflow"
+// @Success 200 {object} models.WorkflowResponse "Workflow saved"
+// @Failure 400 {object} map[string]interface{} "Bad request or invalid workflow"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Insufficient permissions"
+// @Router /org/workflow [put]
+func (h *WorkflowHandler) SetOrganizationWorkflow(c *gin.Context) {
+	orgID, ok := activeOrganization(c)
+	if !ok {
+		return
+	}
+
+	saveWorkflow(c, h.authz.DB(c), orgID, nil)
+}
+
+// ResetOrganizationWorkflow removes the workflow of the active organization
+// @Summary Reset Organization Workflow
+// @Description Removes the task status workflow of the active organization so the default workflow applies again
+// @Tags Workflows
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.WorkflowResponse "Workflow that applies now"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Insufficient permissions"
+// @Router /org/workflow [delete]
+func (h *WorkflowHandler) ResetOrganizationWorkflow(c *gin.Context) {
+	orgID, ok := activeOrganization(c)
+	if !ok {
+		return
+	}
+
+	deleteWorkflow(c, h.authz.DB(c), orgID, nil)
+}
+
+// GetProjectWorkflow returns the workflow of a project
+// @Summary Get Project Workflow
+// @Description Returns the task status workflow that applies to a project: its own, else the organization's, else the default workflow. Requires at least the viewer role on the project.
+// @Tags Workflows
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Project ID" format(uuid)
+// @Success 200 {object} models.WorkflowResponse "Workflow"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Insufficient permissions"
+// @Failure 404 {object} map[string]interface{} "Project not found"
+// @Router /projects/{id}/workflow [get]
+func (h *WorkflowHandler) GetProjectWorkflow(c *gin.Context) {
+	project, _, ok := h.authz.AuthorizeProject(c, models.TaskRoleViewer)
+	if !ok {
+		return
+	}
+
+	workflow, err := workflowFor(h.authz.DB(c), project.OrgID, &project.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve workflow",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, workflowResponse(workflow))
+}
+
+// SetProjectWorkflow defines the workflow of a project
+// @Summary Set Project Workflow
+// @Description Defines the task status workflow of a project, replacing the previous one; requires the owner role on the project. Tasks keep their status; tasks whose status is no longer part of the workflow can move to any status of it.
+// @Tags Workflows
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Project ID" format(uuid)
+// @Param request body models.WorkflowRequest true "Workflow"
+// @Success 200 {object} models.WorkflowResponse "Workflow saved"
+// @Failure 400 {object} map[string]interface{} "Bad request or invalid workflow"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Insufficient permissions or project role"
+// @Failure 404 {object} map[string]interface{} "Project not found"
+// @Router /projects/{id}/workflow [put]
+func (h *WorkflowHandler) SetProjectWorkflow(c *gin.Context) {
+	project, _, ok := h.authz.AuthorizeProject(c, models.TaskRoleOwner)
+	if !ok {
+		return
+	}
+
+	saveWorkflow(c, h.authz.DB(c), project.OrgID, &project.ID)
+}
+
+// ResetProjectWorkflow removes the workflow of a project
+// @Summary Reset Project Workflow
+// @Description Removes the task status workflow of a project so the organization's workflow applies to it again; requires the owner role on the project
+// @Tags Workflows
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Project ID" format(uuid)
+// @Success 200 {object} models.WorkflowResponse "Workflow that applies now"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Insufficient permissions or project role"
+// @Failure 404 {object} map[string]interface{} "Project not found"
+// @Router /projects/{id}/workflow [delete]
+func (h *WorkflowHandler) ResetProjectWorkflow(c *gin.Context) {
+	project, _, ok := h.authz.AuthorizeProject(c, models.TaskRoleOwner)
+	if !ok {
+		return
+	}
+
+	deleteWorkflow(c, h.authz.DB(c), project.OrgID, &project.ID)
+}
+
+// GetTaskWorkflow returns the workflow of a task
+// @Summary Get Task Workflow
+// @Description Returns the task status workflow that applies to a task and the statuses it can move to next; requires at least the viewer role on the task
+// @Tags Tasks
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Task ID" format(uuid)
+// @Success 200 {object} models.TaskWorkflowResponse "Workflow"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Insufficient permissions or task role"
+// @Failure 404 {object} map[string]interface{} "Task not found"
+// @Router /tasks/{id}/workflow [get]
+func (h *TaskHandler) GetTaskWorkflow(c *gin.Context) {
+	task, _, ok := h.authz.Authorize(c, models.TaskRoleViewer)
+	if !ok {
+		return
+	}
+
+	workflow, err := workflowFor(h.authz.DB(c), task.OrgID, task.ProjectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve workflow",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TaskWorkflowResponse{
+		Status:       task.Status,
+		NextStatuses: workflow.NextStatuses(task.Status),
+		Workflow:     workflowResponse(workflow),
+	})
+}
+
+// workflowFor returns the workflow that applies to tasks of the project, or
+// to tasks outside of projects without a project ID
+func workflowFor(db *gorm.DB, orgID uuid.UUID, projectID *uuid.UUID) (*models.Workflow, error) {
+	query := db.Where("org_id = ? AND project_id IS NULL", orgID)
+	if projectID != nil {
+		query = db.Where("org_id = ? AND (project_id = ? OR project_id IS NULL)", orgID, *projectID).
+			Order("project_id IS NULL")
+	}
+
+	var workflows []models.Workflow
+	if err := query.Limit(1).Find(&workflows).Error; err != nil {
+		return nil, err
+	}
+	if len(workflows) == 0 {
+		return models.DefaultWorkflow(), nil
+	}
+	return &workflows[0], nil
+}
+
+// saveWorkflow creates or replaces the workflow of the organization or the
+// project from the request body
+func saveWorkflow(c *gin.Context, db *gorm.DB, orgID uuid.UUID, projectID *uuid.UUID) {
+	user, _ := auth.GetUserFromContext(c)
+
+	var req models.WorkflowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "A name and at least one status are required",
+		})
+		return
+	}
+
+	workflow := &models.Workflow{}
+	if err := definedWorkflow(db, orgID, projectID).First(workflow).Error; err != nil {
+		if err != gorm.ErrRecordNotFound {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to retrieve workflow",
+			})
+			return
+		}
+		workflow = &models.Workflow{
+			OrgID:     orgID,
+			ProjectID: projectID,
+			CreatedBy: &user.ID,
+		}
+	}
+
+	workflow.Name = strings.TrimSpace(req.Name)
+	workflow.InitialStatus = req.InitialStatus
+	if workflow.InitialStatus == "" {
+		workflow.InitialStatus = req.Statuses[0].Name
+	}
+	workflow.Statuses = req.Statuses
+	workflow.Transitions = req.Transitions
+	if workflow.Transitions == nil {
+		workflow.Transitions = []models.WorkflowTransition{}
+	}
+
+	if err := workflow.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid workflow: " + err.Error(),
+		})
+		return
+	}
+
+	if err := db.Save(workflow).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to save workflow",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, workflowResponse(workflow))
+}
+
+// deleteWorkflow removes the workflow of the organization or the project and
+// responds with the workflow that applies instead
+func deleteWorkflow(c *gin.Context, db *gorm.DB, orgID uuid.UUID, projectID *uuid.UUID) {
+	if err := definedWorkflow(db, orgID, projectID).Delete(&models.Workflow{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to reset workflow",
+		})
+		return
+	}
+
+	workflow, err := workflowFor(db, orgID, projectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve workflow",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, workflowResponse(workflow))
+}
+
+// applyStatus moves the task to the status, recording when it reached a
+// terminal status
+func applyStatus(task *models.Task, workflow *models.Workflow, status string) {
+	if !workflow.IsTerminal(status) {
+		task.CompletedAt = nil
+	} else if task.CompletedAt == nil || task.Status != status {
+		now := time.Now()
+		task.CompletedAt = &now
+	}
+	task.Status = status
+}
+
+// settleMovedStatus keeps the status of a task moved to another workflow
+// and completes or reopens the task as that workflow says. It reports false
+// when the workflow does not have the status.
+func settleMovedStatus(task *models.Task, workflow *models.Workflow) bool {
+	if !workflow.HasStatus(task.Status) {
+		return false
+	}
+	applyStatus(task, workflow, task.Status)
+	return true
+}
+
+// definedWorkflow limits a workflow query to the workflow defined for the
+// project, or for the organization without a project ID
+func definedWorkflow(db *gorm.DB, orgID uuid.UUID, projectID *uuid.UUID) *gorm.DB {
+	if projectID != nil {
+		return db.Where("org_id = ? AND project_id = ?", orgID, *projectID)
+	}
+	return db.Where("org_id = ? AND project_id IS NULL", orgID)
+}
+
+// workflowResponse converts a workflow to its response
+func workflowResponse(workflow *models.Workflow) models.WorkflowResponse {
+	response := models.WorkflowResponse{
+		Scope:         models.WorkflowScopeDefault,
+		ProjectID:     workflow.ProjectID,
+		Name:          workflow.Name,
+		InitialStatus: workflow.InitialStatus,
+		Statuses:      workflow.Statuses,
+		Transitions:   workflow.Transitions,
+	}
+	if workflow.ID != uuid.Nil {
+		response.ID = &workflow.ID
+		response.Scope = models.WorkflowScopeOrganization
+		if workflow.ProjectID != nil {
+			response.Scope = models.WorkflowScopeProject
+		}
+		response.UpdatedAt = &workflow.UpdatedAt
+	}
+	return response
+}
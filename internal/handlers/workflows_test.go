@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"ishare-task-api/internal/models"
+)
+
+func TestSettleMovedStatus(t *testing.T) {
+	// In the review workflow "completed" is where work waits, not where it
+	// ends
+	review := &models.Workflow{
+		InitialStatus: "pending",
+		Statuses: []models.WorkflowStatus{
+			{Name: "pending"},
+			{Name: "completed"},
+			{Name: "approved", Terminal: true},
+		},
+	}
+	completedAt := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name          string
+		task          models.Task
+		workflow      *models.Workflow
+		settled       bool
+		wantCompleted bool
+	}{
+		{"terminal status becomes open", models.Task{Status: "completed", CompletedAt: &completedAt}, review, true, false},
+		{"open status becomes terminal", models.Task{Status: "completed"}, models.DefaultWorkflow(), true, true},
+		{"terminal status stays terminal", models.Task{Status: "completed", CompletedAt: &completedAt}, models.DefaultWorkflow(), true, true},
+		{"unknown status", models.Task{Status: "in_progress"}, review, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := tt.task
+			if settled := settleMovedStatus(&task, tt.workflow); settled != tt.settled {
+				t.Fatalf("settleMovedStatus = %v; want %v", settled, tt.settled)
+			}
+			if task.Status != tt.task.Status {
+				t.Errorf("Status = %q; want %q", task.Status, tt.task.Status)
+			}
+			if (task.CompletedAt != nil) != tt.wantCompleted {
+				t.Errorf("CompletedAt = %v; want set %v", task.CompletedAt, tt.wantCompleted)
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package mail
+
+import (
+	"log"
+)
+
+// LogMailer writes messages to the application log instead of sending them
+type LogMailer struct{}
+
+// NewLogMailer creates a new log-only mailer
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+// Send logs the message
+func (m *LogMailer) Send(msg Message) error {
+	log.Printf("Email to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
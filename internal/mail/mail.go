@@ -0,0 +1,40 @@
+package mail
+
+import (
+	"fmt"
+
+	"ishare-task-api/internal/config"
+)
+
+// Mail drivers
+const (
+	DriverSMTP   = "smtp"
+	DriverLog    = "log"
+	DriverMemory = "memory"
+)
+
+// Message represents an outgoing email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends email messages
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// New creates the mailer selected by the configuration
+func New(cfg config.MailConfig) (Mailer, error) {
+	switch cfg.Driver {
+	case DriverSMTP:
+		return NewSMTPMailer(cfg), nil
+	case DriverLog, "":
+		return NewLogMailer(), nil
+	case DriverMemory:
+		return NewMemoryMailer(), nil
+	default:
+		return nil, fmt.Errorf("unsupported mail driver: %s", cfg.Driver)
+	}
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// FederatedIdentity links a user to an account at an upstream OpenID
+// Connect provider, identified by the provider's subject
+type FederatedIdentity struct {
+	ID          uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID      uuid.UUID  `json:"user_id" gorm:"type:uuid;not null"`
+	Provider    string     `json:"provider" gorm:"not null;size:64;uniqueIndex:idx_federated_identities_provider_subject"`
+	Subject     string     `json:"subject" gorm:"not null;size:255;uniqueIndex:idx_federated_identities_provider_subject"`
+	Email       string     `json:"email" gorm:"size:255"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"not null;default:now()"`
+}
+
+// BeforeCreate will set a UUID rather than numeric ID
+func (identity *FederatedIdentity) BeforeCreate(tx *gorm.DB) error {
+	if identity.ID == uuid.Nil {
+		identity.ID = uuid.New()
+	}
+	return nil
+}
+
+// FederatedLoginState tracks an upstream sign-in in progress: the PKCE code
+// verifier and nonce sent upstream and the local authorization request to
+// continue afterwards. Only a hash of the state parameter is stored.
+type FederatedLoginState struct {
+	ID           uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	StateHash    string    `json:"-" gorm:"unique;not null;size:64"`
+	Provider     string    `json:"provider" gorm:"not null;size:64"`
+	CodeVerifier string    `json:"-" gorm:"not null;size:128"`
+	Nonce        string    `json:"-" gorm:"not null;size:64"`
+	ClientID     string    `json:"client_id" gorm:"not null;size:255"`
+	RedirectURI  string    `json:"redirect_uri" gorm:"not null;size:1024"`
+	Scope        string    `json:"scope" gorm:"size:255"`
+	State        string    `json:"state" gorm:"size:1024"`
+	ExpiresAt    time.Time `json:"expires_at" gorm:"not null"`
+	CreatedAt    time.Time `json:"created_at" gorm:"not null;default:now()"`
+}
+
+// BeforeCreate will set a UUID rather than numeric ID
+func (state *FederatedLoginState) BeforeCreate(tx *gorm.DB) error {
+	if state.ID == uuid.Nil {
+		state.ID = uuid.New()
+	}
+	return nil
+}
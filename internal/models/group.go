@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Group is a named set of users, managed by a provisioning client
+type Group struct {
+	ID          uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	DisplayName string    `json:"display_name" gorm:"unique;not null;size:255"`
+	ExternalID  string    `json:"external_id,omitempty" gorm:"not null;default:'';size:255"`
+	Members     []User    `json:"members,omitempty" gorm:"many2many:group_members;"`
+	CreatedAt   time.Time `json:"created_at" gorm:"not null;default:now()"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"not null;default:now()"`
+}
+
+// BeforeCreate will set a UUID rather than numeric ID
+func (group *Group) BeforeCreate(tx *gorm.DB) error {
+	if group.ID == uuid.Nil {
+		group.ID = uuid.New()
+	}
+	return nil
+}
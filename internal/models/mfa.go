@@ -0,0 +1,93 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MFACredential represents a user's TOTP authenticator. The secret is stored
+// encrypted and the credential only becomes active once confirmed.
+type MFACredential struct {
+	ID              uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID          uuid.UUID  `json:"user_id" gorm:"type:uuid;unique;not null"`
+	SecretEncrypted string     `json:"-" gorm:"not null;size:255"`
+	LastUsedStep    int64      `json:"-" gorm:"not null;default:0"`
+	ConfirmedAt     *time.Time `json:"confirmed_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at" gorm:"not null;default:now()"`
+	UpdatedAt       time.Time  `json:"updated_at" gorm:"not null;default:now()"`
+}
+
+// BeforeCreate will set a UUID rather than numeric ID
+func (credential *MFACredential) BeforeCreate(tx *gorm.DB) error {
+	if credential.ID == uuid.Nil {
+		credential.ID = uuid.New()
+	}
+	return nil
+}
+
+// RecoveryCode represents a hashed single-use MFA recovery code
+type RecoveryCode struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID    uuid.UUID  `json:"user_id" gorm:"type:uuid;not null"`
+	CodeHash  string     `json:"-" gorm:"unique;not null;size:64"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at" gorm:"not null;default:now()"`
+}
+
+// BeforeCreate will set a UUID rather than numeric ID
+func (code *RecoveryCode) BeforeCreate(tx *gorm.DB) error {
+	if code.ID == uuid.Nil {
+		code.ID = uuid.New()
+	}
+	return nil
+}
+
+// MFAChallenge represents a pending second-factor step of the authorize
+// flow, created after the first factor was verified. AMR lists the methods
+// of the first factor, e.g. pwd or fed.
+type MFAChallenge struct {
+	ID          uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	TokenHash   string    `json:"-" gorm:"unique;not null;size:64"`
+	UserID      uuid.UUID `json:"user_id" gorm:"type:uuid;not null"`
+	ClientID    string    `json:"client_id" gorm:"size:255"`
+	RedirectURI string    `json:"redirect_uri" gorm:"size:2048"`
+	Scope       string    `json:"scope" gorm:"size:255"`
+	State       string    `json:"state" gorm:"size:512"`
+	AMR         string    `json:"amr" gorm:"not null;default:'pwd';size:50"`
+	Attempts    int       `json:"attempts" gorm:"not null;default:0"`
+	ExpiresAt   time.Time `json:"expires_at" gorm:"not null"`
+	CreatedAt   time.Time `json:"created_at" gorm:"not null;default:now()"`
+}
+
+// BeforeCreate will set a UUID rather than numeric ID
+func (challenge *MFAChallenge) BeforeCreate(tx *gorm.DB) error {
+	if challenge.ID == uuid.Nil {
+		challenge.ID = uuid.New()
+	}
+	return nil
+}
+
+// MFACodeRequest represents a request carrying a TOTP or recovery code
+type MFACodeRequest struct {
+	Code string `json:"code" form:"code" binding:"required" example:"123456"`
+}
+
+// MFAEnrollmentResponse represents the response body for starting TOTP enrollment
+type MFAEnrollmentResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// RecoveryCodesResponse represents the response body carrying new recovery codes
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// MFAStatusResponse represents the MFA status of the current user
+type MFAStatusResponse struct {
+	Enabled                bool       `json:"enabled"`
+	ConfirmedAt            *time.Time `json:"confirmed_at,omitempty"`
+	RecoveryCodesRemaining int64      `json:"recovery_codes_remaining"`
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DefaultOrganizationSlug names the organization created by the migrations.
+// Data that predates organizations belongs to it.
+const DefaultOrganizationSlug = "default"
+
+// Organization is a tenant. Tasks belong to exactly one organization and
+// users see the tasks of the organization active in their token.
+type Organization struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Name      string    `json:"name" gorm:"not null;size:100"`
+	Slug      string    `json:"slug" gorm:"unique;not null;size:64"`
+	CreatedAt time.Time `json:"created_at" gorm:"not null;default:now()"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null;default:now()"`
+}
+
+// BeforeCreate will set a UUID rather than numeric ID
+func (org *Organization) BeforeCreate(tx *gorm.DB) error {
+	if org.ID == uuid.Nil {
+		org.ID = uuid.New()
+	}
+	return nil
+}
+
+// OrganizationMember grants a user a role within an organization. The role
+// decides the organization-scoped permissions, such as access to tasks.
+type OrganizationMember struct {
+	OrgID        uuid.UUID    `json:"org_id" gorm:"type:uuid;primaryKey"`
+	UserID       uuid.UUID    `json:"user_id" gorm:"type:uuid;primaryKey"`
+	RoleID       uuid.UUID    `json:"role_id" gorm:"type:uuid;not null"`
+	Organization Organization `json:"-" gorm:"foreignKey:OrgID"`
+	User         User         `json:"-" gorm:"foreignKey:UserID"`
+	Role         Role         `json:"-" gorm:"foreignKey:RoleID"`
+	CreatedAt    time.Time    `json:"created_at" gorm:"not null;default:now()"`
+}
+
+// CreateOrganizationRequest represents the request body for creating an
+// organization
+type CreateOrganizationRequest struct {
+	Name       string `json:"name" binding:"required,max=100" example:"Acme Inc."`
+	Slug       string `json:"slug" binding:"required,max=64" example:"acme"`
+	AdminEmail string `json:"admin_email" binding:"omitempty,email" example:"owner@acme.example"`
+}
+
+// AddOrganizationMemberRequest represents the request body for adding an
+// existing user to an organization
+type AddOrganizationMemberRequest struct {
+	Email string `json:"email" binding:"required,email" example:"user@example.com"`
+	Role  string `json:"role" binding:"required" example:"member"`
+}
+
+// UpdateOrganizationMemberRequest represents the request body for changing
+// the role of a member
+type UpdateOrganizationMemberRequest struct {
+	Role string `json:"role" binding:"required" example:"viewer"`
+}
+
+// SwitchOrganizationRequest represents the request body for switching the
+// active organization
+type SwitchOrganizationRequest struct {
+	OrgID uuid.UUID `json:"org_id" binding:"required" example:"3f1c2a9e-7b4d-4c8e-9a55-2d6f0b1e8c47"`
+}
+
+// OrganizationResponse represents an organization, with the role of the
+// current user where relevant
+type OrganizationResponse struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Slug      string    `json:"slug"`
+	Role      string    `json:"role,omitempty"`
+	Active    bool      `json:"active,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// OrganizationsResponse represents a list of organizations
+type OrganizationsResponse struct {
+	Organizations []OrganizationResponse `json:"organizations"`
+	Total         int64                  `json:"total"`
+}
+
+// OrganizationMemberResponse represents a member of an organization
+type OrganizationMemberResponse struct {
+	UserID      uuid.UUID `json:"user_id"`
+	Email       string    `json:"email"`
+	DisplayName string    `json:"display_name"`
+	Role        string    `json:"role"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// OrganizationMembersResponse represents the members of an organization
+type OrganizationMembersResponse struct {
+	Members []OrganizationMemberResponse `json:"members"`
+	Total   int64                        `json:"total"`
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Project groups tasks of an organization. Project members hold a task
+// role (viewer, editor or owner) that applies to the project and to every
+// task in it. No tasks can be added to archived projects.
+type Project struct {
+	ID          uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	OrgID       uuid.UUID  `json:"org_id" gorm:"type:uuid;not null;index"`
+	Name        string     `json:"name" gorm:"not null;size:100"`
+	Description string     `json:"description" gorm:"type:text"`
+	Archived    bool       `json:"archived" gorm:"not null;default:false"`
+	CreatedBy   *uuid.UUID `json:"created_by,omitempty" gorm:"type:uuid"`
+	Creator     *User      `json:"-" gorm:"foreignKey:CreatedBy;constraint:OnDelete:SET NULL"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"not null;default:now()"`
+	UpdatedAt   time.Time  `json:"updated_at" gorm:"not null;default:now()"`
+}
+
+// BeforeCreate will set a UUID rather than numeric ID
+func (project *Project) BeforeCreate(tx *gorm.DB) error {
+	if project.ID == uuid.Nil {
+		project.ID = uuid.New()
+	}
+	return nil
+}
+
+// ProjectMember grants a member of the organization a role on a project
+type ProjectMember struct {
+	ProjectID uuid.UUID `json:"project_id" gorm:"type:uuid;primaryKey"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;primaryKey"`
+	OrgID     uuid.UUID `json:"org_id" gorm:"type:uuid;not null"`
+	Role      string    `json:"role" gorm:"not null;size:20"`
+	Project   *Project  `json:"-" gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE"`
+	User      *User     `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	CreatedAt time.Time `json:"created_at" gorm:"not null;default:now()"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null;default:now()"`
+}
+
+// CreateProjectRequest represents the request body for creating a project
+type CreateProjectRequest struct {
+	Name        string `json:"name" binding:"required,max=100" example:"Website relaunch"`
+	Description string `json:"description" example:"Tasks for the new company website"`
+}
+
+// UpdateProjectRequest represents the request body for updating a project;
+// omitted fields are left unchanged
+type UpdateProjectRequest struct {
+	Name        *string `json:"name" binding:"omitempty,min=1,max=100" example:"Website relaunch"`
+	Description *string `json:"description" example:"Tasks for the new company website"`
+	Archived    *bool   `json:"archived" example:"true"`
+}
+
+// AddProjectMemberRequest represents the request body for adding a member
+// of the organization to a project
+type AddProjectMemberRequest struct {
+	Email string `json:"email" binding:"required,email" example:"colleague@example.com"`
+	Role  string `json:"role" binding:"required,oneof=viewer editor owner" example:"editor"`
+}
+
+// ProjectResponse represents a project with the current user's role on it
+type ProjectResponse struct {
+	ID          uuid.UUID  `json:"id"`
+	OrgID       uuid.UUID  `json:"org_id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Archived    bool       `json:"archived"`
+	Role        string     `json:"role,omitempty"`
+	CreatedBy   *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// ProjectsResponse represents a list of projects
+type ProjectsResponse struct {
+	Projects []ProjectResponse `json:"projects"`
+	Total    int64             `json:"total"`
+}
+
+// ProjectMemberResponse represents a member of a project
+type ProjectMemberResponse struct {
+	UserID      uuid.UUID `json:"user_id"`
+	Email       string    `json:"email"`
+	DisplayName string    `json:"display_name"`
+	Role        string    `json:"role"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ProjectMembersResponse represents the members of a project
+type ProjectMembersResponse struct {
+	Members []ProjectMemberResponse `json:"members"`
+	Total   int64                   `json:"total"`
+}
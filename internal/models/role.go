@@ -0,0 +1,157 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Permissions checked by the API
+const (
+	PermissionTasksRead   = "tasks:read"
+	PermissionTasksWrite  = "tasks:write"
+	PermissionTasksDelete = "tasks:delete"
+	PermissionUsersRead   = "users:read"
+	PermissionUsersManage = "users:manage"
+	PermissionRolesManage = "roles:manage"
+	PermissionAuditRead   = "audit:read"
+	PermissionOrgRead     = "org:read"
+	PermissionOrgManage   = "org:manage"
+	PermissionOrgsManage  = "orgs:manage"
+)
+
+// Built-in roles
+const (
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+	RoleViewer = "viewer"
+)
+
+// BuiltinPermissions describes every permission known to the API
+var BuiltinPermissions = map[string]string{
+	PermissionTasksRead:   "Read tasks",
+	PermissionTasksWrite:  "Create and update tasks",
+	PermissionTasksDelete: "Delete tasks",
+	PermissionUsersRead:   "View user accounts",
+	PermissionUsersManage: "Manage user accounts and login lockouts",
+	PermissionRolesManage: "Manage roles and role assignments",
+	PermissionAuditRead:   "Read the security audit trail",
+	PermissionOrgRead:     "View the active organization and its members",
+	PermissionOrgManage:   "Manage the members of the active organization",
+	PermissionOrgsManage:  "Create organizations and manage any organization's members",
+}
+
+// OrgScopedPermissions are granted by the user's role in the organization
+// active in the token rather than by the user's own roles
+var OrgScopedPermissions = map[string]bool{
+	PermissionTasksRead:   true,
+	PermissionTasksWrite:  true,
+	PermissionTasksDelete: true,
+	PermissionOrgRead:     true,
+	PermissionOrgManage:   true,
+}
+
+// BuiltinRole defines a role that is created at startup and cannot be
+// changed through the API
+type BuiltinRole struct {
+	Name        string
+	Description string
+	Permissions []string
+}
+
+// BuiltinRoles are seeded by the database migrations. New users get the
+// member role. The same roles are assigned per organization.
+var BuiltinRoles = []BuiltinRole{
+	{
+		Name:        RoleAdmin,
+		Description: "Full access, including user and role administration",
+		Permissions: []string{
+			PermissionTasksRead, PermissionTasksWrite, PermissionTasksDelete,
+			PermissionUsersRead, PermissionUsersManage, PermissionRolesManage, PermissionAuditRead,
+			PermissionOrgRead, PermissionOrgManage, PermissionOrgsManage,
+		},
+	},
+	{
+		Name:        RoleMember,
+		Description: "Read, write and delete tasks",
+		Permissions: []string{PermissionTasksRead, PermissionTasksWrite, PermissionTasksDelete, PermissionOrgRead},
+	},
+	{
+		Name:        RoleViewer,
+		Description: "Read-only access to tasks",
+		Permissions: []string{PermissionTasksRead, PermissionOrgRead},
+	},
+}
+
+// Permission represents a named permission that can be granted to roles
+type Permission struct {
+	ID          uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Name        string    `json:"name" gorm:"unique;not null;size:64"`
+	Description string    `json:"description" gorm:"size:255"`
+	CreatedAt   time.Time `json:"created_at" gorm:"not null;default:now()"`
+}
+
+// BeforeCreate will set a UUID rather than numeric ID
+func (permission *Permission) BeforeCreate(tx *gorm.DB) error {
+	if permission.ID == uuid.Nil {
+		permission.ID = uuid.New()
+	}
+	return nil
+}
+
+// Role represents a named set of permissions assigned to users
+type Role struct {
+	ID          uuid.UUID    `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Name        string       `json:"name" gorm:"unique;not null;size:64"`
+	Description string       `json:"description" gorm:"size:255"`
+	Builtin     bool         `json:"builtin" gorm:"not null;default:false"`
+	Permissions []Permission `json:"permissions" gorm:"many2many:role_permissions;"`
+	CreatedAt   time.Time    `json:"created_at" gorm:"not null;default:now()"`
+	UpdatedAt   time.Time    `json:"updated_at" gorm:"not null;default:now()"`
+}
+
+// BeforeCreate will set a UUID rather than numeric ID
+func (role *Role) BeforeCreate(tx *gorm.DB) error {
+	if role.ID == uuid.Nil {
+		role.ID = uuid.New()
+	}
+	return nil
+}
+
+// PermissionNames returns the names of the role's permissions
+func (role *Role) PermissionNames() []string {
+	names := make([]string, len(role.Permissions))
+	for i, permission := range role.Permissions {
+		names[i] = permission.Name
+	}
+	return names
+}
+
+// CreateRoleRequest represents the request body for creating a role
+type CreateRoleRequest struct {
+	Name        string   `json:"name" binding:"required,max=64" example:"editor"`
+	Description string   `json:"description" binding:"max=255" example:"Edit but not delete tasks"`
+	Permissions []string `json:"permissions" example:"tasks:read,tasks:write"`
+}
+
+// UpdateRoleRequest represents the request body for updating a role
+type UpdateRoleRequest struct {
+	Description *string  `json:"description" binding:"omitempty,max=255" example:"Edit but not delete tasks"`
+	Permissions []string `json:"permissions" example:"tasks:read,tasks:write"`
+}
+
+// AssignRoleRequest represents the request body for assigning a role to a user
+type AssignRoleRequest struct {
+	Role string `json:"role" binding:"required" example:"viewer"`
+}
+
+// RolesResponse represents a list of roles
+type RolesResponse struct {
+	Roles []Role `json:"roles"`
+}
+
+// PermissionsResponse represents a list of permissions
+type PermissionsResponse struct {
+	Permissions []Permission `json:"permissions"`
+}
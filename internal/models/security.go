@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// LoginThrottle counts recent failed sign-in attempts for an account (by
+// email) or a client IP address
+type LoginThrottle struct {
+	ID            uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Scope         string     `json:"scope" gorm:"not null;size:16;uniqueIndex:idx_login_throttles_scope_subject"`
+	Subject       string     `json:"subject" gorm:"not null;size:255;uniqueIndex:idx_login_throttles_scope_subject"`
+	Failures      int        `json:"failures" gorm:"not null;default:0"`
+	LastFailureAt time.Time  `json:"last_failure_at" gorm:"not null"`
+	LockedUntil   *time.Time `json:"locked_until,omitempty"`
+	Locked        bool       `json:"locked" gorm:"not null;default:false"`
+	CreatedAt     time.Time  `json:"created_at" gorm:"not null;default:now()"`
+}
+
+// BeforeCreate will set a UUID rather than numeric ID
+func (throttle *LoginThrottle) BeforeCreate(tx *gorm.DB) error {
+	if throttle.ID == uuid.Nil {
+		throttle.ID = uuid.New()
+	}
+	return nil
+}
+
+// AuditEvent records a security relevant event
+type AuditEvent struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Event     string     `json:"event" gorm:"not null;size:64"`
+	UserID    *uuid.UUID `json:"user_id,omitempty" gorm:"type:uuid"`
+	ActorID   *uuid.UUID `json:"actor_id,omitempty" gorm:"type:uuid"`
+	Email     string     `json:"email,omitempty" gorm:"size:255"`
+	IPAddress string     `json:"ip_address,omitempty" gorm:"size:45"`
+	Details   string     `json:"details,omitempty" gorm:"type:text"`
+	CreatedAt time.Time  `json:"created_at" gorm:"not null;default:now()"`
+}
+
+// BeforeCreate will set a UUID rather than numeric ID
+func (event *AuditEvent) BeforeCreate(tx *gorm.DB) error {
+	if event.ID == uuid.Nil {
+		event.ID = uuid.New()
+	}
+	return nil
+}
+
+// UnlockRequest represents the request body for lifting a login lockout
+type UnlockRequest struct {
+	Email     string `json:"email" binding:"omitempty,email" example:"user@example.com"`
+	IPAddress string `json:"ip_address" binding:"omitempty,ip" example:"192.0.2.10"`
+}
+
+// LockoutsResponse represents the active login lockouts
+type LockoutsResponse struct {
+	Lockouts []LoginThrottle `json:"lockouts"`
+}
+
+// AuditEventsResponse represents a page of audit events
+type AuditEventsResponse struct {
+	Events []AuditEvent `json:"events"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SSOSession represents a browser single sign-on session established at
+// login. The cookie only carries a random token; its hash is stored here.
+type SSOSession struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	TokenHash string    `json:"-" gorm:"unique;not null;size:64"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;not null"`
+	AuthTime  time.Time `json:"auth_time" gorm:"not null"`
+	AMR       string    `json:"amr" gorm:"size:255"`
+	IPAddress string    `json:"ip_address" gorm:"size:45"`
+	UserAgent string    `json:"user_agent" gorm:"size:512"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"not null;default:now()"`
+}
+
+// BeforeCreate will set a UUID rather than numeric ID
+func (session *SSOSession) BeforeCreate(tx *gorm.DB) error {
+	if session.ID == uuid.Nil {
+		session.ID = uuid.New()
+	}
+	return nil
+}
@@ -1,20 +1,36 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
-// Task represents a task in the system
+// Task represents a task in the system. Tasks are visible to their owner,
+// the users they are shared with and the members of their project, if any;
+// deleting the owner's account deletes them. CreatedBy is empty for tasks
+// that predate ownership or whose creator was deleted. Status follows the
+// workflow of the task's project or organization, and CompletedAt is set
+// while the status is terminal. StartAt and DueAt are optional.
 type Task struct {
-	ID          uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
-	Title       string    `json:"title" gorm:"not null;size:255"`
-	Description string    `json:"description" gorm:"type:text"`
-	Status      string    `json:"status" gorm:"not null;default:'pending';size:50"`
-	CreatedAt   time.Time `json:"created_at" gorm:"not null;default:now()"`
-	UpdatedAt   time.Time `json:"updated_at" gorm:"not null;default:now()"`
+	ID          uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	OrgID       uuid.UUID  `json:"org_id" gorm:"type:uuid;not null"`
+	OwnerID     uuid.UUID  `json:"owner_id" gorm:"type:uuid;not null"`
+	CreatedBy   *uuid.UUID `json:"created_by,omitempty" gorm:"type:uuid"`
+	Owner       *User      `json:"-" gorm:"foreignKey:OwnerID;constraint:OnDelete:CASCADE"`
+	Creator     *User      `json:"-" gorm:"foreignKey:CreatedBy;constraint:OnDelete:SET NULL"`
+	ProjectID   *uuid.UUID `json:"project_id,omitempty" gorm:"type:uuid"`
+	Project     *Project   `json:"-" gorm:"foreignKey:ProjectID;constraint:OnDelete:SET NULL"`
+	Title       string     `json:"title" gorm:"not null;size:255"`
+	Description string     `json:"description" gorm:"type:text"`
+	Status      string     `json:"status" gorm:"not null;default:'pending';size:50"`
+	StartAt     *time.Time `json:"start_at,omitempty"`
+	DueAt       *time.Time `json:"due_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"not null;default:now()"`
+	UpdatedAt   time.Time  `json:"updated_at" gorm:"not null;default:now()"`
 }
 
 // BeforeCreate will set a UUID rather than numeric ID
@@ -25,28 +41,79 @@ func (task *Task) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
-// CreateTaskRequest represents the request body for creating a task
+// CreateTaskRequest represents the request body for creating a task. Start
+// and due dates are RFC 3339 timestamps; without an offset, or as plain
+// dates, they are in the user's timezone.
 type CreateTaskRequest struct {
-	Title       string `json:"title" binding:"required" example:"Complete project documentation"`
-	Description string `json:"description" example:"Write comprehensive documentation for the API"`
-	Status      string `json:"status" example:"pending"`
+	Title       string     `json:"title" binding:"required" example:"Complete project documentation"`
+	Description string     `json:"description" example:"Write comprehensive documentation for the API"`
+	Status      string     `json:"status" example:"pending"`
+	ProjectID   *uuid.UUID `json:"project_id" example:"5b8e2c1a-9f4d-4e7b-8a63-1c2d3e4f5a6b"`
+	StartAt     string     `json:"start_at" example:"2026-11-02T09:00:00+01:00"`
+	DueAt       string     `json:"due_at" example:"2026-11-06"`
 }
 
-// UpdateTaskRequest represents the request body for updating a task
+// UpdateTaskRequest represents the request body for updating a task. Start
+// and due dates are parsed like in CreateTaskRequest; an empty string
+// clears them. A null project_id takes the task out of its project.
 type UpdateTaskRequest struct {
-	Title       string `json:"title" example:"Complete project documentation"`
-	Description string `json:"description" example:"Write comprehensive documentation for the API"`
-	Status      string `json:"status" example:"completed"`
+	Title       string       `json:"title" example:"Complete project documentation"`
+	Description string       `json:"description" example:"Write comprehensive documentation for the API"`
+	Status      string       `json:"status" example:"completed"`
+	ProjectID   OptionalUUID `json:"project_id" swaggertype:"string" format:"uuid" example:"5b8e2c1a-9f4d-4e7b-8a63-1c2d3e4f5a6b"`
+	StartAt     *string      `json:"start_at" example:"2026-11-02T09:00:00+01:00"`
+	DueAt       *string      `json:"due_at" example:"2026-11-06T17:00"`
+}
+
+// OptionalUUID is a request field that tells an explicit null apart from a
+// missing field
+type OptionalUUID struct {
+	Set   bool       // the field was in the request
+	Value *uuid.UUID // nil for null
+}
+
+// UnmarshalJSON records that the field was set, to null or a UUID
+func (o *OptionalUUID) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	o.Value = nil
+	if string(data) == "null" {
+		return nil
+	}
+
+	var id uuid.UUID
+	if err := json.Unmarshal(data, &id); err != nil {
+		return err
+	}
+	o.Value = &id
+	return nil
+}
+
+// Changes reports whether the field is set to something other than current
+func (o OptionalUUID) Changes(current *uuid.UUID) bool {
+	if !o.Set {
+		return false
+	}
+	if o.Value == nil || current == nil {
+		return o.Value != current
+	}
+	return *o.Value != *current
 }
 
 // TaskResponse represents the response body for task operations
 type TaskResponse struct {
-	ID          uuid.UUID `json:"id"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	Status      string    `json:"status"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          uuid.UUID  `json:"id"`
+	OrgID       uuid.UUID  `json:"org_id"`
+	OwnerID     uuid.UUID  `json:"owner_id"`
+	CreatedBy   *uuid.UUID `json:"created_by,omitempty"`
+	ProjectID   *uuid.UUID `json:"project_id,omitempty"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Status      string     `json:"status"`
+	StartAt     *time.Time `json:"start_at,omitempty"`
+	DueAt       *time.Time `json:"due_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
 }
 
 // TasksResponse represents the response body for listing tasks
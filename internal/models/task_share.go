@@ -0,0 +1,123 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Task roles, from least to most access. Viewers can read a task, editors
+// can also change it, and owners can also delete and share it. The task's
+// owner always holds the owner role.
+const (
+	TaskRoleViewer = "viewer"
+	TaskRoleEditor = "editor"
+	TaskRoleOwner  = "owner"
+)
+
+// TaskRoleRank orders the task roles; unknown roles rank zero
+var TaskRoleRank = map[string]int{
+	TaskRoleViewer: 1,
+	TaskRoleEditor: 2,
+	TaskRoleOwner:  3,
+}
+
+// TaskShare grants a user a role on a task of their organization
+type TaskShare struct {
+	TaskID    uuid.UUID  `json:"task_id" gorm:"type:uuid;primaryKey"`
+	UserID    uuid.UUID  `json:"user_id" gorm:"type:uuid;primaryKey"`
+	OrgID     uuid.UUID  `json:"org_id" gorm:"type:uuid;not null"`
+	Role      string     `json:"role" gorm:"not null;size:20"`
+	CreatedBy *uuid.UUID `json:"created_by,omitempty" gorm:"type:uuid"`
+	Task      *Task      `json:"-" gorm:"foreignKey:TaskID;constraint:OnDelete:CASCADE"`
+	User      *User      `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Creator   *User      `json:"-" gorm:"foreignKey:CreatedBy;constraint:OnDelete:SET NULL"`
+	CreatedAt time.Time  `json:"created_at" gorm:"not null;default:now()"`
+	UpdatedAt time.Time  `json:"updated_at" gorm:"not null;default:now()"`
+}
+
+// TaskLink is a share-by-link token. Until it expires or is revoked, any
+// member of the task's organization who accepts the link is granted its
+// role. Only a SHA-256 hash of the token is stored.
+type TaskLink struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	TaskID    uuid.UUID  `json:"task_id" gorm:"type:uuid;not null;index"`
+	OrgID     uuid.UUID  `json:"org_id" gorm:"type:uuid;not null"`
+	TokenHash string     `json:"-" gorm:"unique;not null;size:64"`
+	Prefix    string     `json:"prefix" gorm:"not null;size:32"`
+	Role      string     `json:"role" gorm:"not null;size:20"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedBy *uuid.UUID `json:"created_by,omitempty" gorm:"type:uuid"`
+	Task      *Task      `json:"-" gorm:"foreignKey:TaskID;constraint:OnDelete:CASCADE"`
+	Creator   *User      `json:"-" gorm:"foreignKey:CreatedBy;constraint:OnDelete:SET NULL"`
+	CreatedAt time.Time  `json:"created_at" gorm:"not null;default:now()"`
+}
+
+// BeforeCreate will set a UUID rather than numeric ID
+func (link *TaskLink) BeforeCreate(tx *gorm.DB) error {
+	if link.ID == uuid.Nil {
+		link.ID = uuid.New()
+	}
+	return nil
+}
+
+// ShareTaskRequest represents the request body for sharing a task with a
+// member of its organization
+type ShareTaskRequest struct {
+	Email string `json:"email" binding:"required,email" example:"colleague@example.com"`
+	Role  string `json:"role" binding:"required,oneof=viewer editor owner" example:"editor"`
+}
+
+// CreateTaskLinkRequest represents the request body for creating a
+// share-by-link token
+type CreateTaskLinkRequest struct {
+	Role           string `json:"role" binding:"required,oneof=viewer editor" example:"viewer"`
+	ExpiresInHours int    `json:"expires_in_hours" binding:"required,min=1,max=720" example:"72"`
+}
+
+// AcceptTaskLinkRequest represents the request body for accepting a
+// share-by-link token
+type AcceptTaskLinkRequest struct {
+	Token string `json:"token" binding:"required" example:"ishare_link_..."`
+}
+
+// TaskShareResponse represents a user a task is shared with
+type TaskShareResponse struct {
+	UserID      uuid.UUID  `json:"user_id"`
+	Email       string     `json:"email"`
+	DisplayName string     `json:"display_name"`
+	Role        string     `json:"role"`
+	CreatedBy   *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// TaskSharesResponse represents the users a task is shared with
+type TaskSharesResponse struct {
+	Shares []TaskShareResponse `json:"shares"`
+	Total  int64               `json:"total"`
+}
+
+// TaskLinkResponse represents a share-by-link token without its secret
+type TaskLinkResponse struct {
+	ID        uuid.UUID  `json:"id"`
+	Prefix    string     `json:"prefix"`
+	Role      string     `json:"role"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	CreatedBy *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TaskLinksResponse represents the active share-by-link tokens of a task
+type TaskLinksResponse struct {
+	Links []TaskLinkResponse `json:"links"`
+	Total int64              `json:"total"`
+}
+
+// CreatedTaskLinkResponse is returned once when a link is created; the
+// token itself cannot be retrieved again
+type CreatedTaskLinkResponse struct {
+	TaskLinkResponse
+	Token string `json:"token" example:"ishare_link_..."`
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestUpdateTaskRequestProjectID(t *testing.T) {
+	current := uuid.New()
+	other := uuid.New()
+
+	tests := []struct {
+		name    string
+		body    string
+		set     bool
+		value   *uuid.UUID
+		changes bool
+	}{
+		{"missing", `{"title": "Task"}`, false, nil, false},
+		{"null", `{"project_id": null}`, true, nil, true},
+		{"same project", `{"project_id": "` + current.String() + `"}`, true, &current, false},
+		{"other project", `{"project_id": "` + other.String() + `"}`, true, &other, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req UpdateTaskRequest
+			if err := json.Unmarshal([]byte(tt.body), &req); err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+			if req.ProjectID.Set != tt.set {
+				t.Errorf("Set = %v; want %v", req.ProjectID.Set, tt.set)
+			}
+			if (req.ProjectID.Value == nil) != (tt.value == nil) ||
+				(tt.value != nil && *req.ProjectID.Value != *tt.value) {
+				t.Errorf("Value = %v; want %v", req.ProjectID.Value, tt.value)
+			}
+			if changes := req.ProjectID.Changes(&current); changes != tt.changes {
+				t.Errorf("Changes = %v; want %v", changes, tt.changes)
+			}
+		})
+	}
+
+	var req UpdateTaskRequest
+	if err := json.Unmarshal([]byte(`{"project_id": null}`), &req); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if req.ProjectID.Changes(nil) {
+		t.Error("null project_id changes a task without a project")
+	}
+	if err := json.Unmarshal([]byte(`{"project_id": "not-a-uuid"}`), &req); err == nil {
+		t.Error("Unmarshal accepted an invalid project_id")
+	}
+}
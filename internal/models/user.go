@@ -9,11 +9,19 @@ import (
 
 // User represents a user in the system
 type User struct {
-	ID           uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
-	Email        string    `json:"email" gorm:"unique;not null;size:255"`
-	PasswordHash string    `json:"-" gorm:"not null;size:255"`
-	CreatedAt    time.Time `json:"created_at" gorm:"not null;default:now()"`
-	UpdatedAt    time.Time `json:"updated_at" gorm:"not null;default:now()"`
+	ID                  uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Email               string     `json:"email" gorm:"unique;not null;size:255"`
+	PasswordHash        string     `json:"-" gorm:"not null;size:255"`
+	EmailVerifiedAt     *time.Time `json:"email_verified_at,omitempty"`
+	DisplayName         string     `json:"display_name" gorm:"not null;default:'';size:100"`
+	Locale              string     `json:"locale" gorm:"not null;default:'';size:35"`
+	Timezone            string     `json:"timezone" gorm:"not null;default:'';size:64"`
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+	DisabledAt          *time.Time `json:"disabled_at,omitempty"`
+	ExternalID          string     `json:"external_id,omitempty" gorm:"not null;default:'';size:255"`
+	Roles               []Role     `json:"roles,omitempty" gorm:"many2many:user_roles;"`
+	CreatedAt           time.Time  `json:"created_at" gorm:"not null;default:now()"`
+	UpdatedAt           time.Time  `json:"updated_at" gorm:"not null;default:now()"`
 }
 
 // BeforeCreate will set a UUID rather than numeric ID
@@ -24,10 +32,39 @@ func (user *User) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// EmailVerified reports whether the user has verified their email address
+func (user *User) EmailVerified() bool {
+	return user.EmailVerifiedAt != nil
+}
+
+// Location returns the user's timezone, or UTC if they have not set one
+func (user *User) Location() *time.Location {
+	if user.Timezone != "" {
+		if location, err := time.LoadLocation(user.Timezone); err == nil {
+			return location
+		}
+	}
+	return time.UTC
+}
+
+// Disabled reports whether an administrator has disabled the account
+func (user *User) Disabled() bool {
+	return user.DisabledAt != nil
+}
+
+// RoleNames returns the names of the user's loaded roles
+func (user *User) RoleNames() []string {
+	names := make([]string, len(user.Roles))
+	for i, role := range user.Roles {
+		names[i] = role.Name
+	}
+	return names
+}
+
 // CreateUserRequest represents the request body for creating a user
 type CreateUserRequest struct {
 	Email    string `json:"email" binding:"required,email" example:"user@example.com"`
-	Password string `json:"password" binding:"required,min=6" example:"password123"`
+	Password string `json:"password" binding:"required" example:"Correct-horse-42"`
 }
 
 // LoginRequest represents the request body for user login
@@ -36,12 +73,66 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required" example:"password123"`
 }
 
+// ForgotPasswordRequest represents the request body for requesting a password reset
+type ForgotPasswordRequest struct {
+	Email string `json:"email" form:"email" binding:"required,email" example:"user@example.com"`
+}
+
+// ResetPasswordRequest represents the request body for resetting a password
+type ResetPasswordRequest struct {
+	Token    string `json:"token" form:"token" binding:"required" example:"reset-token-here"`
+	Password string `json:"password" form:"password" binding:"required" example:"New-battery-staple-7"`
+}
+
+// ResendVerificationRequest represents the request body for re-sending the verification email
+type ResendVerificationRequest struct {
+	Email string `json:"email" form:"email" binding:"required,email" example:"user@example.com"`
+}
+
+// UpdateProfileRequest represents the request body for updating the current
+// user's profile. Omitted fields are left unchanged, empty strings clear them.
+type UpdateProfileRequest struct {
+	DisplayName *string `json:"display_name" binding:"omitempty,max=100" example:"Jane Doe"`
+	Locale      *string `json:"locale" binding:"omitempty,max=35" example:"en-US"`
+	Timezone    *string `json:"timezone" binding:"omitempty,max=64" example:"Europe/Amsterdam"`
+}
+
+// ChangePasswordRequest represents the request body for changing the password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required" example:"Correct-horse-42"`
+	NewPassword     string `json:"new_password" binding:"required" example:"New-battery-staple-7"`
+}
+
+// ChangeEmailRequest represents the request body for changing the email address
+type ChangeEmailRequest struct {
+	NewEmail        string `json:"new_email" binding:"required,email" example:"new@example.com"`
+	CurrentPassword string `json:"current_password" binding:"required" example:"Correct-horse-42"`
+}
+
+// DeleteAccountRequest represents the request body for deleting the account
+type DeleteAccountRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required" example:"Correct-horse-42"`
+}
+
 // UserResponse represents the response body for user operations
 type UserResponse struct {
-	ID        uuid.UUID `json:"id"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID                  uuid.UUID  `json:"id"`
+	Email               string     `json:"email"`
+	EmailVerified       bool       `json:"email_verified"`
+	DisplayName         string     `json:"display_name"`
+	Locale              string     `json:"locale"`
+	Timezone            string     `json:"timezone"`
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+	DisabledAt          *time.Time `json:"disabled_at,omitempty"`
+	Roles               []string   `json:"roles"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+// UsersResponse represents the response body for listing users
+type UsersResponse struct {
+	Users []UserResponse `json:"users"`
+	Total int64          `json:"total"`
 }
 
 // AuthorizationCode represents a temporary authorization code for OAuth flow
@@ -51,6 +142,9 @@ type AuthorizationCode struct {
 	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;not null"`
 	ClientID  string    `json:"client_id" gorm:"not null;size:255"`
 	Scope     string    `json:"scope" gorm:"size:255"`
+	AMR       string    `json:"amr" gorm:"size:255"`
+	IPAddress string    `json:"ip_address" gorm:"size:45"`
+	UserAgent string    `json:"user_agent" gorm:"size:512"`
 	ExpiresAt time.Time `json:"expires_at" gorm:"not null"`
 	CreatedAt time.Time `json:"created_at" gorm:"not null;default:now()"`
 }
@@ -63,15 +157,22 @@ func (code *AuthorizationCode) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
-// AccessToken represents an OAuth access token
+// AccessToken represents an OAuth access token. Only the token's jti and a
+// SHA-256 hash of the bearer token are stored, never the token itself.
 type AccessToken struct {
-	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
-	Token     string    `json:"token" gorm:"unique;not null;size:500"`
-	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;not null"`
-	ClientID  string    `json:"client_id" gorm:"not null;size:255"`
-	Scope     string    `json:"scope" gorm:"size:255"`
-	ExpiresAt time.Time `json:"expires_at" gorm:"not null"`
-	CreatedAt time.Time `json:"created_at" gorm:"not null;default:now()"`
+	ID         uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	JTI        string     `json:"jti" gorm:"column:jti;unique;not null;size:64"`
+	TokenHash  string     `json:"-" gorm:"unique;not null;size:64"`
+	UserID     uuid.UUID  `json:"user_id" gorm:"type:uuid;not null"`
+	OrgID      *uuid.UUID `json:"org_id,omitempty" gorm:"type:uuid"`
+	ClientID   string     `json:"client_id" gorm:"not null;size:255"`
+	Scope      string     `json:"scope" gorm:"size:255"`
+	IPAddress  string     `json:"ip_address" gorm:"size:45"`
+	UserAgent  string     `json:"user_agent" gorm:"size:512"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"not null"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"not null;default:now()"`
 }
 
 // BeforeCreate will set a UUID rather than numeric ID
@@ -80,4 +181,41 @@ func (token *AccessToken) BeforeCreate(tx *gorm.DB) error {
 		token.ID = uuid.New()
 	}
 	return nil
-} 
\ No newline at end of file
+}
+
+// PasswordResetToken represents a single-use password reset token. Only a
+// hash of the token sent by email is stored.
+type PasswordResetToken struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID    uuid.UUID  `json:"user_id" gorm:"type:uuid;not null"`
+	TokenHash string     `json:"-" gorm:"unique;not null;size:64"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at" gorm:"not null;default:now()"`
+}
+
+// BeforeCreate will set a UUID rather than numeric ID
+func (token *PasswordResetToken) BeforeCreate(tx *gorm.DB) error {
+	if token.ID == uuid.Nil {
+		token.ID = uuid.New()
+	}
+	return nil
+}
+
+// SessionResponse represents an active session (access token) of a user
+type SessionResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	ClientID   string     `json:"client_id"`
+	IPAddress  string     `json:"ip_address"`
+	UserAgent  string     `json:"user_agent"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	Current    bool       `json:"current"`
+}
+
+// SessionsResponse represents the response body for listing sessions
+type SessionsResponse struct {
+	Sessions []SessionResponse `json:"sessions"`
+	Total    int64             `json:"total"`
+}
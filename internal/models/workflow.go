@@ -0,0 +1,196 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Workflow scopes, from most to least specific. A task follows the workflow
+// of its project, else the workflow of its organization, else the default
+// workflow.
+const (
+	WorkflowScopeProject      = "project"
+	WorkflowScopeOrganization = "organization"
+	WorkflowScopeDefault      = "default"
+)
+
+// Workflow lists the statuses a task can have and the transitions allowed
+// between them. Reaching a terminal status records when the task was
+// completed; leaving it clears that again.
+type Workflow struct {
+	ID            uuid.UUID            `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	OrgID         uuid.UUID            `json:"org_id" gorm:"type:uuid;not null"`
+	ProjectID     *uuid.UUID           `json:"project_id,omitempty" gorm:"type:uuid"`
+	Name          string               `json:"name" gorm:"not null;size:100"`
+	InitialStatus string               `json:"initial_status" gorm:"not null;size:50"`
+	Statuses      []WorkflowStatus     `json:"statuses" gorm:"type:jsonb;serializer:json;not null"`
+	Transitions   []WorkflowTransition `json:"transitions" gorm:"type:jsonb;serializer:json;not null"`
+	Project       *Project             `json:"-" gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE"`
+	CreatedBy     *uuid.UUID           `json:"created_by,omitempty" gorm:"type:uuid"`
+	Creator       *User                `json:"-" gorm:"foreignKey:CreatedBy;constraint:OnDelete:SET NULL"`
+	CreatedAt     time.Time            `json:"created_at" gorm:"not null;default:now()"`
+	UpdatedAt     time.Time            `json:"updated_at" gorm:"not null;default:now()"`
+}
+
+// WorkflowStatus is a status of a workflow
+type WorkflowStatus struct {
+	Name     string `json:"name" binding:"required,max=50" example:"in_progress"`
+	Terminal bool   `json:"terminal" example:"false"`
+}
+
+// WorkflowTransition allows tasks to move from one status to another
+type WorkflowTransition struct {
+	From string `json:"from" binding:"required" example:"pending"`
+	To   string `json:"to" binding:"required" example:"in_progress"`
+}
+
+// BeforeCreate will set a UUID rather than numeric ID
+func (workflow *Workflow) BeforeCreate(tx *gorm.DB) error {
+	if workflow.ID == uuid.Nil {
+		workflow.ID = uuid.New()
+	}
+	return nil
+}
+
+// DefaultWorkflow returns the workflow of organizations that have not
+// defined their own
+func DefaultWorkflow() *Workflow {
+	return &Workflow{
+		Name:          "Default",
+		InitialStatus: "pending",
+		Statuses: []WorkflowStatus{
+			{Name: "pending"},
+			{Name: "in_progress"},
+			{Name: "completed", Terminal: true},
+			{Name: "cancelled", Terminal: true},
+		},
+		Transitions: []WorkflowTransition{
+			{From: "pending", To: "in_progress"},
+			{From: "pending", To: "completed"},
+			{From: "pending", To: "cancelled"},
+			{From: "in_progress", To: "pending"},
+			{From: "in_progress", To: "completed"},
+			{From: "in_progress", To: "cancelled"},
+			{From: "completed", To: "in_progress"},
+			{From: "cancelled", To: "pending"},
+		},
+	}
+}
+
+// HasStatus reports whether the status belongs to the workflow
+func (workflow *Workflow) HasStatus(status string) bool {
+	for _, s := range workflow.Statuses {
+		if s.Name == status {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTerminal reports whether the status is a terminal status of the workflow
+func (workflow *Workflow) IsTerminal(status string) bool {
+	for _, s := range workflow.Statuses {
+		if s.Name == status {
+			return s.Terminal
+		}
+	}
+	return false
+}
+
+// StatusNames returns the names of the workflow's statuses in order
+func (workflow *Workflow) StatusNames() []string {
+	names := make([]string, len(workflow.Statuses))
+	for i, s := range workflow.Statuses {
+		names[i] = s.Name
+	}
+	return names
+}
+
+// NextStatuses returns the statuses a task can move to from the status. A
+// task whose status is not part of the workflow, for instance because the
+// workflow changed, can move to any status of it.
+func (workflow *Workflow) NextStatuses(from string) []string {
+	if !workflow.HasStatus(from) {
+		return workflow.StatusNames()
+	}
+	next := []string{}
+	for _, t := range workflow.Transitions {
+		if t.From == from {
+			next = append(next, t.To)
+		}
+	}
+	return next
+}
+
+// CanTransition reports whether a task can move between the statuses
+func (workflow *Workflow) CanTransition(from, to string) bool {
+	for _, status := range workflow.NextStatuses(from) {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks that the statuses are unique and that the initial status
+// and the transitions only refer to them
+func (workflow *Workflow) Validate() error {
+	seen := make(map[string]bool)
+	for _, s := range workflow.Statuses {
+		if seen[s.Name] {
+			return errors.New("duplicate status: " + s.Name)
+		}
+		seen[s.Name] = true
+	}
+	if !seen[workflow.InitialStatus] {
+		return errors.New("initial status is not a status of the workflow: " + workflow.InitialStatus)
+	}
+
+	transitions := make(map[WorkflowTransition]bool)
+	for _, t := range workflow.Transitions {
+		if !seen[t.From] || !seen[t.To] {
+			return errors.New("transition between unknown statuses: " + t.From + " -> " + t.To)
+		}
+		if t.From == t.To {
+			return errors.New("transition to the same status: " + t.From)
+		}
+		if transitions[t] {
+			return errors.New("duplicate transition: " + t.From + " -> " + t.To)
+		}
+		transitions[t] = true
+	}
+	return nil
+}
+
+// WorkflowRequest represents the request body for defining a workflow. The
+// initial status defaults to the first status.
+type WorkflowRequest struct {
+	Name          string               `json:"name" binding:"required,max=100" example:"Review process"`
+	InitialStatus string               `json:"initial_status" example:"pending"`
+	Statuses      []WorkflowStatus     `json:"statuses" binding:"required,min=1,dive"`
+	Transitions   []WorkflowTransition `json:"transitions" binding:"dive"`
+}
+
+// WorkflowResponse represents the workflow that applies to a project or an
+// organization and where it is defined
+type WorkflowResponse struct {
+	ID            *uuid.UUID           `json:"id,omitempty"`
+	Scope         string               `json:"scope" example:"project"`
+	ProjectID     *uuid.UUID           `json:"project_id,omitempty"`
+	Name          string               `json:"name"`
+	InitialStatus string               `json:"initial_status"`
+	Statuses      []WorkflowStatus     `json:"statuses"`
+	Transitions   []WorkflowTransition `json:"transitions"`
+	UpdatedAt     *time.Time           `json:"updated_at,omitempty"`
+}
+
+// TaskWorkflowResponse represents the workflow of a task and the statuses
+// the task can move to next
+type TaskWorkflowResponse struct {
+	Status       string           `json:"status" example:"pending"`
+	NextStatuses []string         `json:"next_statuses" example:"in_progress,completed,cancelled"`
+	Workflow     WorkflowResponse `json:"workflow"`
+}
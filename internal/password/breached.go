@@ -0,0 +1,131 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// hashPrefixLength is the number of hex characters of the SHA-1 hash used to
+// name range files (the k-anonymity prefix)
+const hashPrefixLength = 5
+
+// BreachedChecker reports whether a password appears in a breach corpus
+type BreachedChecker interface {
+	IsBreached(password string) (bool, error)
+}
+
+// NewBreachedChecker creates a checker for the configured list. It returns
+// nil when no list is configured.
+func NewBreachedChecker(path string) (BreachedChecker, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password list: %w", err)
+	}
+
+	if info.IsDir() {
+		return &RangeDirectory{dir: path}, nil
+	}
+
+	return LoadHashFile(path)
+}
+
+// RangeDirectory looks passwords up in a directory of k-anonymity range
+// files: a file per 5 character SHA-1 prefix holding "SUFFIX:COUNT" lines,
+// as served by the Pwned Passwords range API. Only the file for the
+// password's prefix is read.
+type RangeDirectory struct {
+	dir string
+}
+
+// IsBreached implements BreachedChecker
+func (r *RangeDirectory) IsBreached(password string) (bool, error) {
+	hash := sha1Hex(password)
+	prefix, suffix := hash[:hashPrefixLength], hash[hashPrefixLength:]
+
+	file, err := os.Open(filepath.Join(r.dir, prefix))
+	if os.IsNotExist(err) {
+		file, err = os.Open(filepath.Join(r.dir, prefix+".txt"))
+	}
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if hashField(scanner.Text()) == suffix {
+			return true, nil
+		}
+	}
+
+	return false, scanner.Err()
+}
+
+// HashFile holds a breached password list of full SHA-1 hashes loaded into
+// memory, indexed by hash prefix
+type HashFile struct {
+	ranges map[string]map[string]struct{}
+}
+
+// LoadHashFile loads a file of full SHA-1 hashes, one per line, optionally
+// followed by ":COUNT"
+func LoadHashFile(path string) (*HashFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password list: %w", err)
+	}
+	defer file.Close()
+
+	hashes := &HashFile{ranges: make(map[string]map[string]struct{})}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		hash := hashField(scanner.Text())
+		if len(hash) != sha1.Size*2 {
+			continue
+		}
+
+		prefix := hash[:hashPrefixLength]
+		if hashes.ranges[prefix] == nil {
+			hashes.ranges[prefix] = make(map[string]struct{})
+		}
+		hashes.ranges[prefix][hash[hashPrefixLength:]] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read breached password list: %w", err)
+	}
+
+	return hashes, nil
+}
+
+// IsBreached implements BreachedChecker
+func (h *HashFile) IsBreached(password string) (bool, error) {
+	hash := sha1Hex(password)
+	_, found := h.ranges[hash[:hashPrefixLength]][hash[hashPrefixLength:]]
+	return found, nil
+}
+
+// sha1Hex returns the uppercase hex SHA-1 hash used by breach corpora
+func sha1Hex(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// hashField returns the normalized hash part of a "HASH:COUNT" line
+func hashField(line string) string {
+	if colon := strings.IndexByte(line, ':'); colon >= 0 {
+		line = line[:colon]
+	}
+	return strings.ToUpper(strings.TrimSpace(line))
+}
@@ -0,0 +1,226 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"ishare-task-api/internal/config"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Hashing algorithms
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+// ErrUnknownHashFormat is returned for stored hashes no hasher understands
+var ErrUnknownHashFormat = errors.New("unknown password hash format")
+
+// Hasher hashes passwords into self-describing strings that carry the
+// algorithm and its parameters, so stored hashes can outlive a change of
+// configuration
+type Hasher interface {
+	// Hash returns the encoded hash of a password
+	Hash(password string) (string, error)
+	// Verify reports whether the password matches an encoded hash of any
+	// supported algorithm
+	Verify(password, encoded string) (bool, error)
+	// NeedsRehash reports whether an encoded hash was produced with another
+	// algorithm or outdated parameters
+	NeedsRehash(encoded string) bool
+}
+
+// NewHasher creates the hasher configured for new passwords. It verifies
+// argon2id and bcrypt hashes regardless of the configured algorithm.
+func NewHasher(cfg config.PasswordConfig) (Hasher, error) {
+	argon := &Argon2idHasher{
+		Memory:      cfg.Argon2Memory,
+		Iterations:  cfg.Argon2Iterations,
+		Parallelism: cfg.Argon2Parallelism,
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+	bcryptHasher := &BcryptHasher{Cost: cfg.BcryptCost}
+
+	switch cfg.HashAlgorithm {
+	case AlgorithmArgon2id, "":
+		if argon.Memory < 8*uint32(argon.Parallelism) || argon.Iterations < 1 || argon.Parallelism < 1 {
+			return nil, fmt.Errorf("invalid argon2id parameters m=%d t=%d p=%d", argon.Memory, argon.Iterations, argon.Parallelism)
+		}
+		return &multiHasher{current: argon, argon2id: argon, bcrypt: bcryptHasher}, nil
+	case AlgorithmBcrypt:
+		if bcryptHasher.Cost < bcrypt.MinCost || bcryptHasher.Cost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("invalid bcrypt cost %d", bcryptHasher.Cost)
+		}
+		return &multiHasher{current: bcryptHasher, argon2id: argon, bcrypt: bcryptHasher}, nil
+	default:
+		return nil, fmt.Errorf("unsupported password hash algorithm: %s", cfg.HashAlgorithm)
+	}
+}
+
+// multiHasher hashes with the configured algorithm and verifies all of them
+type multiHasher struct {
+	current  Hasher
+	argon2id *Argon2idHasher
+	bcrypt   *BcryptHasher
+}
+
+// Hash implements Hasher
+func (m *multiHasher) Hash(password string) (string, error) {
+	return m.current.Hash(password)
+}
+
+// Verify implements Hasher
+func (m *multiHasher) Verify(password, encoded string) (bool, error) {
+	switch {
+	case strings.HasPrefix(encoded, "$"+AlgorithmArgon2id+"$"):
+		return m.argon2id.Verify(password, encoded)
+	case isBcryptHash(encoded):
+		return m.bcrypt.Verify(password, encoded)
+	default:
+		return false, ErrUnknownHashFormat
+	}
+}
+
+// NeedsRehash implements Hasher
+func (m *multiHasher) NeedsRehash(encoded string) bool {
+	return m.current.NeedsRehash(encoded)
+}
+
+// Argon2idHasher hashes passwords with argon2id and encodes them in the PHC
+// string format: $argon2id$v=19$m=<KiB>,t=<iterations>,p=<parallelism>$<salt>$<hash>
+type Argon2idHasher struct {
+	Memory      uint32 // KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  int
+	KeyLength   uint32
+}
+
+// argon2idParams are the parameters decoded from a PHC string
+type argon2idParams struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+// Hash implements Hasher
+func (a *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, a.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, a.Iterations, a.Memory, a.Parallelism, a.KeyLength)
+
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		AlgorithmArgon2id, argon2.Version, a.Memory, a.Iterations, a.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify implements Hasher
+func (a *Argon2idHasher) Verify(password, encoded string) (bool, error) {
+	params, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	key := argon2.IDKey([]byte(password), params.salt, params.iterations, params.memory, params.parallelism, uint32(len(params.key)))
+	return subtle.ConstantTimeCompare(key, params.key) == 1, nil
+}
+
+// NeedsRehash implements Hasher
+func (a *Argon2idHasher) NeedsRehash(encoded string) bool {
+	params, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+
+	return params.memory != a.Memory ||
+		params.iterations != a.Iterations ||
+		params.parallelism != a.Parallelism ||
+		len(params.salt) != a.SaltLength ||
+		uint32(len(params.key)) != a.KeyLength
+}
+
+// decodeArgon2id parses an argon2id PHC string
+func decodeArgon2id(encoded string) (*argon2idParams, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != AlgorithmArgon2id {
+		return nil, ErrUnknownHashFormat
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, ErrUnknownHashFormat
+	}
+
+	params := &argon2idParams{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil {
+		return nil, ErrUnknownHashFormat
+	}
+
+	var err error
+	if params.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, ErrUnknownHashFormat
+	}
+	if params.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(params.key) == 0 {
+		return nil, ErrUnknownHashFormat
+	}
+
+	return params, nil
+}
+
+// BcryptHasher hashes passwords with bcrypt, whose hashes embed the cost
+type BcryptHasher struct {
+	Cost int
+}
+
+// Hash implements Hasher
+func (b *BcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), b.Cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Verify implements Hasher
+func (b *BcryptHasher) Verify(password, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	switch err {
+	case nil:
+		return true, nil
+	case bcrypt.ErrMismatchedHashAndPassword, bcrypt.ErrPasswordTooLong:
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// NeedsRehash implements Hasher
+func (b *BcryptHasher) NeedsRehash(encoded string) bool {
+	if !isBcryptHash(encoded) {
+		return true
+	}
+
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost != b.Cost
+}
+
+// isBcryptHash reports whether an encoded hash is a bcrypt hash
+func isBcryptHash(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") ||
+		strings.HasPrefix(encoded, "$2b$") ||
+		strings.HasPrefix(encoded, "$2y$")
+}
@@ -0,0 +1,152 @@
+package password
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"ishare-task-api/internal/config"
+)
+
+// Character classes that can be required by the policy
+const (
+	ClassLower  = "lower"
+	ClassUpper  = "upper"
+	ClassDigit  = "digit"
+	ClassSymbol = "symbol"
+)
+
+// PolicyError lists the rules a password violates
+type PolicyError struct {
+	Violations []string
+}
+
+func (e *PolicyError) Error() string {
+	return "password does not meet the policy: " + strings.Join(e.Violations, "; ")
+}
+
+// Policy validates new passwords
+type Policy struct {
+	config   config.PasswordConfig
+	breached BreachedChecker
+}
+
+// NewPolicy creates the password policy, loading the breached password list
+// if one is configured
+func NewPolicy(cfg config.PasswordConfig) (*Policy, error) {
+	for _, class := range cfg.RequiredClasses {
+		switch class {
+		case ClassLower, ClassUpper, ClassDigit, ClassSymbol:
+		default:
+			return nil, fmt.Errorf("unknown password character class: %s", class)
+		}
+	}
+
+	if cfg.MaxLength > 0 && cfg.MaxLength < cfg.MinLength {
+		return nil, fmt.Errorf("password max length %d is below min length %d", cfg.MaxLength, cfg.MinLength)
+	}
+
+	breached, err := NewBreachedChecker(cfg.BreachedList)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Policy{
+		config:   cfg,
+		breached: breached,
+	}, nil
+}
+
+// Validate checks a new password for the account with the given email and
+// returns a PolicyError listing every violated rule
+func (p *Policy) Validate(password, email string) error {
+	var violations []string
+
+	length := utf8.RuneCountInString(password)
+	if length < p.config.MinLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters long", p.config.MinLength))
+	}
+	if p.config.MaxLength > 0 && length > p.config.MaxLength {
+		violations = append(violations, fmt.Sprintf("must be at most %d characters long", p.config.MaxLength))
+	}
+
+	present := characterClasses(password)
+	for _, class := range p.config.RequiredClasses {
+		if !present[class] {
+			violations = append(violations, "must contain "+classDescription(class))
+		}
+	}
+
+	if p.config.RejectEmail && containsEmail(password, email) {
+		violations = append(violations, "must not contain the email address")
+	}
+
+	if len(violations) == 0 && p.breached != nil {
+		breached, err := p.breached.IsBreached(password)
+		if err != nil {
+			return err
+		}
+		if breached {
+			violations = append(violations, "appears in a list of breached passwords, choose a different one")
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+
+	return nil
+}
+
+// characterClasses reports which character classes occur in the password
+func characterClasses(password string) map[string]bool {
+	present := make(map[string]bool)
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			present[ClassLower] = true
+		case unicode.IsUpper(r):
+			present[ClassUpper] = true
+		case unicode.IsDigit(r):
+			present[ClassDigit] = true
+		default:
+			present[ClassSymbol] = true
+		}
+	}
+	return present
+}
+
+// classDescription returns a readable name for a character class
+func classDescription(class string) string {
+	switch class {
+	case ClassLower:
+		return "a lowercase letter"
+	case ClassUpper:
+		return "an uppercase letter"
+	case ClassDigit:
+		return "a digit"
+	default:
+		return "a symbol"
+	}
+}
+
+// containsEmail reports whether the password contains the email address or
+// its local part, ignoring case. Very short local parts are not checked.
+func containsEmail(password, email string) bool {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return false
+	}
+
+	password = strings.ToLower(password)
+	if strings.Contains(password, email) {
+		return true
+	}
+
+	local := email
+	if at := strings.LastIndex(email, "@"); at >= 0 {
+		local = email[:at]
+	}
+	return len(local) >= 3 && strings.Contains(password, local)
+}
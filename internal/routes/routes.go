@@ -1,9 +1,15 @@
 package routes
 
 import (
+	"log"
+
 	"ishare-task-api/internal/auth"
 	"ishare-task-api/internal/config"
+	"ishare-task-api/internal/federation"
 	"ishare-task-api/internal/handlers"
+	"ishare-task-api/internal/mail"
+	"ishare-task-api/internal/models"
+	"ishare-task-api/internal/password"
 
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
@@ -12,17 +18,72 @@ import (
 )
 
 // Setup configures all routes and middleware
-func Setup(cfg *config.Config, db *gorm.DB) *gin.Engine {
+func Setup(cfg *config.Config, db *gorm.DB) (*gin.Engine, error) {
 	router := gin.Default()
 
 	// Initialize auth components
-	jwtManager := auth.NewJWTManager(cfg.JWT)
-	oauthManager := auth.NewOAuthManager(cfg.OAuth, db, jwtManager)
-	authMiddleware := auth.NewAuthMiddleware(jwtManager, db)
+	jwtManager, err := auth.NewJWTManager(cfg.JWT)
+	if err != nil {
+		return nil, err
+	}
+	passwordPolicy, err := password.NewPolicy(cfg.Password)
+	if err != nil {
+		return nil, err
+	}
+	passwordHasher, err := password.NewHasher(cfg.Password)
+	if err != nil {
+		return nil, err
+	}
+	revocations := auth.NewRevocationCache(db, cfg.JWT.RevocationRefresh)
+	userCache := auth.NewUserCache(db, cfg.JWT.UserCacheTTL)
+	oauthManager := auth.NewOAuthManager(cfg.OAuth, db, jwtManager, revocations, passwordPolicy, passwordHasher)
+	rbac := auth.NewRBAC(db, cfg.JWT.UserCacheTTL)
+	authMiddleware := auth.NewAuthMiddleware(jwtManager, db, revocations, userCache, rbac)
+
+	// Password sign-in tries the backends listed in AUTHENTICATORS in order
+	authenticators, err := auth.NewAuthenticators(cfg.OAuth.Authenticators, oauthManager, rbac, cfg.LDAP)
+	if err != nil {
+		return nil, err
+	}
+	oauthManager.SetAuthenticators(authenticators...)
+
+	// Accounts listed in ADMIN_EMAILS are granted the admin role
+	granted, err := rbac.BootstrapAdmins(cfg.OAuth.AdminEmails)
+	if err != nil {
+		return nil, err
+	}
+	if granted > 0 {
+		log.Printf("Granted the admin role to %d account(s) from ADMIN_EMAILS", granted)
+	}
+
+	// Stateless validation relies on the in-memory revocation list
+	if cfg.JWT.ValidationMode == auth.ValidationModeStateless {
+		revocations.Start()
+	}
+
+	mailer, err := mail.New(cfg.Mail)
+	if err != nil {
+		return nil, err
+	}
+
+	providers, err := federation.NewRegistry(cfg.Federation, nil)
+	if err != nil {
+		return nil, err
+	}
 
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(oauthManager, cfg)
-	taskHandler := handlers.NewTaskHandler(db)
+	authHandler := handlers.NewAuthHandler(oauthManager, cfg, mailer, providers)
+	taskAuthorizer := auth.NewTaskAuthorizer(db)
+	taskHandler := handlers.NewTaskHandler(taskAuthorizer)
+	projectHandler := handlers.NewProjectHandler(taskAuthorizer)
+	workflowHandler := handlers.NewWorkflowHandler(taskAuthorizer)
+	sessionHandler := handlers.NewSessionHandler(oauthManager)
+	tokenHandler := handlers.NewTokenHandler(oauthManager)
+	mfaHandler := handlers.NewMFAHandler(oauthManager)
+	adminHandler := handlers.NewAdminHandler(oauthManager, rbac, userCache, cfg, mailer)
+	meHandler := handlers.NewMeHandler(oauthManager, cfg, mailer, userCache)
+	scimHandler := handlers.NewSCIMHandler(oauthManager, userCache, db, cfg)
+	orgHandler := handlers.NewOrganizationHandler(oauthManager, rbac)
 
 	// Load HTML templates for OAuth flow
 	router.LoadHTMLGlob("templates/*")
@@ -43,21 +104,148 @@ func Setup(cfg *config.Config, db *gorm.DB) *gin.Engine {
 	{
 		oauth.GET("/authorize", authHandler.Authorize)
 		oauth.POST("/login", authHandler.Login)
+		oauth.POST("/login/mfa", authHandler.LoginMFA)
+		oauth.POST("/logout", authHandler.Logout)
 		oauth.POST("/token", authHandler.Token)
+		oauth.POST("/revoke", authHandler.Revoke)
 		oauth.GET("/callback", authHandler.Callback)
+		oauth.GET("/federated/:provider", authHandler.FederatedLogin)
+		oauth.GET("/federated/:provider/callback", authHandler.FederatedCallback)
 		oauth.POST("/register", authHandler.Register)
+		oauth.GET("/verify-email", authHandler.VerifyEmail)
+		oauth.POST("/verify-email/resend", authHandler.ResendVerification)
 		oauth.POST("/cleanup", authHandler.CleanupTokens)
+		oauth.GET("/password/forgot", authHandler.ForgotPasswordPage)
+		oauth.POST("/password/forgot", authHandler.ForgotPassword)
+		oauth.GET("/password/reset", authHandler.ResetPasswordPage)
+		oauth.POST("/password/reset", authHandler.ResetPassword)
 	}
 
 	// Task management routes (authentication required)
 	tasks := router.Group("/tasks")
-	tasks.Use(authMiddleware.Authenticate())
+	tasks.Use(authMiddleware.Authenticate(), authMiddleware.TenantScope())
+	{
+		tasks.POST("", authMiddleware.RequirePermission(models.PermissionTasksWrite), taskHandler.CreateTask)
+		tasks.GET("", authMiddleware.RequirePermission(models.PermissionTasksRead), taskHandler.ListTasks)
+		tasks.GET("/:id", authMiddleware.RequirePermission(models.PermissionTasksRead), taskHandler.GetTask)
+		tasks.PUT("/:id", authMiddleware.RequirePermission(models.PermissionTasksWrite), taskHandler.UpdateTask)
+		tasks.DELETE("/:id", authMiddleware.RequirePermission(models.PermissionTasksDelete), taskHandler.DeleteTask)
+		tasks.GET("/:id/workflow", authMiddleware.RequirePermission(models.PermissionTasksRead), taskHandler.GetTaskWorkflow)
+		tasks.GET("/:id/shares", authMiddleware.RequirePermission(models.PermissionTasksRead), taskHandler.ListShares)
+		tasks.POST("/:id/shares", authMiddleware.RequirePermission(models.PermissionTasksWrite), taskHandler.ShareTask)
+		tasks.DELETE("/:id/shares/:user_id", authMiddleware.RequirePermission(models.PermissionTasksRead), taskHandler.UnshareTask)
+		tasks.GET("/:id/links", authMiddleware.RequirePermission(models.PermissionTasksWrite), taskHandler.ListLinks)
+		tasks.POST("/:id/links", authMiddleware.RequirePermission(models.PermissionTasksWrite), taskHandler.CreateLink)
+		tasks.DELETE("/:id/links/:link_id", authMiddleware.RequirePermission(models.PermissionTasksWrite), taskHandler.RevokeLink)
+		tasks.POST("/links/accept", authMiddleware.RequirePermission(models.PermissionTasksRead), taskHandler.AcceptLink)
+	}
+
+	// Project routes (authentication required)
+	projects := router.Group("/projects")
+	projects.Use(authMiddleware.Authenticate(), authMiddleware.TenantScope())
 	{
-		tasks.POST("", taskHandler.CreateTask)
-		tasks.GET("", taskHandler.ListTasks)
-		tasks.GET("/:id", taskHandler.GetTask)
-		tasks.PUT("/:id", taskHandler.UpdateTask)
-		tasks.DELETE("/:id", taskHandler.DeleteTask)
+		projects.GET("", authMiddleware.RequirePermission(models.PermissionTasksRead), projectHandler.ListProjects)
+		projects.POST("", authMiddleware.RequirePermission(models.PermissionTasksWrite), projectHandler.CreateProject)
+		projects.GET("/:id", authMiddleware.RequirePermission(models.PermissionTasksRead), projectHandler.GetProject)
+		projects.PATCH("/:id", authMiddleware.RequirePermission(models.PermissionTasksWrite), projectHandler.UpdateProject)
+		projects.DELETE("/:id", authMiddleware.RequirePermission(models.PermissionTasksDelete), projectHandler.DeleteProject)
+		projects.GET("/:id/tasks", authMiddleware.RequirePermission(models.PermissionTasksRead), projectHandler.ListProjectTasks)
+		projects.GET("/:id/members", authMiddleware.RequirePermission(models.PermissionTasksRead), projectHandler.ListMembers)
+		projects.POST("/:id/members", authMiddleware.RequirePermission(models.PermissionTasksWrite), projectHandler.AddMember)
+		projects.DELETE("/:id/members/:user_id", authMiddleware.RequirePermission(models.PermissionTasksRead), projectHandler.RemoveMember)
+		projects.GET("/:id/workflow", authMiddleware.RequirePermission(models.PermissionTasksRead), workflowHandler.GetProjectWorkflow)
+		projects.PUT("/:id/workflow", authMiddleware.RequirePermission(models.PermissionTasksWrite), workflowHandler.SetProjectWorkflow)
+		projects.DELETE("/:id/workflow", authMiddleware.RequirePermission(models.PermissionTasksWrite), workflowHandler.ResetProjectWorkflow)
+	}
+
+	// Current user routes (authentication required, personal access tokens refused)
+	me := router.Group("/me")
+	me.Use(authMiddleware.Authenticate(), authMiddleware.RejectPersonalAccessTokens())
+	{
+		me.GET("", meHandler.GetMe)
+		me.PATCH("", meHandler.UpdateMe)
+		me.DELETE("", meHandler.DeleteMe)
+		me.POST("/password", meHandler.ChangePassword)
+		me.POST("/email", meHandler.ChangeEmail)
+		me.GET("/sessions", sessionHandler.ListSessions)
+		me.DELETE("/sessions", sessionHandler.RevokeAllSessions)
+		me.DELETE("/sessions/:id", sessionHandler.RevokeSession)
+		me.GET("/tokens", tokenHandler.ListTokens)
+		me.POST("/tokens", tokenHandler.CreateToken)
+		me.DELETE("/tokens/:id", tokenHandler.RevokeToken)
+		me.GET("/orgs", orgHandler.ListMyOrganizations)
+		me.POST("/orgs/switch", orgHandler.SwitchOrganization)
+		me.GET("/mfa", mfaHandler.GetStatus)
+		me.POST("/mfa/totp", mfaHandler.BeginTOTP)
+		me.POST("/mfa/totp/confirm", mfaHandler.ConfirmTOTP)
+		me.DELETE("/mfa/totp", mfaHandler.DisableTOTP)
+		me.POST("/mfa/recovery-codes", mfaHandler.RegenerateRecoveryCodes)
+	}
+
+	// Active organization routes (organization role required per route)
+	org := router.Group("/org")
+	org.Use(authMiddleware.Authenticate())
+	{
+		org.GET("", authMiddleware.RequirePermission(models.PermissionOrgRead), orgHandler.GetOrganization)
+		org.GET("/members", authMiddleware.RequirePermission(models.PermissionOrgRead), orgHandler.ListMembers)
+		org.POST("/members", authMiddleware.RequirePermission(models.PermissionOrgManage), orgHandler.AddMember)
+		org.PATCH("/members/:id", authMiddleware.RequirePermission(models.PermissionOrgManage), orgHandler.UpdateMember)
+		org.DELETE("/members/:id", authMiddleware.RequirePermission(models.PermissionOrgManage), orgHandler.RemoveMember)
+		org.GET("/workflow", authMiddleware.RequirePermission(models.PermissionOrgRead), authMiddleware.TenantScope(), workflowHandler.GetOrganizationWorkflow)
+		org.PUT("/workflow", authMiddleware.RequirePermission(models.PermissionOrgManage), authMiddleware.TenantScope(), workflowHandler.SetOrganizationWorkflow)
+		org.DELETE("/workflow", authMiddleware.RequirePermission(models.PermissionOrgManage), authMiddleware.TenantScope(), workflowHandler.ResetOrganizationWorkflow)
+	}
+
+	// Administrative routes (permission required per route)
+	admin := router.Group("/admin")
+	admin.Use(authMiddleware.Authenticate())
+	{
+		admin.GET("/lockouts", authMiddleware.RequirePermission(models.PermissionUsersManage), adminHandler.ListLockouts)
+		admin.POST("/lockouts/unlock", authMiddleware.RequirePermission(models.PermissionUsersManage), adminHandler.Unlock)
+		admin.GET("/audit", authMiddleware.RequirePermission(models.PermissionAuditRead), adminHandler.ListAuditEvents)
+		admin.GET("/users", authMiddleware.RequirePermission(models.PermissionUsersRead), adminHandler.ListUsers)
+		admin.GET("/users/:id", authMiddleware.RequirePermission(models.PermissionUsersRead), adminHandler.GetUser)
+		admin.DELETE("/users/:id", authMiddleware.RequirePermission(models.PermissionUsersManage), adminHandler.DeleteUser)
+		admin.POST("/users/:id/disable", authMiddleware.RequirePermission(models.PermissionUsersManage), adminHandler.DisableUser)
+		admin.POST("/users/:id/enable", authMiddleware.RequirePermission(models.PermissionUsersManage), adminHandler.EnableUser)
+		admin.POST("/users/:id/password-reset", authMiddleware.RequirePermission(models.PermissionUsersManage), adminHandler.ForcePasswordReset)
+		admin.POST("/users/:id/revoke-tokens", authMiddleware.RequirePermission(models.PermissionUsersManage), adminHandler.RevokeUserTokens)
+		admin.GET("/orgs", authMiddleware.RequirePermission(models.PermissionOrgsManage), adminHandler.ListOrganizations)
+		admin.POST("/orgs", authMiddleware.RequirePermission(models.PermissionOrgsManage), adminHandler.CreateOrganization)
+		admin.POST("/orgs/:id/members", authMiddleware.RequirePermission(models.PermissionOrgsManage), adminHandler.AddOrganizationMember)
+
+		roles := admin.Group("")
+		roles.Use(authMiddleware.RequirePermission(models.PermissionRolesManage))
+		{
+			roles.GET("/roles", adminHandler.ListRoles)
+			roles.POST("/roles", adminHandler.CreateRole)
+			roles.PATCH("/roles/:name", adminHandler.UpdateRole)
+			roles.DELETE("/roles/:name", adminHandler.DeleteRole)
+			roles.GET("/permissions", adminHandler.ListPermissions)
+			roles.GET("/users/:id/roles", adminHandler.ListUserRoles)
+			roles.POST("/users/:id/roles", adminHandler.AssignRole)
+			roles.DELETE("/users/:id/roles/:role", adminHandler.RemoveRole)
+		}
+	}
+
+	// SCIM 2.0 provisioning routes (SCIM bearer token required)
+	scim := router.Group("/scim/v2")
+	scim.Use(scimHandler.Authenticate())
+	{
+		scim.GET("/ServiceProviderConfig", scimHandler.ServiceProviderConfig)
+		scim.GET("/ResourceTypes", scimHandler.ResourceTypes)
+		scim.GET("/Users", scimHandler.ListUsers)
+		scim.POST("/Users", scimHandler.CreateUser)
+		scim.GET("/Users/:id", scimHandler.GetUser)
+		scim.PUT("/Users/:id", scimHandler.ReplaceUser)
+		scim.PATCH("/Users/:id", scimHandler.PatchUser)
+		scim.DELETE("/Users/:id", scimHandler.DeleteUser)
+		scim.GET("/Groups", scimHandler.ListGroups)
+		scim.POST("/Groups", scimHandler.CreateGroup)
+		scim.GET("/Groups/:id", scimHandler.GetGroup)
+		scim.PUT("/Groups/:id", scimHandler.ReplaceGroup)
+		scim.PATCH("/Groups/:id", scimHandler.PatchGroup)
+		scim.DELETE("/Groups/:id", scimHandler.DeleteGroup)
 	}
 
 	// API documentation endpoint
@@ -71,20 +259,99 @@ func Setup(cfg *config.Config, db *gorm.DB) *gin.Engine {
 				"oauth": gin.H{
 					"authorize": "GET /oauth/authorize - OAuth 2.0 authorization endpoint",
 					"token": "POST /oauth/token - OAuth 2.0 token endpoint",
+					"revoke": "POST /oauth/revoke - OAuth 2.0 token revocation endpoint",
 					"callback": "GET /oauth/callback - OAuth callback endpoint",
+					"federated_login": "GET /oauth/federated/{provider} - Sign in with an upstream OpenID Connect provider",
+					"federated_callback": "GET /oauth/federated/{provider}/callback - Callback from an upstream OpenID Connect provider",
 					"register": "POST /oauth/register - User registration",
+					"verify_email": "GET /oauth/verify-email - Verify an email address from a signed link",
+					"resend_verification": "POST /oauth/verify-email/resend - Re-send the verification email",
+					"forgot_password": "POST /oauth/password/forgot - Request a password reset email",
+					"reset_password": "POST /oauth/password/reset - Reset password with a reset token",
 				},
 				"tasks": gin.H{
 					"create": "POST /tasks - Create a new task",
-					"list": "GET /tasks - List all tasks",
+					"list": "GET /tasks - List your own, shared and project tasks in the active organization; filter by status, due_before, due_after, overdue and due_today",
 					"get": "GET /tasks/{id} - Get a specific task",
 					"update": "PUT /tasks/{id} - Update a task",
 					"delete": "DELETE /tasks/{id} - Delete a task",
+					"workflow": "GET /tasks/{id}/workflow - Get the workflow of a task and its next statuses",
+					"shares": "GET|POST /tasks/{id}/shares - List shares or share a task with a member",
+					"unshare": "DELETE /tasks/{id}/shares/{user_id} - Remove a share",
+					"links": "GET|POST /tasks/{id}/links - List or create share-by-link tokens",
+					"revoke_link": "DELETE /tasks/{id}/links/{link_id} - Revoke a share-by-link token",
+					"accept_link": "POST /tasks/links/accept - Accept a share-by-link token",
+				},
+				"projects": gin.H{
+					"list": "GET /projects - List your projects in the active organization",
+					"create": "POST /projects - Create a project",
+					"get": "GET /projects/{id} - Get a project",
+					"update": "PATCH /projects/{id} - Rename, describe or archive a project",
+					"delete": "DELETE /projects/{id} - Delete a project",
+					"tasks": "GET /projects/{id}/tasks - List the tasks of a project",
+					"members": "GET|POST /projects/{id}/members - List or add project members",
+					"remove_member": "DELETE /projects/{id}/members/{user_id} - Remove a project member",
+					"workflow": "GET|PUT|DELETE /projects/{id}/workflow - Get, define or reset the status workflow of a project",
+				},
+				"me": gin.H{
+					"get": "GET /me - Get the current user",
+					"update": "PATCH /me - Update display name, locale and timezone",
+					"delete": "DELETE /me - Delete the account after a grace period",
+					"change_password": "POST /me/password - Change the password",
+					"change_email": "POST /me/email - Change the email address",
+					"sessions": "GET /me/sessions - List active sessions",
+					"revoke_session": "DELETE /me/sessions/{id} - Revoke a session",
+					"revoke_all_sessions": "DELETE /me/sessions - Sign out everywhere",
+					"tokens": "GET /me/tokens - List personal access tokens",
+					"create_token": "POST /me/tokens - Create a personal access token",
+					"revoke_token": "DELETE /me/tokens/{id} - Revoke a personal access token",
+					"orgs": "GET /me/orgs - List the organizations of the current user",
+					"switch_org": "POST /me/orgs/switch - Exchange the token for one in another organization",
+					"mfa": "POST /me/mfa/totp - Enroll a TOTP authenticator",
+				},
+				"org": gin.H{
+					"get": "GET /org - Get the active organization",
+					"members": "GET /org/members - List the members of the active organization",
+					"add_member": "POST /org/members - Add a user to the active organization",
+					"update_member": "PATCH /org/members/{id} - Change the role of a member",
+					"remove_member": "DELETE /org/members/{id} - Remove a member",
+					"workflow": "GET|PUT|DELETE /org/workflow - Get, define or reset the status workflow of the active organization",
+				},
+				"admin": gin.H{
+					"lockouts": "GET /admin/lockouts - List locked accounts and IP addresses",
+					"unlock": "POST /admin/lockouts/unlock - Lift a login lockout",
+					"audit": "GET /admin/audit - List security audit events",
+					"users": "GET /admin/users - List and search users",
+					"get_user": "GET /admin/users/{id} - Get a user",
+					"delete_user": "DELETE /admin/users/{id} - Delete a user",
+					"disable_user": "POST /admin/users/{id}/disable - Disable a user and revoke its tokens",
+					"enable_user": "POST /admin/users/{id}/enable - Enable a disabled user",
+					"force_password_reset": "POST /admin/users/{id}/password-reset - Force a password reset",
+					"revoke_user_tokens": "POST /admin/users/{id}/revoke-tokens - Revoke all tokens of a user",
+					"orgs": "GET /admin/orgs - List organizations",
+					"create_org": "POST /admin/orgs - Create an organization",
+					"add_org_member": "POST /admin/orgs/{id}/members - Add a user to an organization",
+					"roles": "GET /admin/roles - List roles and their permissions",
+					"create_role": "POST /admin/roles - Create a custom role",
+					"update_role": "PATCH /admin/roles/{name} - Update a custom role",
+					"delete_role": "DELETE /admin/roles/{name} - Delete a custom role",
+					"permissions": "GET /admin/permissions - List permissions",
+					"user_roles": "GET /admin/users/{id}/roles - List a user's roles",
+					"assign_role": "POST /admin/users/{id}/roles - Assign a role to a user",
+					"remove_role": "DELETE /admin/users/{id}/roles/{role} - Remove a role from a user",
+				},
+				"scim": gin.H{
+					"service_provider_config": "GET /scim/v2/ServiceProviderConfig - Supported SCIM features",
+					"resource_types": "GET /scim/v2/ResourceTypes - SCIM resource types",
+					"users": "GET|POST /scim/v2/Users - List (filter, startIndex, count) and provision users",
+					"user": "GET|PUT|PATCH|DELETE /scim/v2/Users/{id} - Get, replace, patch or delete a user",
+					"groups": "GET|POST /scim/v2/Groups - List and create groups",
+					"group": "GET|PUT|PATCH|DELETE /scim/v2/Groups/{id} - Get, replace, patch or delete a group",
 				},
 			},
-			"authentication": "All task endpoints require Bearer token authentication",
+			"authentication": "All task endpoints require Bearer token (access token or personal access token) authentication and a role in the token's organization granting the tasks permission",
 		})
 	})
 
-	return router
+	return router, nil
 } 
\ No newline at end of file
@@ -0,0 +1,444 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Expr is a parsed filter expression (RFC 7644 section 3.4.2.2)
+type Expr interface {
+	expr()
+}
+
+// LogicalExpr combines two expressions with "and" or "or"
+type LogicalExpr struct {
+	Operator string
+	Left     Expr
+	Right    Expr
+}
+
+// NotExpr negates an expression
+type NotExpr struct {
+	Expr Expr
+}
+
+// AttributeExpr compares an attribute with a value; Value is nil for "pr"
+type AttributeExpr struct {
+	Attribute string
+	Operator  string
+	Value     interface{}
+}
+
+// ValuePathExpr filters the values of a multi-valued attribute, as in
+// emails[type eq "work"]
+type ValuePathExpr struct {
+	Attribute string
+	Filter    Expr
+}
+
+func (*LogicalExpr) expr()   {}
+func (*NotExpr) expr()       {}
+func (*AttributeExpr) expr() {}
+func (*ValuePathExpr) expr() {}
+
+// comparison operators; "pr" takes no value
+var comparisonOperators = map[string]bool{
+	"eq": true, "ne": true, "co": true, "sw": true, "ew": true,
+	"gt": true, "ge": true, "lt": true, "le": true, "pr": true,
+}
+
+// ParseFilter parses a filter. Attribute names are normalized with
+// NormalizeAttribute and operators are lower-cased.
+func ParseFilter(filter string) (Expr, error) {
+	tokens, err := tokenize(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, BadRequest(ErrorInvalidFilter, fmt.Sprintf("unexpected %q in filter", p.peek().text))
+	}
+	return expr, nil
+}
+
+// EqualValues returns the values of a filter that only compares the
+// attribute for equality, joined with "or", as in
+// value eq "1" or value eq "2"
+func EqualValues(expr Expr, attribute string) ([]string, bool) {
+	switch e := expr.(type) {
+	case *AttributeExpr:
+		value, ok := e.Value.(string)
+		if !ok || e.Operator != "eq" || e.Attribute != attribute {
+			return nil, false
+		}
+		return []string{value}, true
+	case *LogicalExpr:
+		if e.Operator != "or" {
+			return nil, false
+		}
+		left, ok := EqualValues(e.Left, attribute)
+		if !ok {
+			return nil, false
+		}
+		right, ok := EqualValues(e.Right, attribute)
+		if !ok {
+			return nil, false
+		}
+		return append(left, right...), true
+	default:
+		return nil, false
+	}
+}
+
+// AttributeKind is the type of a filterable attribute
+type AttributeKind int
+
+// Attribute kinds
+const (
+	KindString          AttributeKind = iota // compared case-insensitively
+	KindCaseExactString                      // compared case-sensitively
+	KindBoolean
+	KindDateTime
+)
+
+// Attribute maps a filterable attribute onto an SQL expression. Within,
+// if set, wraps the condition, e.g. in a subquery on a join table.
+type Attribute struct {
+	Column string
+	Kind   AttributeKind
+	Within string
+}
+
+// Attributes maps normalized attribute paths onto SQL expressions
+type Attributes map[string]Attribute
+
+// Where translates a filter into an SQL condition with placeholders
+func (attributes Attributes) Where(expr Expr) (string, []interface{}, error) {
+	return attributes.where(expr, "")
+}
+
+func (attributes Attributes) where(expr Expr, prefix string) (string, []interface{}, error) {
+	switch e := expr.(type) {
+	case *LogicalExpr:
+		left, leftArgs, err := attributes.where(e.Left, prefix)
+		if err != nil {
+			return "", nil, err
+		}
+		right, rightArgs, err := attributes.where(e.Right, prefix)
+		if err != nil {
+			return "", nil, err
+		}
+		return "(" + left + " " + strings.ToUpper(e.Operator) + " " + right + ")", append(leftArgs, rightArgs...), nil
+
+	case *NotExpr:
+		inner, args, err := attributes.where(e.Expr, prefix)
+		if err != nil {
+			return "", nil, err
+		}
+		return "NOT (" + inner + ")", args, nil
+
+	case *ValuePathExpr:
+		return attributes.where(e.Filter, prefix+e.Attribute+".")
+
+	case *AttributeExpr:
+		attribute, ok := attributes[prefix+e.Attribute]
+		if !ok {
+			return "", nil, BadRequest(ErrorInvalidFilter, fmt.Sprintf("attribute %q cannot be filtered", prefix+e.Attribute))
+		}
+		condition, args, err := attribute.condition(e.Operator, e.Value)
+		if err != nil {
+			return "", nil, err
+		}
+		if attribute.Within != "" {
+			condition = fmt.Sprintf(attribute.Within, condition)
+		}
+		return condition, args, nil
+
+	default:
+		return "", nil, BadRequest(ErrorInvalidFilter, "unsupported filter")
+	}
+}
+
+// sqlOperators maps ordering and equality operators onto SQL
+var sqlOperators = map[string]string{
+	"eq": "=", "ne": "<>", "gt": ">", "ge": ">=", "lt": "<", "le": "<=",
+}
+
+// condition builds the SQL condition comparing the attribute with a value
+func (a Attribute) condition(operator string, value interface{}) (string, []interface{}, error) {
+	column := a.Column
+
+	switch a.Kind {
+	case KindString, KindCaseExactString:
+		if operator == "pr" {
+			return fmt.Sprintf("(%s IS NOT NULL AND %s <> '')", column, column), nil, nil
+		}
+		text, ok := value.(string)
+		if !ok {
+			return "", nil, BadRequest(ErrorInvalidFilter, "a string value is required")
+		}
+		placeholder := "?"
+		if a.Kind == KindString {
+			column = "LOWER(" + column + ")"
+			placeholder = "LOWER(?)"
+		}
+		switch operator {
+		case "co":
+			return column + " LIKE " + placeholder, []interface{}{"%" + escapeLike(text) + "%"}, nil
+		case "sw":
+			return column + " LIKE " + placeholder, []interface{}{escapeLike(text) + "%"}, nil
+		case "ew":
+			return column + " LIKE " + placeholder, []interface{}{"%" + escapeLike(text)}, nil
+		default:
+			return column + " " + sqlOperators[operator] + " " + placeholder, []interface{}{text}, nil
+		}
+
+	case KindBoolean:
+		if operator == "pr" {
+			return "TRUE", nil, nil
+		}
+		flag, ok := value.(bool)
+		if !ok || (operator != "eq" && operator != "ne") {
+			return "", nil, BadRequest(ErrorInvalidFilter, "boolean attributes support eq and ne with true or false")
+		}
+		return "(" + column + ") " + sqlOperators[operator] + " ?", []interface{}{flag}, nil
+
+	case KindDateTime:
+		if operator == "pr" {
+			return column + " IS NOT NULL", nil, nil
+		}
+		text, ok := value.(string)
+		if !ok {
+			return "", nil, BadRequest(ErrorInvalidFilter, "a date-time value is required")
+		}
+		moment, err := time.Parse(time.RFC3339, text)
+		if err != nil {
+			return "", nil, BadRequest(ErrorInvalidFilter, "date-time values must use RFC 3339")
+		}
+		sqlOperator, ok := sqlOperators[operator]
+		if !ok {
+			return "", nil, BadRequest(ErrorInvalidFilter, fmt.Sprintf("operator %q does not apply to date-time attributes", operator))
+		}
+		return column + " " + sqlOperator + " ?", []interface{}{moment}, nil
+
+	default:
+		return "", nil, BadRequest(ErrorInvalidFilter, "unsupported attribute")
+	}
+}
+
+// escapeLike escapes the wildcard characters of a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// token kinds
+const (
+	tokenWord = iota
+	tokenString
+	tokenOpen         // (
+	tokenClose        // )
+	tokenOpenBracket  // [
+	tokenCloseBracket // ]
+)
+
+type token struct {
+	kind int
+	text string
+}
+
+// tokenize splits a filter into words, quoted strings and brackets
+func tokenize(filter string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(filter); {
+		switch ch := filter[i]; {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			i++
+		case ch == '(':
+			tokens = append(tokens, token{tokenOpen, "("})
+			i++
+		case ch == ')':
+			tokens = append(tokens, token{tokenClose, ")"})
+			i++
+		case ch == '[':
+			tokens = append(tokens, token{tokenOpenBracket, "["})
+			i++
+		case ch == ']':
+			tokens = append(tokens, token{tokenCloseBracket, "]"})
+			i++
+		case ch == '"':
+			end := i + 1
+			for ; end < len(filter) && filter[end] != '"'; end++ {
+				if filter[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(filter) {
+				return nil, BadRequest(ErrorInvalidFilter, "unterminated string in filter")
+			}
+			var text string
+			if err := json.Unmarshal([]byte(filter[i:end+1]), &text); err != nil {
+				return nil, BadRequest(ErrorInvalidFilter, "invalid string in filter")
+			}
+			tokens = append(tokens, token{tokenString, text})
+			i = end + 1
+		default:
+			end := i
+			for end < len(filter) && !strings.ContainsRune(" \t\n\r()[]\"", rune(filter[end])) {
+				end++
+			}
+			tokens = append(tokens, token{tokenWord, filter[i:end]})
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+// parser is a recursive descent parser over filter tokens
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *parser) peek() token {
+	if p.done() {
+		return token{kind: -1}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+// keyword reports whether the next token is the given word
+func (p *parser) keyword(word string) bool {
+	t := p.peek()
+	return t.kind == tokenWord && strings.EqualFold(t.text, word)
+}
+
+func (p *parser) expect(kind int, text string) error {
+	if t := p.next(); t.kind != kind {
+		return BadRequest(ErrorInvalidFilter, fmt.Sprintf("expected %q in filter", text))
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &LogicalExpr{Operator: "or", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &LogicalExpr{Operator: "and", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	if p.keyword("not") {
+		p.next()
+		if err := p.expect(tokenOpen, "("); err != nil {
+			return nil, err
+		}
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenClose, ")"); err != nil {
+			return nil, err
+		}
+		return &NotExpr{Expr: inner}, nil
+	}
+
+	if p.peek().kind == tokenOpen {
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenClose, ")"); err != nil {
+			return nil, err
+		}
+		return inner, nil
+	}
+
+	return p.parseAttribute()
+}
+
+func (p *parser) parseAttribute() (Expr, error) {
+	t := p.next()
+	if t.kind != tokenWord {
+		return nil, BadRequest(ErrorInvalidFilter, "expected an attribute in filter")
+	}
+	attribute := NormalizeAttribute(t.text)
+
+	if p.peek().kind == tokenOpenBracket {
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenCloseBracket, "]"); err != nil {
+			return nil, err
+		}
+		return &ValuePathExpr{Attribute: attribute, Filter: inner}, nil
+	}
+
+	operatorToken := p.next()
+	operator := strings.ToLower(operatorToken.text)
+	if operatorToken.kind != tokenWord || !comparisonOperators[operator] {
+		return nil, BadRequest(ErrorInvalidFilter, fmt.Sprintf("expected an operator after %q", t.text))
+	}
+	if operator == "pr" {
+		return &AttributeExpr{Attribute: attribute, Operator: operator}, nil
+	}
+
+	valueToken := p.next()
+	var value interface{}
+	switch {
+	case valueToken.kind == tokenString:
+		value = valueToken.text
+	case valueToken.kind == tokenWord && strings.EqualFold(valueToken.text, "true"):
+		value = true
+	case valueToken.kind == tokenWord && strings.EqualFold(valueToken.text, "false"):
+		value = false
+	default:
+		return nil, BadRequest(ErrorInvalidFilter, fmt.Sprintf("unsupported value for %q", t.text))
+	}
+
+	return &AttributeExpr{Attribute: attribute, Operator: operator, Value: value}, nil
+}
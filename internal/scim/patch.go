@@ -0,0 +1,134 @@
+package scim
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// PatchRequest is the body of a PATCH request (RFC 7644 section 3.5.2)
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+// Validate checks the schema and operations of the request
+func (r *PatchRequest) Validate() *Error {
+	if !HasSchema(r.Schemas, SchemaPatchOp) {
+		return BadRequest(ErrorInvalidSyntax, "schemas must contain "+SchemaPatchOp)
+	}
+	if len(r.Operations) == 0 {
+		return BadRequest(ErrorInvalidSyntax, "at least one operation is required")
+	}
+	for _, operation := range r.Operations {
+		switch operation.Operation() {
+		case "add", "replace":
+			if len(operation.Value) == 0 {
+				return BadRequest(ErrorInvalidSyntax, operation.Op+" requires a value")
+			}
+		case "remove":
+			if operation.Path == "" {
+				return BadRequest(ErrorNoTarget, "remove requires a path")
+			}
+		default:
+			return BadRequest(ErrorInvalidSyntax, "unsupported operation "+operation.Op)
+		}
+	}
+	return nil
+}
+
+// PatchOperation is a single add, remove or replace operation
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Operation returns the lower-cased operation name; some clients send
+// "Replace" or "Add"
+func (o PatchOperation) Operation() string {
+	return strings.ToLower(o.Op)
+}
+
+// Path is a parsed PATCH path: attribute[filter].subAttribute
+type Path struct {
+	Attribute    string
+	Filter       Expr
+	SubAttribute string
+}
+
+// ParsePath parses the path of a PATCH operation
+func ParsePath(path string) (*Path, *Error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return nil, BadRequest(ErrorInvalidPath, "empty path")
+	}
+
+	parsed := &Path{}
+	rest := ""
+	if open := strings.Index(path, "["); open >= 0 {
+		end := strings.LastIndex(path, "]")
+		if end < open {
+			return nil, BadRequest(ErrorInvalidPath, "unbalanced brackets in path")
+		}
+		filter, err := ParseFilter(path[open+1 : end])
+		if err != nil {
+			return nil, BadRequest(ErrorInvalidPath, err.Error())
+		}
+		parsed.Attribute = NormalizeAttribute(path[:open])
+		parsed.Filter = filter
+		rest = path[end+1:]
+		if rest != "" && !strings.HasPrefix(rest, ".") {
+			return nil, BadRequest(ErrorInvalidPath, "invalid path "+path)
+		}
+		parsed.SubAttribute = NormalizeAttribute(strings.TrimPrefix(rest, "."))
+		return parsed, nil
+	}
+
+	attribute := NormalizeAttribute(path)
+	if dot := strings.Index(attribute, "."); dot >= 0 {
+		parsed.Attribute = attribute[:dot]
+		parsed.SubAttribute = attribute[dot+1:]
+	} else {
+		parsed.Attribute = attribute
+	}
+	return parsed, nil
+}
+
+// StringValue decodes a string operation value
+func StringValue(raw json.RawMessage) (string, *Error) {
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return "", BadRequest(ErrorInvalidValue, "a string value is required")
+	}
+	return value, nil
+}
+
+// BoolValue decodes a boolean operation value. Strings such as "False" are
+// accepted too, as some clients send them.
+func BoolValue(raw json.RawMessage) (bool, *Error) {
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err == nil {
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			if strings.EqualFold(v, "true") {
+				return true, nil
+			}
+			if strings.EqualFold(v, "false") {
+				return false, nil
+			}
+		}
+	}
+	return false, BadRequest(ErrorInvalidValue, "a boolean value is required")
+}
+
+// HasSchema reports whether the schema URN is listed
+func HasSchema(schemas []string, schema string) bool {
+	for _, s := range schemas {
+		if strings.EqualFold(s, schema) {
+			return true
+		}
+	}
+	return false
+}
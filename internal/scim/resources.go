@@ -0,0 +1,292 @@
+package scim
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContentType is the media type of SCIM requests and responses
+const ContentType = "application/scim+json"
+
+// Schema URNs
+const (
+	SchemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SchemaGroup                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SchemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SchemaResourceType          = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+	SchemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+// Error types (scimType) of 400 responses
+const (
+	ErrorInvalidFilter = "invalidFilter"
+	ErrorInvalidSyntax = "invalidSyntax"
+	ErrorInvalidPath   = "invalidPath"
+	ErrorInvalidValue  = "invalidValue"
+	ErrorNoTarget      = "noTarget"
+	ErrorMutability    = "mutability"
+	ErrorUniqueness    = "uniqueness"
+)
+
+// MaxResults is the largest page returned by list requests
+const MaxResults = 200
+
+// Error is a SCIM error with its HTTP status
+type Error struct {
+	Status int
+	Type   string
+	Detail string
+}
+
+// NewError creates a SCIM error
+func NewError(status int, scimType, detail string) *Error {
+	return &Error{
+		Status: status,
+		Type:   scimType,
+		Detail: detail,
+	}
+}
+
+// BadRequest creates a 400 error of the given type
+func BadRequest(scimType, detail string) *Error {
+	return NewError(http.StatusBadRequest, scimType, detail)
+}
+
+func (e *Error) Error() string {
+	return e.Detail
+}
+
+// ErrorResponse is the body of SCIM error responses
+type ErrorResponse struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+// WriteError sends a SCIM error response
+func WriteError(c *gin.Context, err *Error) {
+	Write(c, err.Status, ErrorResponse{
+		Schemas:  []string{SchemaError},
+		Status:   strconv.Itoa(err.Status),
+		ScimType: err.Type,
+		Detail:   err.Detail,
+	})
+}
+
+// Write sends a SCIM response body
+func Write(c *gin.Context, status int, body interface{}) {
+	c.Header("Content-Type", ContentType)
+	c.JSON(status, body)
+}
+
+// Meta is the metadata of a resource
+type Meta struct {
+	ResourceType string    `json:"resourceType"`
+	Created      time.Time `json:"created"`
+	LastModified time.Time `json:"lastModified"`
+	Location     string    `json:"location"`
+	Version      string    `json:"version"`
+}
+
+// ETag returns the weak entity tag of a resource last modified at the given
+// time. The time is truncated to the database precision.
+func ETag(lastModified time.Time) string {
+	return `W/"` + strconv.FormatInt(lastModified.Truncate(time.Microsecond).UnixMicro(), 36) + `"`
+}
+
+// CheckPreconditions evaluates If-Match against the current entity tag of
+// a resource about to be changed
+func CheckPreconditions(c *gin.Context, etag string) *Error {
+	ifMatch := c.GetHeader("If-Match")
+	if ifMatch == "" || matchesETag(ifMatch, etag) {
+		return nil
+	}
+	return NewError(http.StatusPreconditionFailed, "", "Resource has been modified")
+}
+
+// NotModified reports whether If-None-Match matches the current entity tag
+func NotModified(c *gin.Context, etag string) bool {
+	ifNoneMatch := c.GetHeader("If-None-Match")
+	return ifNoneMatch != "" && matchesETag(ifNoneMatch, etag)
+}
+
+// matchesETag compares a list of entity tags from a conditional header
+// using weak comparison
+func matchesETag(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// ListResponse is the body of list responses
+type ListResponse struct {
+	Schemas      []string      `json:"schemas"`
+	TotalResults int64         `json:"totalResults"`
+	StartIndex   int           `json:"startIndex"`
+	ItemsPerPage int           `json:"itemsPerPage"`
+	Resources    []interface{} `json:"Resources"`
+}
+
+// NewListResponse creates a list response for a page of resources
+func NewListResponse(resources []interface{}, total int64, startIndex int) ListResponse {
+	if resources == nil {
+		resources = []interface{}{}
+	}
+	return ListResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}
+}
+
+// Pagination reads the 1-based startIndex and the count of a list request
+func Pagination(c *gin.Context) (startIndex, count int, err *Error) {
+	startIndex, count = 1, 100
+	if value := c.Query("startIndex"); value != "" {
+		parsed, parseErr := strconv.Atoi(value)
+		if parseErr != nil {
+			return 0, 0, BadRequest(ErrorInvalidValue, "startIndex must be an integer")
+		}
+		if parsed > 1 {
+			startIndex = parsed
+		}
+	}
+	if value := c.Query("count"); value != "" {
+		parsed, parseErr := strconv.Atoi(value)
+		if parseErr != nil {
+			return 0, 0, BadRequest(ErrorInvalidValue, "count must be an integer")
+		}
+		count = parsed
+	}
+	if count < 0 {
+		count = 0
+	}
+	if count > MaxResults {
+		count = MaxResults
+	}
+	return startIndex, count, nil
+}
+
+// Excluded reports whether an attribute is listed in excludedAttributes
+func Excluded(c *gin.Context, attribute string) bool {
+	for _, excluded := range strings.Split(c.Query("excludedAttributes"), ",") {
+		if NormalizeAttribute(excluded) == NormalizeAttribute(attribute) {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeAttribute lower-cases an attribute path and strips a schema URN
+// prefix; SCIM attribute names are case-insensitive
+func NormalizeAttribute(attribute string) string {
+	attribute = strings.TrimSpace(attribute)
+	if strings.HasPrefix(strings.ToLower(attribute), "urn:") {
+		if separator := strings.LastIndex(attribute, ":"); separator >= 0 {
+			attribute = attribute[separator+1:]
+		}
+	}
+	return strings.ToLower(attribute)
+}
+
+// IsExtensionAttribute reports whether an attribute path is qualified with a
+// schema URN other than the given core schema. Extension attributes are
+// not stored and ignored on write.
+func IsExtensionAttribute(attribute, schema string) bool {
+	lower := strings.ToLower(strings.TrimSpace(attribute))
+	return strings.HasPrefix(lower, "urn:") && !strings.HasPrefix(lower, strings.ToLower(schema)+":")
+}
+
+// User is the SCIM representation of a user. Password is write-only and
+// Groups read-only.
+type User struct {
+	Schemas     []string   `json:"schemas"`
+	ID          string     `json:"id,omitempty"`
+	ExternalID  string     `json:"externalId,omitempty"`
+	UserName    string     `json:"userName"`
+	Name        *Name      `json:"name,omitempty"`
+	DisplayName string     `json:"displayName,omitempty"`
+	Emails      []Email    `json:"emails,omitempty"`
+	Active      *bool      `json:"active,omitempty"`
+	Locale      string     `json:"locale,omitempty"`
+	Timezone    string     `json:"timezone,omitempty"`
+	Password    string     `json:"password,omitempty"`
+	Groups      []GroupRef `json:"groups,omitempty"`
+	Meta        *Meta      `json:"meta,omitempty"`
+}
+
+// Name is the name of a user
+type Name struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+// FullName returns the formatted name, or given and family name joined
+func (n *Name) FullName() string {
+	if n == nil {
+		return ""
+	}
+	if n.Formatted != "" {
+		return n.Formatted
+	}
+	return strings.TrimSpace(n.GivenName + " " + n.FamilyName)
+}
+
+// Email is an email address of a user
+type Email struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// PrimaryEmail returns the primary email, or the first one
+func PrimaryEmail(emails []Email) string {
+	for _, email := range emails {
+		if email.Primary {
+			return email.Value
+		}
+	}
+	if len(emails) > 0 {
+		return emails[0].Value
+	}
+	return ""
+}
+
+// GroupRef references a group a user belongs to
+type GroupRef struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+// Group is the SCIM representation of a group
+type Group struct {
+	Schemas     []string `json:"schemas"`
+	ID          string   `json:"id,omitempty"`
+	ExternalID  string   `json:"externalId,omitempty"`
+	DisplayName string   `json:"displayName"`
+	Members     []Member `json:"members,omitempty"`
+	Meta        *Meta    `json:"meta,omitempty"`
+}
+
+// Member references a user in a group
+type Member struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+	Type    string `json:"type,omitempty"`
+}